@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+	"github.com/kfcempoyee/gofilesharing/internal/config"
 	"github.com/kfcempoyee/gofilesharing/internal/gateway"
+	"github.com/kfcempoyee/gofilesharing/internal/jwt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -16,7 +22,30 @@ func main() {
 	lg := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	slog.SetDefault(lg)
 
-	conn, err := grpc.NewClient("localhost:5051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	cmd, args := config.Command(os.Args[1:])
+	cfg, err := config.LoadGateway(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		lg.Error("invalid config", "error", err)
+		os.Exit(2)
+	}
+
+	switch cmd {
+	case "":
+	case "dump-config":
+		if err := config.Dump(os.Stdout, cfg); err != nil {
+			lg.Error("failed to dump config", "error", err)
+			os.Exit(1)
+		}
+		return
+	default:
+		lg.Error("unknown command", "command", cmd)
+		os.Exit(2)
+	}
+
+	conn, err := grpc.NewClient(cfg.Registry, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -25,15 +54,53 @@ func main() {
 	client := pb.NewRegServiceClient(conn)
 
 	handler := &gateway.FileHandler{
-		TmpDir:     "./data/tmp",
-		GRpcClient: client,
-		Logger:     lg,
+		TmpDir:        cfg.TmpDir,
+		MaxUploadSize: cfg.MaxUploadSize,
+		UploadTTL:     time.Duration(cfg.UploadTTL),
+		GRpcClient:    client,
+		Logger:        lg,
+	}
+
+	// брошенные возобновляемые загрузки убирает сам гейтвей, реестр может не видеть его диска
+	handler.StartSweeper(context.Background())
+
+	// без ключа или токена можно загружать, только если это разрешено настройками
+	auth := &gateway.Auth{
+		Client:    client,
+		Anonymous: cfg.AnonymousUploads,
+		Logger:    lg,
+	}
+
+	if cfg.JWT.JWKS != "" {
+		keys, err := jwt.NewKeySet(context.Background(), cfg.JWT.JWKS)
+		if err != nil {
+			lg.Error("failed to load jwks", "error", err)
+			os.Exit(1)
+		}
+		auth.JWT = &jwt.Verifier{Keys: keys, Issuer: cfg.JWT.Issuer, Audience: cfg.JWT.Audience}
+	}
+
+	// без хостов и префикса пути арендаторы не различаются, все идет в пространство по умолчанию
+	var tenants *gateway.Tenants
+	if len(cfg.Tenants.Hosts) > 0 || cfg.Tenants.PathPrefix {
+		tenants = &gateway.Tenants{Hosts: cfg.Tenants.Hosts, PathPrefix: cfg.Tenants.PathPrefix}
+	}
+
+	// адреса уже проверены вместе с остальными настройками
+	proxies, _ := config.ParsePrefixes(cfg.RateLimit.TrustedProxies)
+	limits := &gateway.RateLimit{
+		Upload:         gateway.Rate(cfg.RateLimit.Upload),
+		Download:       gateway.Rate(cfg.RateLimit.Download),
+		Info:           gateway.Rate(cfg.RateLimit.Info),
+		Auth:           gateway.Rate(cfg.RateLimit.Auth),
+		TrustedProxies: proxies,
+		Logger:         lg,
 	}
 
-	router := gateway.NewRouter(handler)
+	router := gateway.NewRouter(handler, auth, tenants, limits)
 	mux := router.Route(lg)
 
-	if err = http.ListenAndServe(":8080", mux); err != nil {
+	if err = http.ListenAndServe(cfg.Listen, mux); err != nil {
 		lg.Error("error with gateway")
 		return
 	}
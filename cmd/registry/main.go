@@ -3,15 +3,21 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"log/slog"
 	"net"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/kfcempoyee/gofilesharing/internal/config"
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
 	"github.com/kfcempoyee/gofilesharing/internal/registry/handler"
 	"github.com/kfcempoyee/gofilesharing/internal/registry/repository"
 	"github.com/kfcempoyee/gofilesharing/internal/registry/service"
+	"github.com/kfcempoyee/gofilesharing/internal/registry/storage"
 	_ "github.com/mattn/go-sqlite3"
 	"google.golang.org/grpc"
 
@@ -22,8 +28,32 @@ func main() {
 	// настраиваем логгер
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
+	// читаем настройки: флаги, окружение, файл
+	cmd, args := config.Command(os.Args[1:])
+	cfg, err := config.LoadRegistry(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logger.Error("invalid config", "error", err)
+		os.Exit(2)
+	}
+
+	switch cmd {
+	case "":
+	case "dump-config":
+		if err := config.Dump(os.Stdout, cfg); err != nil {
+			logger.Error("failed to dump config", "error", err)
+			os.Exit(1)
+		}
+		return
+	default:
+		logger.Error("unknown command", "command", cmd)
+		os.Exit(2)
+	}
+
 	// настраиваем бд
-	db, err := sql.Open("sqlite3", "storage.db")
+	db, err := sql.Open("sqlite3", cfg.DB)
 	if err != nil {
 		logger.Error("failed to open db", "error", err)
 		os.Exit(1)
@@ -36,33 +66,92 @@ func main() {
 		os.Exit(1)
 	}
 
-	svc := service.NewFileService(repo, logger)
+	// хранилище содержимого: S3-совместимое, если задан его адрес, иначе локальный диск
+	var blobs service.BlobStore
+	if s3 := cfg.Storage.S3; s3.Endpoint != "" {
+		blobs = storage.NewS3(s3.Endpoint, s3.Region, s3.Bucket, s3.AccessKey, string(s3.SecretKey))
+	} else {
+		blobs, err = storage.NewLocal(cfg.Storage.Dir)
+		if err != nil {
+			logger.Error("failed to init storage", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	svc := service.NewFileService(repo, blobs, logger)
+	svc.TmpDir = cfg.TmpDir
+	svc.IDLength = cfg.IDLength // начальная длина, при заполнении пространства айди она растет сама
+	svc.DefaultTTL = time.Duration(cfg.DefaultTTL)
+	svc.MaxTTL = time.Duration(cfg.MaxTTL)
+	svc.CleanupInterval = time.Duration(cfg.CleanupInterval)
+	svc.ScrubInterval = time.Duration(cfg.ScrubInterval)
+	svc.GCInterval = time.Duration(cfg.GCInterval)
+	svc.GCMinAge = time.Duration(cfg.GCMinAge)
+	svc.Quota = domain.Quota{MaxBytes: cfg.Quota.MaxBytes, MaxFiles: cfg.Quota.MaxFiles}
+	svc.Tenants = make(map[string]domain.Tenant, len(cfg.Tenants))
+	for name, t := range cfg.Tenants {
+		svc.Tenants[name] = domain.Tenant{
+			Name:         name,
+			MaxSize:      t.MaxSize,
+			DefaultTTL:   time.Duration(t.DefaultTTL),
+			MaxTTL:       time.Duration(t.MaxTTL),
+			ContentTypes: t.ContentTypes,
+			Quota:        domain.Quota{MaxBytes: t.Quota.MaxBytes, MaxFiles: t.Quota.MaxFiles},
+		}
+	}
+
 	h := handler.NewGRPCHandler(svc)
 
 	// создаем контекст для всего приложения
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// запускаем очистку после инициализации контекста
+	// запускаем очистку, проверку хранилища и сборку мусора после инициализации контекста
 	svc.StartCleanup(ctx)
+	svc.StartScrubber(ctx)
+	svc.StartCollector(ctx)
 
 	// настраиваем gRpc-сервер
-	lis, err := net.Listen("tcp", ":50051")
+	lis, err := net.Listen("tcp", cfg.Listen)
 	if err != nil {
 		logger.Error("failed to listen", "error", err)
 		os.Exit(1)
 	}
 
-	grpcServer := grpc.NewServer()
+	// администрирование слушает отдельный адрес: по общему с гейтвеем ключи и квоты
+	// мог бы менять любой, кто достучался до реестра
+	adminLis, err := net.Listen("tcp", cfg.AdminListen)
+	if err != nil {
+		logger.Error("failed to listen", "error", err)
+		os.Exit(1)
+	}
+
+	// арендатор из метаданных проверяется до любого вызова
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(handler.TenantUnaryInterceptor(svc)),
+		grpc.StreamInterceptor(handler.TenantStreamInterceptor(svc)),
+	)
 	pb.RegisterRegServiceServer(grpcServer, h)
 
-	// запускаем сервер в горутине
+	adminServer := grpc.NewServer(
+		grpc.UnaryInterceptor(handler.TenantUnaryInterceptor(svc)),
+		grpc.StreamInterceptor(handler.TenantStreamInterceptor(svc)),
+	)
+	pb.RegisterAdminServiceServer(adminServer, handler.NewAdminHandler(svc))
+
+	// запускаем серверы в горутинах
 	go func() {
-		logger.Info("gRPC server starting on :50051")
+		logger.Info("gRPC server starting on " + cfg.Listen)
 		if err := grpcServer.Serve(lis); err != nil {
 			logger.Error("failed to serve", "error", err)
 		}
 	}()
+	go func() {
+		logger.Info("admin gRPC server starting on " + cfg.AdminListen)
+		if err := adminServer.Serve(adminLis); err != nil {
+			logger.Error("failed to serve admin", "error", err)
+		}
+	}()
 
 	// начинаем слушать сигналы для graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -78,6 +167,7 @@ func main() {
 
 	// останавливаем grpc-сервер
 	logger.Info("stopping gRPC server...")
+	adminServer.GracefulStop()
 	grpcServer.GracefulStop()
 	logger.Info("gRPC server stopped")
 
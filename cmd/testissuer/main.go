@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kfcempoyee/gofilesharing/internal/jwt"
+)
+
+// локальный издатель токенов для проверки гейтвея без настоящего провайдера.
+// при первом запуске создает в -dir пару ключей (key.pem) и JWKS (jwks.json), который
+// передается гейтвею в -jwt-jwks, и печатает подписанный токен:
+//
+//	testissuer -sub alice > token
+//	gateway -jwt-jwks data/issuer/jwks.json -jwt-issuer http://localhost/test -jwt-audience gofilesharing
+//	curl -H "Authorization: Bearer $(cat token)" -F File=@a.txt localhost:8080/upload
+
+func main() {
+	dir := flag.String("dir", "data/issuer", "directory for the key pair and JWKS")
+	alg := flag.String("alg", "RS256", "signing algorithm of a new key: RS256 or ES256")
+	iss := flag.String("iss", "http://localhost/test", "token issuer")
+	aud := flag.String("aud", "gofilesharing", "token audience")
+	sub := flag.String("sub", "alice", "token subject, becomes the owner of uploaded files")
+	ttl := flag.Duration("ttl", time.Hour, "token lifetime")
+	flag.Parse()
+
+	signer, err := loadSigner(*dir, *alg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	now := time.Now()
+	token, err := signer.Sign(jwt.Claims{
+		Issuer:    *iss,
+		Subject:   *sub,
+		Audience:  jwt.Audience{*aud},
+		ExpiresAt: now.Add(*ttl).Unix(),
+		IssuedAt:  now.Unix(),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(token)
+}
+
+// ключ из dir или новый, если его еще нет. -alg действует только на новый ключ
+func loadSigner(dir, alg string) (*jwt.Signer, error) {
+	keyPath := filepath.Join(dir, "key.pem")
+
+	data, err := os.ReadFile(keyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return newSigner(dir, alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", keyPath)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", keyPath, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported key type", keyPath)
+	}
+
+	return &jwt.Signer{Key: signer, KeyID: "test"}, nil
+}
+
+func newSigner(dir, alg string) (*jwt.Signer, error) {
+	var key crypto.Signer
+	var err error
+	switch alg {
+	case "RS256":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	signer := &jwt.Signer{Key: key, KeyID: "test"}
+	jwks, err := signer.JWKS()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "jwks.json"), jwks, 0644); err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
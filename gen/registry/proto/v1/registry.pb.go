@@ -27,6 +27,14 @@ type RegisterFileRequest struct {
 	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
 	SizeBytes     int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
 	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	TtlSeconds    int64                  `protobuf:"varint,5,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`       // 0 - срок по умолчанию, -1 - бессрочно
+	MaxDownloads  int64                  `protobuf:"varint,6,opt,name=max_downloads,json=maxDownloads,proto3" json:"max_downloads,omitempty"` // 0 - без ограничений
+	Password      string                 `protobuf:"bytes,7,opt,name=password,proto3" json:"password,omitempty"`                              // пустая строка - файл без пароля
+	ShortName     string                 `protobuf:"bytes,8,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`           // желаемое короткое имя, пустая строка - случайное
+	Sha256        []byte                 `protobuf:"bytes,9,opt,name=sha256,proto3" json:"sha256,omitempty"`                                  // ожидаемый клиентом хеш содержимого, пусто - не проверять
+	Md5           []byte                 `protobuf:"bytes,10,opt,name=md5,proto3" json:"md5,omitempty"`                                       // то же для Content-MD5
+	KeyId         string                 `protobuf:"bytes,11,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`                      // ключ, которым гейтвей подтвердил загрузку, пустая строка - анонимная загрузка
+	Owner         string                 `protobuf:"bytes,12,opt,name=owner,proto3" json:"owner,omitempty"`                                   // кто загружает: субъект токена или key:<айди ключа>, пустая строка - аноним
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -89,28 +97,1987 @@ func (x *RegisterFileRequest) GetContentType() string {
 	return ""
 }
 
+func (x *RegisterFileRequest) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *RegisterFileRequest) GetMaxDownloads() int64 {
+	if x != nil {
+		return x.MaxDownloads
+	}
+	return 0
+}
+
+func (x *RegisterFileRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *RegisterFileRequest) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *RegisterFileRequest) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+func (x *RegisterFileRequest) GetMd5() []byte {
+	if x != nil {
+		return x.Md5
+	}
+	return nil
+}
+
+func (x *RegisterFileRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *RegisterFileRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+// первое сообщение потока несет метаданные файла, все последующие - его содержимое
+type UploadFileReq struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*UploadFileReq_Meta
+	//	*UploadFileReq_Chunk
+	Data          isUploadFileReq_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadFileReq) Reset() {
+	*x = UploadFileReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadFileReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadFileReq) ProtoMessage() {}
+
+func (x *UploadFileReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadFileReq.ProtoReflect.Descriptor instead.
+func (*UploadFileReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UploadFileReq) GetData() isUploadFileReq_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *UploadFileReq) GetMeta() *FileMeta {
+	if x != nil {
+		if x, ok := x.Data.(*UploadFileReq_Meta); ok {
+			return x.Meta
+		}
+	}
+	return nil
+}
+
+func (x *UploadFileReq) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*UploadFileReq_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isUploadFileReq_Data interface {
+	isUploadFileReq_Data()
+}
+
+type UploadFileReq_Meta struct {
+	Meta *FileMeta `protobuf:"bytes,1,opt,name=meta,proto3,oneof"`
+}
+
+type UploadFileReq_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+func (*UploadFileReq_Meta) isUploadFileReq_Data() {}
+
+func (*UploadFileReq_Chunk) isUploadFileReq_Data() {}
+
+type FileMeta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	SizeBytes     int64                  `protobuf:"varint,2,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"` // 0, если размер заранее неизвестен
+	ContentType   string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	TtlSeconds    int64                  `protobuf:"varint,4,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`       // 0 - срок по умолчанию, -1 - бессрочно
+	MaxDownloads  int64                  `protobuf:"varint,5,opt,name=max_downloads,json=maxDownloads,proto3" json:"max_downloads,omitempty"` // 0 - без ограничений
+	Password      string                 `protobuf:"bytes,6,opt,name=password,proto3" json:"password,omitempty"`                              // пустая строка - файл без пароля
+	ShortName     string                 `protobuf:"bytes,7,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`           // желаемое короткое имя, пустая строка - случайное
+	Sha256        []byte                 `protobuf:"bytes,8,opt,name=sha256,proto3" json:"sha256,omitempty"`                                  // ожидаемый клиентом хеш содержимого, пусто - не проверять
+	Md5           []byte                 `protobuf:"bytes,9,opt,name=md5,proto3" json:"md5,omitempty"`                                        // то же для Content-MD5
+	KeyId         string                 `protobuf:"bytes,10,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`                      // ключ, которым гейтвей подтвердил загрузку, пустая строка - анонимная загрузка
+	Owner         string                 `protobuf:"bytes,11,opt,name=owner,proto3" json:"owner,omitempty"`                                   // кто загружает: субъект токена или key:<айди ключа>, пустая строка - аноним
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileMeta) Reset() {
+	*x = FileMeta{}
+	mi := &file_proto_v1_registry_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileMeta) ProtoMessage() {}
+
+func (x *FileMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileMeta.ProtoReflect.Descriptor instead.
+func (*FileMeta) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FileMeta) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *FileMeta) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *FileMeta) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *FileMeta) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *FileMeta) GetMaxDownloads() int64 {
+	if x != nil {
+		return x.MaxDownloads
+	}
+	return 0
+}
+
+func (x *FileMeta) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *FileMeta) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *FileMeta) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+func (x *FileMeta) GetMd5() []byte {
+	if x != nil {
+		return x.Md5
+	}
+	return nil
+}
+
+func (x *FileMeta) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *FileMeta) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
 type RegisterFileResp struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	DeleteToken   string                 `protobuf:"bytes,2,opt,name=delete_token,json=deleteToken,proto3" json:"delete_token,omitempty"` // секрет владельца, показывается один раз
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterFileResp) Reset() {
+	*x = RegisterFileResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterFileResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterFileResp) ProtoMessage() {}
+
+func (x *RegisterFileResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterFileResp.ProtoReflect.Descriptor instead.
+func (*RegisterFileResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RegisterFileResp) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *RegisterFileResp) GetDeleteToken() string {
+	if x != nil {
+		return x.DeleteToken
+	}
+	return ""
+}
+
+type GetFileDataReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"` // нужен только для защищенных файлов
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFileDataReq) Reset() {
+	*x = GetFileDataReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFileDataReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFileDataReq) ProtoMessage() {}
+
+func (x *GetFileDataReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFileDataReq.ProtoReflect.Descriptor instead.
+func (*GetFileDataReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetFileDataReq) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *GetFileDataReq) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type GetFileDataResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	SizeBytes     int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	ExpiresAt     int64                  `protobuf:"varint,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`          // unix-время истечения ссылки, 0 - бессрочно
+	MaxDownloads  int64                  `protobuf:"varint,6,opt,name=max_downloads,json=maxDownloads,proto3" json:"max_downloads,omitempty"` // 0 - без ограничений
+	Downloads     int64                  `protobuf:"varint,7,opt,name=downloads,proto3" json:"downloads,omitempty"`
+	Sha256        string                 `protobuf:"bytes,8,opt,name=sha256,proto3" json:"sha256,omitempty"` // хеш содержимого в hex, пустая строка - файл загружен до появления хешей
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFileDataResp) Reset() {
+	*x = GetFileDataResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFileDataResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFileDataResp) ProtoMessage() {}
+
+func (x *GetFileDataResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFileDataResp.ProtoReflect.Descriptor instead.
+func (*GetFileDataResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetFileDataResp) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *GetFileDataResp) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *GetFileDataResp) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *GetFileDataResp) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *GetFileDataResp) GetMaxDownloads() int64 {
+	if x != nil {
+		return x.MaxDownloads
+	}
+	return 0
+}
+
+func (x *GetFileDataResp) GetDownloads() int64 {
+	if x != nil {
+		return x.Downloads
+	}
+	return 0
+}
+
+func (x *GetFileDataResp) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type DownloadFileReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length        int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`    // 0 - до конца файла
+	Password      string                 `protobuf:"bytes,4,opt,name=password,proto3" json:"password,omitempty"` // нужен только для защищенных файлов
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadFileReq) Reset() {
+	*x = DownloadFileReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadFileReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadFileReq) ProtoMessage() {}
+
+func (x *DownloadFileReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadFileReq.ProtoReflect.Descriptor instead.
+func (*DownloadFileReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DownloadFileReq) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *DownloadFileReq) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *DownloadFileReq) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *DownloadFileReq) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type DownloadFileResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Chunk         []byte                 `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadFileResp) Reset() {
+	*x = DownloadFileResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadFileResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadFileResp) ProtoMessage() {}
+
+func (x *DownloadFileResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadFileResp.ProtoReflect.Descriptor instead.
+func (*DownloadFileResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DownloadFileResp) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+type DeleteFileReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	DeleteToken   string                 `protobuf:"bytes,2,opt,name=delete_token,json=deleteToken,proto3" json:"delete_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileReq) Reset() {
+	*x = DeleteFileReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileReq) ProtoMessage() {}
+
+func (x *DeleteFileReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileReq.ProtoReflect.Descriptor instead.
+func (*DeleteFileReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeleteFileReq) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *DeleteFileReq) GetDeleteToken() string {
+	if x != nil {
+		return x.DeleteToken
+	}
+	return ""
+}
+
+type DeleteFileResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileResp) Reset() {
+	*x = DeleteFileResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileResp) ProtoMessage() {}
+
+func (x *DeleteFileResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileResp.ProtoReflect.Descriptor instead.
+func (*DeleteFileResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{9}
+}
+
+// новый срок считается от текущего момента, но не может выйти за максимальный срок от загрузки:
+// сервера, арендатора и ключа, по которому загружен файл. ссылку отозванного ключа можно только сократить
+type UpdateExpiryReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	DeleteToken   string                 `protobuf:"bytes,2,opt,name=delete_token,json=deleteToken,proto3" json:"delete_token,omitempty"`
+	TtlSeconds    int64                  `protobuf:"varint,3,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"` // 0 - срок по умолчанию, -1 - бессрочно
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateExpiryReq) Reset() {
+	*x = UpdateExpiryReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateExpiryReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateExpiryReq) ProtoMessage() {}
+
+func (x *UpdateExpiryReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateExpiryReq.ProtoReflect.Descriptor instead.
+func (*UpdateExpiryReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UpdateExpiryReq) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *UpdateExpiryReq) GetDeleteToken() string {
+	if x != nil {
+		return x.DeleteToken
+	}
+	return ""
+}
+
+func (x *UpdateExpiryReq) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+type UpdateExpiryResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExpiresAt     int64                  `protobuf:"varint,1,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // unix-время истечения ссылки, 0 - бессрочно
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateExpiryResp) Reset() {
+	*x = UpdateExpiryResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateExpiryResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateExpiryResp) ProtoMessage() {}
+
+func (x *UpdateExpiryResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateExpiryResp.ProtoReflect.Descriptor instead.
+func (*UpdateExpiryResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *UpdateExpiryResp) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+// набор уже загруженных файлов под одной ссылкой. DeleteFile и UpdateExpiry с коротким именем
+// набора и его токеном удаляют набор или меняют его срок вместе со всеми файлами
+type CreateCollectionReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Members       []*CollectionMember    `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	TtlSeconds    int64                  `protobuf:"varint,2,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"` // 0 - срок по умолчанию, -1 - бессрочно
+	Password      string                 `protobuf:"bytes,3,opt,name=password,proto3" json:"password,omitempty"`                        // пустая строка - набор без пароля
+	ShortName     string                 `protobuf:"bytes,4,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`     // желаемое короткое имя, пустая строка - случайное
+	KeyId         string                 `protobuf:"bytes,5,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`                 // ключ загрузки, его ограничение срока действует и на набор
+	Owner         string                 `protobuf:"bytes,6,opt,name=owner,proto3" json:"owner,omitempty"`                              // от чьего имени создается набор, как owner в FileMeta
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateCollectionReq) Reset() {
+	*x = CreateCollectionReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateCollectionReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateCollectionReq) ProtoMessage() {}
+
+func (x *CreateCollectionReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateCollectionReq.ProtoReflect.Descriptor instead.
+func (*CreateCollectionReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CreateCollectionReq) GetMembers() []*CollectionMember {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *CreateCollectionReq) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *CreateCollectionReq) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *CreateCollectionReq) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *CreateCollectionReq) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *CreateCollectionReq) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+type CollectionMember struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	DeleteToken   string                 `protobuf:"bytes,2,opt,name=delete_token,json=deleteToken,proto3" json:"delete_token,omitempty"` // подтверждает, что файл принадлежит создателю набора
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CollectionMember) Reset() {
+	*x = CollectionMember{}
+	mi := &file_proto_v1_registry_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CollectionMember) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectionMember) ProtoMessage() {}
+
+func (x *CollectionMember) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectionMember.ProtoReflect.Descriptor instead.
+func (*CollectionMember) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CollectionMember) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *CollectionMember) GetDeleteToken() string {
+	if x != nil {
+		return x.DeleteToken
+	}
+	return ""
+}
+
+type GetCollectionReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"` // нужен только для защищенных наборов
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCollectionReq) Reset() {
+	*x = GetCollectionReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCollectionReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCollectionReq) ProtoMessage() {}
+
+func (x *GetCollectionReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCollectionReq.ProtoReflect.Descriptor instead.
+func (*GetCollectionReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetCollectionReq) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *GetCollectionReq) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type GetCollectionResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExpiresAt     int64                  `protobuf:"varint,1,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // unix-время истечения набора, 0 - бессрочно
+	Files         []*CollectionFile      `protobuf:"bytes,2,rep,name=files,proto3" json:"files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCollectionResp) Reset() {
+	*x = GetCollectionResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCollectionResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCollectionResp) ProtoMessage() {}
+
+func (x *GetCollectionResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCollectionResp.ProtoReflect.Descriptor instead.
+func (*GetCollectionResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetCollectionResp) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *GetCollectionResp) GetFiles() []*CollectionFile {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+type CollectionFile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	SizeBytes     int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	MaxDownloads  int64                  `protobuf:"varint,5,opt,name=max_downloads,json=maxDownloads,proto3" json:"max_downloads,omitempty"`
+	Downloads     int64                  `protobuf:"varint,6,opt,name=downloads,proto3" json:"downloads,omitempty"`
+	Sha256        string                 `protobuf:"bytes,7,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CollectionFile) Reset() {
+	*x = CollectionFile{}
+	mi := &file_proto_v1_registry_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CollectionFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectionFile) ProtoMessage() {}
+
+func (x *CollectionFile) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectionFile.ProtoReflect.Descriptor instead.
+func (*CollectionFile) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CollectionFile) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *CollectionFile) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *CollectionFile) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *CollectionFile) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *CollectionFile) GetMaxDownloads() int64 {
+	if x != nil {
+		return x.MaxDownloads
+	}
+	return 0
+}
+
+func (x *CollectionFile) GetDownloads() int64 {
+	if x != nil {
+		return x.Downloads
+	}
+	return 0
+}
+
+func (x *CollectionFile) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type ScrubReportReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Run           bool                   `protobuf:"varint,1,opt,name=run,proto3" json:"run,omitempty"` // запустить проверку хранилища сейчас и дождаться ее окончания
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScrubReportReq) Reset() {
+	*x = ScrubReportReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScrubReportReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScrubReportReq) ProtoMessage() {}
+
+func (x *ScrubReportReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScrubReportReq.ProtoReflect.Descriptor instead.
+func (*ScrubReportReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ScrubReportReq) GetRun() bool {
+	if x != nil {
+		return x.Run
+	}
+	return false
+}
+
+type ScrubReportResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StartedAt     int64                  `protobuf:"varint,1,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`    // unix-время начала последней проверки, 0 - проверки еще не было
+	FinishedAt    int64                  `protobuf:"varint,2,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"` // 0 - проверка еще идет
+	Checked       int64                  `protobuf:"varint,3,opt,name=checked,proto3" json:"checked,omitempty"`
+	Missing       int64                  `protobuf:"varint,4,opt,name=missing,proto3" json:"missing,omitempty"`
+	Corrupted     int64                  `protobuf:"varint,5,opt,name=corrupted,proto3" json:"corrupted,omitempty"`
+	Damaged       []*DamagedFile         `protobuf:"bytes,6,rep,name=damaged,proto3" json:"damaged,omitempty"` // все файлы с найденными проблемами, а не только из последней проверки
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScrubReportResp) Reset() {
+	*x = ScrubReportResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScrubReportResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScrubReportResp) ProtoMessage() {}
+
+func (x *ScrubReportResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScrubReportResp.ProtoReflect.Descriptor instead.
+func (*ScrubReportResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ScrubReportResp) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *ScrubReportResp) GetFinishedAt() int64 {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return 0
+}
+
+func (x *ScrubReportResp) GetChecked() int64 {
+	if x != nil {
+		return x.Checked
+	}
+	return 0
+}
+
+func (x *ScrubReportResp) GetMissing() int64 {
+	if x != nil {
+		return x.Missing
+	}
+	return 0
+}
+
+func (x *ScrubReportResp) GetCorrupted() int64 {
+	if x != nil {
+		return x.Corrupted
+	}
+	return 0
+}
+
+func (x *ScrubReportResp) GetDamaged() []*DamagedFile {
+	if x != nil {
+		return x.Damaged
+	}
+	return nil
+}
+
+type DamagedFile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	StorageKey    string                 `protobuf:"bytes,2,opt,name=storage_key,json=storageKey,proto3" json:"storage_key,omitempty"`
+	State         string                 `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"` // missing или corrupted
+	Tenant        string                 `protobuf:"bytes,4,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DamagedFile) Reset() {
+	*x = DamagedFile{}
+	mi := &file_proto_v1_registry_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DamagedFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DamagedFile) ProtoMessage() {}
+
+func (x *DamagedFile) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DamagedFile.ProtoReflect.Descriptor instead.
+func (*DamagedFile) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DamagedFile) GetShortName() string {
+	if x != nil {
+		return x.ShortName
+	}
+	return ""
+}
+
+func (x *DamagedFile) GetStorageKey() string {
+	if x != nil {
+		return x.StorageKey
+	}
+	return ""
+}
+
+func (x *DamagedFile) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *DamagedFile) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type CollectGarbageReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DryRun        bool                   `protobuf:"varint,1,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`                        // только найти мусор, ничего не удаляя
+	MinAgeSeconds int64                  `protobuf:"varint,2,opt,name=min_age_seconds,json=minAgeSeconds,proto3" json:"min_age_seconds,omitempty"` // файлы и объекты моложе не трогаются, 0 - порог по умолчанию
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CollectGarbageReq) Reset() {
+	*x = CollectGarbageReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CollectGarbageReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectGarbageReq) ProtoMessage() {}
+
+func (x *CollectGarbageReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectGarbageReq.ProtoReflect.Descriptor instead.
+func (*CollectGarbageReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CollectGarbageReq) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *CollectGarbageReq) GetMinAgeSeconds() int64 {
+	if x != nil {
+		return x.MinAgeSeconds
+	}
+	return 0
+}
+
+type CollectGarbageResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StartedAt     int64                  `protobuf:"varint,1,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt    int64                  `protobuf:"varint,2,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	DryRun        bool                   `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	TmpFiles      []string               `protobuf:"bytes,4,rep,name=tmp_files,json=tmpFiles,proto3" json:"tmp_files,omitempty"`                // забытые временные файлы загрузок
+	OrphanBlobs   []string               `protobuf:"bytes,5,rep,name=orphan_blobs,json=orphanBlobs,proto3" json:"orphan_blobs,omitempty"`       // объекты хранилища без записей в бд
+	DanglingFiles []string               `protobuf:"bytes,6,rep,name=dangling_files,json=danglingFiles,proto3" json:"dangling_files,omitempty"` // короткие имена записей, содержимого которых нет в хранилище
+	FreedBytes    int64                  `protobuf:"varint,7,opt,name=freed_bytes,json=freedBytes,proto3" json:"freed_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CollectGarbageResp) Reset() {
+	*x = CollectGarbageResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CollectGarbageResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectGarbageResp) ProtoMessage() {}
+
+func (x *CollectGarbageResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectGarbageResp.ProtoReflect.Descriptor instead.
+func (*CollectGarbageResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CollectGarbageResp) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *CollectGarbageResp) GetFinishedAt() int64 {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return 0
+}
+
+func (x *CollectGarbageResp) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *CollectGarbageResp) GetTmpFiles() []string {
+	if x != nil {
+		return x.TmpFiles
+	}
+	return nil
+}
+
+func (x *CollectGarbageResp) GetOrphanBlobs() []string {
+	if x != nil {
+		return x.OrphanBlobs
+	}
+	return nil
+}
+
+func (x *CollectGarbageResp) GetDanglingFiles() []string {
+	if x != nil {
+		return x.DanglingFiles
+	}
+	return nil
+}
+
+func (x *CollectGarbageResp) GetFreedBytes() int64 {
+	if x != nil {
+		return x.FreedBytes
+	}
+	return 0
+}
+
+// ключ доступа к загрузке. в реестре хранится только его хеш
+type AuthenticateReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthenticateReq) Reset() {
+	*x = AuthenticateReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthenticateReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthenticateReq) ProtoMessage() {}
+
+func (x *AuthenticateReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthenticateReq.ProtoReflect.Descriptor instead.
+func (*AuthenticateReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *AuthenticateReq) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+// ключ и ограничения загрузок по нему, нулевые значения - без ограничений сверх настроек сервера
+type ApiKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	MaxSizeBytes  int64                  `protobuf:"varint,4,opt,name=max_size_bytes,json=maxSizeBytes,proto3" json:"max_size_bytes,omitempty"`
+	MaxTtlSeconds int64                  `protobuf:"varint,5,opt,name=max_ttl_seconds,json=maxTtlSeconds,proto3" json:"max_ttl_seconds,omitempty"` // при ограничении срока бессрочные ссылки тоже запрещены
+	ContentTypes  []string               `protobuf:"bytes,6,rep,name=content_types,json=contentTypes,proto3" json:"content_types,omitempty"`       // например image/png или image/*, пусто - любые
+	Tenant        string                 `protobuf:"bytes,7,opt,name=tenant,proto3" json:"tenant,omitempty"`                                       // ключ действует только в пространстве этого арендатора
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApiKey) Reset() {
+	*x = ApiKey{}
+	mi := &file_proto_v1_registry_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApiKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApiKey) ProtoMessage() {}
+
+func (x *ApiKey) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApiKey.ProtoReflect.Descriptor instead.
+func (*ApiKey) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ApiKey) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ApiKey) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ApiKey) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *ApiKey) GetMaxSizeBytes() int64 {
+	if x != nil {
+		return x.MaxSizeBytes
+	}
+	return 0
+}
+
+func (x *ApiKey) GetMaxTtlSeconds() int64 {
+	if x != nil {
+		return x.MaxTtlSeconds
+	}
+	return 0
+}
+
+func (x *ApiKey) GetContentTypes() []string {
+	if x != nil {
+		return x.ContentTypes
+	}
+	return nil
+}
+
+func (x *ApiKey) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type CreateApiKeyReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	MaxSizeBytes  int64                  `protobuf:"varint,2,opt,name=max_size_bytes,json=maxSizeBytes,proto3" json:"max_size_bytes,omitempty"`
+	MaxTtlSeconds int64                  `protobuf:"varint,3,opt,name=max_ttl_seconds,json=maxTtlSeconds,proto3" json:"max_ttl_seconds,omitempty"`
+	ContentTypes  []string               `protobuf:"bytes,4,rep,name=content_types,json=contentTypes,proto3" json:"content_types,omitempty"`
+	Tenant        string                 `protobuf:"bytes,5,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateApiKeyReq) Reset() {
+	*x = CreateApiKeyReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateApiKeyReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateApiKeyReq) ProtoMessage() {}
+
+func (x *CreateApiKeyReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateApiKeyReq.ProtoReflect.Descriptor instead.
+func (*CreateApiKeyReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *CreateApiKeyReq) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateApiKeyReq) GetMaxSizeBytes() int64 {
+	if x != nil {
+		return x.MaxSizeBytes
+	}
+	return 0
+}
+
+func (x *CreateApiKeyReq) GetMaxTtlSeconds() int64 {
+	if x != nil {
+		return x.MaxTtlSeconds
+	}
+	return 0
+}
+
+func (x *CreateApiKeyReq) GetContentTypes() []string {
+	if x != nil {
+		return x.ContentTypes
+	}
+	return nil
+}
+
+func (x *CreateApiKeyReq) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type CreateApiKeyResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           *ApiKey                `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Secret        string                 `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"` // сам ключ, показывается один раз
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateApiKeyResp) Reset() {
+	*x = CreateApiKeyResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateApiKeyResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateApiKeyResp) ProtoMessage() {}
+
+func (x *CreateApiKeyResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateApiKeyResp.ProtoReflect.Descriptor instead.
+func (*CreateApiKeyResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *CreateApiKeyResp) GetKey() *ApiKey {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *CreateApiKeyResp) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+type ListApiKeysReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListApiKeysReq) Reset() {
+	*x = ListApiKeysReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListApiKeysReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListApiKeysReq) ProtoMessage() {}
+
+func (x *ListApiKeysReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListApiKeysReq.ProtoReflect.Descriptor instead.
+func (*ListApiKeysReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{26}
+}
+
+type ListApiKeysResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []*ApiKey              `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListApiKeysResp) Reset() {
+	*x = ListApiKeysResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListApiKeysResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListApiKeysResp) ProtoMessage() {}
+
+func (x *ListApiKeysResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListApiKeysResp.ProtoReflect.Descriptor instead.
+func (*ListApiKeysResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ListApiKeysResp) GetKeys() []*ApiKey {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type RevokeApiKeyReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeApiKeyReq) Reset() {
+	*x = RevokeApiKeyReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeApiKeyReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeApiKeyReq) ProtoMessage() {}
+
+func (x *RevokeApiKeyReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeApiKeyReq.ProtoReflect.Descriptor instead.
+func (*RevokeApiKeyReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *RevokeApiKeyReq) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type RevokeApiKeyResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeApiKeyResp) Reset() {
+	*x = RevokeApiKeyResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeApiKeyResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeApiKeyResp) ProtoMessage() {}
+
+func (x *RevokeApiKeyResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeApiKeyResp.ProtoReflect.Descriptor instead.
+func (*RevokeApiKeyResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{29}
+}
+
+// действующие файлы, новые сначала. пустые поля фильтра выборку не ограничивают
+type ListFilesReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Owner         string                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`                                       // как owner в FileMeta
+	NamePrefix    string                 `protobuf:"bytes,2,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`           // начало исходного имени файла, без учета регистра
+	ContentType   string                 `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`        // точный тип или шаблон вида image/*
+	ExpiresAfter  int64                  `protobuf:"varint,4,opt,name=expires_after,json=expiresAfter,proto3" json:"expires_after,omitempty"`    // unix-время: ссылки, истекающие не раньше, бессрочные тоже подходят
+	ExpiresBefore int64                  `protobuf:"varint,5,opt,name=expires_before,json=expiresBefore,proto3" json:"expires_before,omitempty"` // unix-время: ссылки, истекающие не позже, бессрочные не подходят
+	PageSize      int32                  `protobuf:"varint,6,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`                // 0 - размер по умолчанию, слишком большой урезается
+	PageToken     string                 `protobuf:"bytes,7,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`              // next_page_token предыдущей страницы, пустая строка - с начала
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesReq) Reset() {
+	*x = ListFilesReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesReq) ProtoMessage() {}
+
+func (x *ListFilesReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesReq.ProtoReflect.Descriptor instead.
+func (*ListFilesReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ListFilesReq) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *ListFilesReq) GetNamePrefix() string {
+	if x != nil {
+		return x.NamePrefix
+	}
+	return ""
+}
+
+func (x *ListFilesReq) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ListFilesReq) GetExpiresAfter() int64 {
+	if x != nil {
+		return x.ExpiresAfter
+	}
+	return 0
+}
+
+func (x *ListFilesReq) GetExpiresBefore() int64 {
+	if x != nil {
+		return x.ExpiresBefore
+	}
+	return 0
+}
+
+func (x *ListFilesReq) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListFilesReq) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListFilesResp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Files         []*ListedFile          `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // пустая строка - страница последняя
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterFileResp) Reset() {
-	*x = RegisterFileResp{}
-	mi := &file_proto_v1_registry_proto_msgTypes[1]
+func (x *ListFilesResp) Reset() {
+	*x = ListFilesResp{}
+	mi := &file_proto_v1_registry_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterFileResp) String() string {
+func (x *ListFilesResp) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterFileResp) ProtoMessage() {}
+func (*ListFilesResp) ProtoMessage() {}
 
-func (x *RegisterFileResp) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_v1_registry_proto_msgTypes[1]
+func (x *ListFilesResp) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -121,40 +2088,56 @@ func (x *RegisterFileResp) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterFileResp.ProtoReflect.Descriptor instead.
-func (*RegisterFileResp) Descriptor() ([]byte, []int) {
-	return file_proto_v1_registry_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use ListFilesResp.ProtoReflect.Descriptor instead.
+func (*ListFilesResp) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{31}
 }
 
-func (x *RegisterFileResp) GetShortName() string {
+func (x *ListFilesResp) GetFiles() []*ListedFile {
 	if x != nil {
-		return x.ShortName
+		return x.Files
+	}
+	return nil
+}
+
+func (x *ListFilesResp) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
 	}
 	return ""
 }
 
-type GetFileDataReq struct {
+type ListedFile struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ShortName     string                 `protobuf:"bytes,1,opt,name=short_name,json=shortName,proto3" json:"short_name,omitempty"`
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	SizeBytes     int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix-время
+	ExpiresAt     int64                  `protobuf:"varint,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // unix-время истечения ссылки, 0 - бессрочно
+	MaxDownloads  int64                  `protobuf:"varint,7,opt,name=max_downloads,json=maxDownloads,proto3" json:"max_downloads,omitempty"`
+	Downloads     int64                  `protobuf:"varint,8,opt,name=downloads,proto3" json:"downloads,omitempty"`
+	Protected     bool                   `protobuf:"varint,9,opt,name=protected,proto3" json:"protected,omitempty"`                           // файл под паролем
+	CollectionId  string                 `protobuf:"bytes,10,opt,name=collection_id,json=collectionId,proto3" json:"collection_id,omitempty"` // набор, в который входит файл, пустая строка - отдельный файл
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetFileDataReq) Reset() {
-	*x = GetFileDataReq{}
-	mi := &file_proto_v1_registry_proto_msgTypes[2]
+func (x *ListedFile) Reset() {
+	*x = ListedFile{}
+	mi := &file_proto_v1_registry_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetFileDataReq) String() string {
+func (x *ListedFile) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetFileDataReq) ProtoMessage() {}
+func (*ListedFile) ProtoMessage() {}
 
-func (x *GetFileDataReq) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_v1_registry_proto_msgTypes[2]
+func (x *ListedFile) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -165,43 +2148,105 @@ func (x *GetFileDataReq) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetFileDataReq.ProtoReflect.Descriptor instead.
-func (*GetFileDataReq) Descriptor() ([]byte, []int) {
-	return file_proto_v1_registry_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use ListedFile.ProtoReflect.Descriptor instead.
+func (*ListedFile) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{32}
 }
 
-func (x *GetFileDataReq) GetShortName() string {
+func (x *ListedFile) GetShortName() string {
 	if x != nil {
 		return x.ShortName
 	}
 	return ""
 }
 
-type GetFileDataResp struct {
+func (x *ListedFile) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *ListedFile) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *ListedFile) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ListedFile) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *ListedFile) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *ListedFile) GetMaxDownloads() int64 {
+	if x != nil {
+		return x.MaxDownloads
+	}
+	return 0
+}
+
+func (x *ListedFile) GetDownloads() int64 {
+	if x != nil {
+		return x.Downloads
+	}
+	return 0
+}
+
+func (x *ListedFile) GetProtected() bool {
+	if x != nil {
+		return x.Protected
+	}
+	return false
+}
+
+func (x *ListedFile) GetCollectionId() string {
+	if x != nil {
+		return x.CollectionId
+	}
+	return ""
+}
+
+// квоты владельцев. загрузка сверх квоты получает RESOURCE_EXHAUSTED с google.rpc.QuotaFailure,
+// subject нарушения - bytes или files. квота и занятое у владельца свои в каждом арендаторе
+type GetUsageReq struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	StPath        string                 `protobuf:"bytes,1,opt,name=st_path,json=stPath,proto3" json:"st_path,omitempty"`
-	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
-	SizeBytes     int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
-	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Owner         string                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"` // как owner в FileMeta
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetFileDataResp) Reset() {
-	*x = GetFileDataResp{}
-	mi := &file_proto_v1_registry_proto_msgTypes[3]
+func (x *GetUsageReq) Reset() {
+	*x = GetUsageReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetFileDataResp) String() string {
+func (x *GetUsageReq) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetFileDataResp) ProtoMessage() {}
+func (*GetUsageReq) ProtoMessage() {}
 
-func (x *GetFileDataResp) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_v1_registry_proto_msgTypes[3]
+func (x *GetUsageReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -212,66 +2257,401 @@ func (x *GetFileDataResp) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetFileDataResp.ProtoReflect.Descriptor instead.
-func (*GetFileDataResp) Descriptor() ([]byte, []int) {
-	return file_proto_v1_registry_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use GetUsageReq.ProtoReflect.Descriptor instead.
+func (*GetUsageReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{33}
 }
 
-func (x *GetFileDataResp) GetStPath() string {
+func (x *GetUsageReq) GetOwner() string {
 	if x != nil {
-		return x.StPath
+		return x.Owner
 	}
 	return ""
 }
 
-func (x *GetFileDataResp) GetFilename() string {
+// занятое владельцем и его квота, нулевые ограничения - без ограничений.
+// истекшие файлы учитываются, пока их не удалит очистка
+type Usage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bytes         int64                  `protobuf:"varint,1,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Files         int64                  `protobuf:"varint,2,opt,name=files,proto3" json:"files,omitempty"`
+	MaxBytes      int64                  `protobuf:"varint,3,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	MaxFiles      int64                  `protobuf:"varint,4,opt,name=max_files,json=maxFiles,proto3" json:"max_files,omitempty"`
+	Tenant        *Usage                 `protobuf:"bytes,5,opt,name=tenant,proto3" json:"tenant,omitempty"` // занятое арендатором из запроса и его квота, только в GetUsage
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Usage) Reset() {
+	*x = Usage{}
+	mi := &file_proto_v1_registry_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Usage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Usage) ProtoMessage() {}
+
+func (x *Usage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[34]
 	if x != nil {
-		return x.Filename
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *GetFileDataResp) GetSizeBytes() int64 {
+// Deprecated: Use Usage.ProtoReflect.Descriptor instead.
+func (*Usage) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *Usage) GetBytes() int64 {
 	if x != nil {
-		return x.SizeBytes
+		return x.Bytes
 	}
 	return 0
 }
 
-func (x *GetFileDataResp) GetContentType() string {
+func (x *Usage) GetFiles() int64 {
 	if x != nil {
-		return x.ContentType
+		return x.Files
+	}
+	return 0
+}
+
+func (x *Usage) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *Usage) GetMaxFiles() int64 {
+	if x != nil {
+		return x.MaxFiles
+	}
+	return 0
+}
+
+func (x *Usage) GetTenant() *Usage {
+	if x != nil {
+		return x.Tenant
+	}
+	return nil
+}
+
+// квота владельца в арендаторе из метаданных вызова
+type SetQuotaReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Owner         string                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	MaxBytes      int64                  `protobuf:"varint,2,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	MaxFiles      int64                  `protobuf:"varint,3,opt,name=max_files,json=maxFiles,proto3" json:"max_files,omitempty"`
+	Reset_        bool                   `protobuf:"varint,4,opt,name=reset,proto3" json:"reset,omitempty"` // вернуть квоту по умолчанию, ограничения из запроса не используются
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetQuotaReq) Reset() {
+	*x = SetQuotaReq{}
+	mi := &file_proto_v1_registry_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetQuotaReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetQuotaReq) ProtoMessage() {}
+
+func (x *SetQuotaReq) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_registry_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetQuotaReq.ProtoReflect.Descriptor instead.
+func (*SetQuotaReq) Descriptor() ([]byte, []int) {
+	return file_proto_v1_registry_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *SetQuotaReq) GetOwner() string {
+	if x != nil {
+		return x.Owner
 	}
 	return ""
 }
 
+func (x *SetQuotaReq) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *SetQuotaReq) GetMaxFiles() int64 {
+	if x != nil {
+		return x.MaxFiles
+	}
+	return 0
+}
+
+func (x *SetQuotaReq) GetReset_() bool {
+	if x != nil {
+		return x.Reset_
+	}
+	return false
+}
+
 var File_proto_v1_registry_proto protoreflect.FileDescriptor
 
 const file_proto_v1_registry_proto_rawDesc = "" +
 	"\n" +
-	"\x17proto/v1/registry.proto\x12\vregistry.v1\"\x8e\x01\n" +
+	"\x17proto/v1/registry.proto\x12\vregistry.v1\"\xe6\x02\n" +
 	"\x13RegisterFileRequest\x12\x19\n" +
 	"\btmp_name\x18\x01 \x01(\tR\atmpName\x12\x1a\n" +
 	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x1d\n" +
 	"\n" +
 	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12!\n" +
-	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\"1\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x1f\n" +
+	"\vttl_seconds\x18\x05 \x01(\x03R\n" +
+	"ttlSeconds\x12#\n" +
+	"\rmax_downloads\x18\x06 \x01(\x03R\fmaxDownloads\x12\x1a\n" +
+	"\bpassword\x18\a \x01(\tR\bpassword\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\b \x01(\tR\tshortName\x12\x16\n" +
+	"\x06sha256\x18\t \x01(\fR\x06sha256\x12\x10\n" +
+	"\x03md5\x18\n" +
+	" \x01(\fR\x03md5\x12\x15\n" +
+	"\x06key_id\x18\v \x01(\tR\x05keyId\x12\x14\n" +
+	"\x05owner\x18\f \x01(\tR\x05owner\"\\\n" +
+	"\rUploadFileReq\x12+\n" +
+	"\x04meta\x18\x01 \x01(\v2\x15.registry.v1.FileMetaH\x00R\x04meta\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\x06\n" +
+	"\x04data\"\xc0\x02\n" +
+	"\bFileMeta\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x02 \x01(\x03R\tsizeBytes\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12\x1f\n" +
+	"\vttl_seconds\x18\x04 \x01(\x03R\n" +
+	"ttlSeconds\x12#\n" +
+	"\rmax_downloads\x18\x05 \x01(\x03R\fmaxDownloads\x12\x1a\n" +
+	"\bpassword\x18\x06 \x01(\tR\bpassword\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\a \x01(\tR\tshortName\x12\x16\n" +
+	"\x06sha256\x18\b \x01(\fR\x06sha256\x12\x10\n" +
+	"\x03md5\x18\t \x01(\fR\x03md5\x12\x15\n" +
+	"\x06key_id\x18\n" +
+	" \x01(\tR\x05keyId\x12\x14\n" +
+	"\x05owner\x18\v \x01(\tR\x05owner\"T\n" +
 	"\x10RegisterFileResp\x12\x1d\n" +
 	"\n" +
-	"short_name\x18\x01 \x01(\tR\tshortName\"/\n" +
+	"short_name\x18\x01 \x01(\tR\tshortName\x12!\n" +
+	"\fdelete_token\x18\x02 \x01(\tR\vdeleteToken\"K\n" +
 	"\x0eGetFileDataReq\x12\x1d\n" +
 	"\n" +
-	"short_name\x18\x01 \x01(\tR\tshortName\"\x88\x01\n" +
-	"\x0fGetFileDataResp\x12\x17\n" +
-	"\ast_path\x18\x01 \x01(\tR\x06stPath\x12\x1a\n" +
+	"short_name\x18\x01 \x01(\tR\tshortName\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"\xf8\x01\n" +
+	"\x0fGetFileDataResp\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\x03R\texpiresAt\x12#\n" +
+	"\rmax_downloads\x18\x06 \x01(\x03R\fmaxDownloads\x12\x1c\n" +
+	"\tdownloads\x18\a \x01(\x03R\tdownloads\x12\x16\n" +
+	"\x06sha256\x18\b \x01(\tR\x06sha256J\x04\b\x01\x10\x02R\ast_path\"|\n" +
+	"\x0fDownloadFileReq\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\x01 \x01(\tR\tshortName\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\x12\x1a\n" +
+	"\bpassword\x18\x04 \x01(\tR\bpassword\"(\n" +
+	"\x10DownloadFileResp\x12\x14\n" +
+	"\x05chunk\x18\x01 \x01(\fR\x05chunk\"Q\n" +
+	"\rDeleteFileReq\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\x01 \x01(\tR\tshortName\x12!\n" +
+	"\fdelete_token\x18\x02 \x01(\tR\vdeleteToken\"\x10\n" +
+	"\x0eDeleteFileResp\"t\n" +
+	"\x0fUpdateExpiryReq\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\x01 \x01(\tR\tshortName\x12!\n" +
+	"\fdelete_token\x18\x02 \x01(\tR\vdeleteToken\x12\x1f\n" +
+	"\vttl_seconds\x18\x03 \x01(\x03R\n" +
+	"ttlSeconds\"1\n" +
+	"\x10UpdateExpiryResp\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x01 \x01(\x03R\texpiresAt\"\xd7\x01\n" +
+	"\x13CreateCollectionReq\x127\n" +
+	"\amembers\x18\x01 \x03(\v2\x1d.registry.v1.CollectionMemberR\amembers\x12\x1f\n" +
+	"\vttl_seconds\x18\x02 \x01(\x03R\n" +
+	"ttlSeconds\x12\x1a\n" +
+	"\bpassword\x18\x03 \x01(\tR\bpassword\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\x04 \x01(\tR\tshortName\x12\x15\n" +
+	"\x06key_id\x18\x05 \x01(\tR\x05keyId\x12\x14\n" +
+	"\x05owner\x18\x06 \x01(\tR\x05owner\"T\n" +
+	"\x10CollectionMember\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\x01 \x01(\tR\tshortName\x12!\n" +
+	"\fdelete_token\x18\x02 \x01(\tR\vdeleteToken\"M\n" +
+	"\x10GetCollectionReq\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\x01 \x01(\tR\tshortName\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"e\n" +
+	"\x11GetCollectionResp\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x01 \x01(\x03R\texpiresAt\x121\n" +
+	"\x05files\x18\x02 \x03(\v2\x1b.registry.v1.CollectionFileR\x05files\"\xe8\x01\n" +
+	"\x0eCollectionFile\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\x01 \x01(\tR\tshortName\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12#\n" +
+	"\rmax_downloads\x18\x05 \x01(\x03R\fmaxDownloads\x12\x1c\n" +
+	"\tdownloads\x18\x06 \x01(\x03R\tdownloads\x12\x16\n" +
+	"\x06sha256\x18\a \x01(\tR\x06sha256\"\"\n" +
+	"\x0eScrubReportReq\x12\x10\n" +
+	"\x03run\x18\x01 \x01(\bR\x03run\"\xd7\x01\n" +
+	"\x0fScrubReportResp\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x01 \x01(\x03R\tstartedAt\x12\x1f\n" +
+	"\vfinished_at\x18\x02 \x01(\x03R\n" +
+	"finishedAt\x12\x18\n" +
+	"\achecked\x18\x03 \x01(\x03R\achecked\x12\x18\n" +
+	"\amissing\x18\x04 \x01(\x03R\amissing\x12\x1c\n" +
+	"\tcorrupted\x18\x05 \x01(\x03R\tcorrupted\x122\n" +
+	"\adamaged\x18\x06 \x03(\v2\x18.registry.v1.DamagedFileR\adamaged\"{\n" +
+	"\vDamagedFile\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\x01 \x01(\tR\tshortName\x12\x1f\n" +
+	"\vstorage_key\x18\x02 \x01(\tR\n" +
+	"storageKey\x12\x14\n" +
+	"\x05state\x18\x03 \x01(\tR\x05state\x12\x16\n" +
+	"\x06tenant\x18\x04 \x01(\tR\x06tenant\"T\n" +
+	"\x11CollectGarbageReq\x12\x17\n" +
+	"\adry_run\x18\x01 \x01(\bR\x06dryRun\x12&\n" +
+	"\x0fmin_age_seconds\x18\x02 \x01(\x03R\rminAgeSeconds\"\xf5\x01\n" +
+	"\x12CollectGarbageResp\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x01 \x01(\x03R\tstartedAt\x12\x1f\n" +
+	"\vfinished_at\x18\x02 \x01(\x03R\n" +
+	"finishedAt\x12\x17\n" +
+	"\adry_run\x18\x03 \x01(\bR\x06dryRun\x12\x1b\n" +
+	"\ttmp_files\x18\x04 \x03(\tR\btmpFiles\x12!\n" +
+	"\forphan_blobs\x18\x05 \x03(\tR\vorphanBlobs\x12%\n" +
+	"\x0edangling_files\x18\x06 \x03(\tR\rdanglingFiles\x12\x1f\n" +
+	"\vfreed_bytes\x18\a \x01(\x03R\n" +
+	"freedBytes\"#\n" +
+	"\x0fAuthenticateReq\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"\xd6\x01\n" +
+	"\x06ApiKey\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x03 \x01(\x03R\tcreatedAt\x12$\n" +
+	"\x0emax_size_bytes\x18\x04 \x01(\x03R\fmaxSizeBytes\x12&\n" +
+	"\x0fmax_ttl_seconds\x18\x05 \x01(\x03R\rmaxTtlSeconds\x12#\n" +
+	"\rcontent_types\x18\x06 \x03(\tR\fcontentTypes\x12\x16\n" +
+	"\x06tenant\x18\a \x01(\tR\x06tenant\"\xb0\x01\n" +
+	"\x0fCreateApiKeyReq\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12$\n" +
+	"\x0emax_size_bytes\x18\x02 \x01(\x03R\fmaxSizeBytes\x12&\n" +
+	"\x0fmax_ttl_seconds\x18\x03 \x01(\x03R\rmaxTtlSeconds\x12#\n" +
+	"\rcontent_types\x18\x04 \x03(\tR\fcontentTypes\x12\x16\n" +
+	"\x06tenant\x18\x05 \x01(\tR\x06tenant\"Q\n" +
+	"\x10CreateApiKeyResp\x12%\n" +
+	"\x03key\x18\x01 \x01(\v2\x13.registry.v1.ApiKeyR\x03key\x12\x16\n" +
+	"\x06secret\x18\x02 \x01(\tR\x06secret\"\x10\n" +
+	"\x0eListApiKeysReq\":\n" +
+	"\x0fListApiKeysResp\x12'\n" +
+	"\x04keys\x18\x01 \x03(\v2\x13.registry.v1.ApiKeyR\x04keys\"!\n" +
+	"\x0fRevokeApiKeyReq\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x12\n" +
+	"\x10RevokeApiKeyResp\"\xf0\x01\n" +
+	"\fListFilesReq\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\x12\x1f\n" +
+	"\vname_prefix\x18\x02 \x01(\tR\n" +
+	"namePrefix\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\x12#\n" +
+	"\rexpires_after\x18\x04 \x01(\x03R\fexpiresAfter\x12%\n" +
+	"\x0eexpires_before\x18\x05 \x01(\x03R\rexpiresBefore\x12\x1b\n" +
+	"\tpage_size\x18\x06 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\a \x01(\tR\tpageToken\"f\n" +
+	"\rListFilesResp\x12-\n" +
+	"\x05files\x18\x01 \x03(\v2\x17.registry.v1.ListedFileR\x05files\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\xcd\x02\n" +
+	"\n" +
+	"ListedFile\x12\x1d\n" +
+	"\n" +
+	"short_name\x18\x01 \x01(\tR\tshortName\x12\x1a\n" +
 	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x1d\n" +
 	"\n" +
 	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12!\n" +
-	"\fcontent_type\x18\x04 \x01(\tR\vcontentType2\xa3\x01\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\x03R\texpiresAt\x12#\n" +
+	"\rmax_downloads\x18\a \x01(\x03R\fmaxDownloads\x12\x1c\n" +
+	"\tdownloads\x18\b \x01(\x03R\tdownloads\x12\x1c\n" +
+	"\tprotected\x18\t \x01(\bR\tprotected\x12#\n" +
+	"\rcollection_id\x18\n" +
+	" \x01(\tR\fcollectionId\"#\n" +
+	"\vGetUsageReq\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\"\x99\x01\n" +
+	"\x05Usage\x12\x14\n" +
+	"\x05bytes\x18\x01 \x01(\x03R\x05bytes\x12\x14\n" +
+	"\x05files\x18\x02 \x01(\x03R\x05files\x12\x1b\n" +
+	"\tmax_bytes\x18\x03 \x01(\x03R\bmaxBytes\x12\x1b\n" +
+	"\tmax_files\x18\x04 \x01(\x03R\bmaxFiles\x12*\n" +
+	"\x06tenant\x18\x05 \x01(\v2\x12.registry.v1.UsageR\x06tenant\"s\n" +
+	"\vSetQuotaReq\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\x12\x1b\n" +
+	"\tmax_bytes\x18\x02 \x01(\x03R\bmaxBytes\x12\x1b\n" +
+	"\tmax_files\x18\x03 \x01(\x03R\bmaxFiles\x12\x14\n" +
+	"\x05reset\x18\x04 \x01(\bR\x05reset2\xb7\x06\n" +
 	"\n" +
 	"RegService\x12O\n" +
 	"\fRegisterFile\x12 .registry.v1.RegisterFileRequest\x1a\x1d.registry.v1.RegisterFileResp\x12D\n" +
-	"\aGetFile\x12\x1b.registry.v1.GetFileDataReq\x1a\x1c.registry.v1.GetFileDataRespB5Z3github.com/kfcempoyee/gofilesharing/gen/registry/v1b\x06proto3"
+	"\aGetFile\x12\x1b.registry.v1.GetFileDataReq\x1a\x1c.registry.v1.GetFileDataResp\x12I\n" +
+	"\n" +
+	"UploadFile\x12\x1a.registry.v1.UploadFileReq\x1a\x1d.registry.v1.RegisterFileResp(\x01\x12M\n" +
+	"\fDownloadFile\x12\x1c.registry.v1.DownloadFileReq\x1a\x1d.registry.v1.DownloadFileResp0\x01\x12E\n" +
+	"\n" +
+	"DeleteFile\x12\x1a.registry.v1.DeleteFileReq\x1a\x1b.registry.v1.DeleteFileResp\x12K\n" +
+	"\fUpdateExpiry\x12\x1c.registry.v1.UpdateExpiryReq\x1a\x1d.registry.v1.UpdateExpiryResp\x12S\n" +
+	"\x10CreateCollection\x12 .registry.v1.CreateCollectionReq\x1a\x1d.registry.v1.RegisterFileResp\x12N\n" +
+	"\rGetCollection\x12\x1d.registry.v1.GetCollectionReq\x1a\x1e.registry.v1.GetCollectionResp\x12A\n" +
+	"\fAuthenticate\x12\x1c.registry.v1.AuthenticateReq\x1a\x13.registry.v1.ApiKey\x12B\n" +
+	"\tListFiles\x12\x19.registry.v1.ListFilesReq\x1a\x1a.registry.v1.ListFilesResp\x128\n" +
+	"\bGetUsage\x12\x18.registry.v1.GetUsageReq\x1a\x12.registry.v1.Usage2\xc9\x03\n" +
+	"\fAdminService\x12H\n" +
+	"\vScrubReport\x12\x1b.registry.v1.ScrubReportReq\x1a\x1c.registry.v1.ScrubReportResp\x12Q\n" +
+	"\x0eCollectGarbage\x12\x1e.registry.v1.CollectGarbageReq\x1a\x1f.registry.v1.CollectGarbageResp\x12K\n" +
+	"\fCreateApiKey\x12\x1c.registry.v1.CreateApiKeyReq\x1a\x1d.registry.v1.CreateApiKeyResp\x12H\n" +
+	"\vListApiKeys\x12\x1b.registry.v1.ListApiKeysReq\x1a\x1c.registry.v1.ListApiKeysResp\x12K\n" +
+	"\fRevokeApiKey\x12\x1c.registry.v1.RevokeApiKeyReq\x1a\x1d.registry.v1.RevokeApiKeyResp\x128\n" +
+	"\bSetQuota\x12\x18.registry.v1.SetQuotaReq\x1a\x12.registry.v1.UsageB5Z3github.com/kfcempoyee/gofilesharing/gen/registry/v1b\x06proto3"
 
 var (
 	file_proto_v1_registry_proto_rawDescOnce sync.Once
@@ -285,23 +2665,93 @@ func file_proto_v1_registry_proto_rawDescGZIP() []byte {
 	return file_proto_v1_registry_proto_rawDescData
 }
 
-var file_proto_v1_registry_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_v1_registry_proto_msgTypes = make([]protoimpl.MessageInfo, 36)
 var file_proto_v1_registry_proto_goTypes = []any{
 	(*RegisterFileRequest)(nil), // 0: registry.v1.RegisterFileRequest
-	(*RegisterFileResp)(nil),    // 1: registry.v1.RegisterFileResp
-	(*GetFileDataReq)(nil),      // 2: registry.v1.GetFileDataReq
-	(*GetFileDataResp)(nil),     // 3: registry.v1.GetFileDataResp
+	(*UploadFileReq)(nil),       // 1: registry.v1.UploadFileReq
+	(*FileMeta)(nil),            // 2: registry.v1.FileMeta
+	(*RegisterFileResp)(nil),    // 3: registry.v1.RegisterFileResp
+	(*GetFileDataReq)(nil),      // 4: registry.v1.GetFileDataReq
+	(*GetFileDataResp)(nil),     // 5: registry.v1.GetFileDataResp
+	(*DownloadFileReq)(nil),     // 6: registry.v1.DownloadFileReq
+	(*DownloadFileResp)(nil),    // 7: registry.v1.DownloadFileResp
+	(*DeleteFileReq)(nil),       // 8: registry.v1.DeleteFileReq
+	(*DeleteFileResp)(nil),      // 9: registry.v1.DeleteFileResp
+	(*UpdateExpiryReq)(nil),     // 10: registry.v1.UpdateExpiryReq
+	(*UpdateExpiryResp)(nil),    // 11: registry.v1.UpdateExpiryResp
+	(*CreateCollectionReq)(nil), // 12: registry.v1.CreateCollectionReq
+	(*CollectionMember)(nil),    // 13: registry.v1.CollectionMember
+	(*GetCollectionReq)(nil),    // 14: registry.v1.GetCollectionReq
+	(*GetCollectionResp)(nil),   // 15: registry.v1.GetCollectionResp
+	(*CollectionFile)(nil),      // 16: registry.v1.CollectionFile
+	(*ScrubReportReq)(nil),      // 17: registry.v1.ScrubReportReq
+	(*ScrubReportResp)(nil),     // 18: registry.v1.ScrubReportResp
+	(*DamagedFile)(nil),         // 19: registry.v1.DamagedFile
+	(*CollectGarbageReq)(nil),   // 20: registry.v1.CollectGarbageReq
+	(*CollectGarbageResp)(nil),  // 21: registry.v1.CollectGarbageResp
+	(*AuthenticateReq)(nil),     // 22: registry.v1.AuthenticateReq
+	(*ApiKey)(nil),              // 23: registry.v1.ApiKey
+	(*CreateApiKeyReq)(nil),     // 24: registry.v1.CreateApiKeyReq
+	(*CreateApiKeyResp)(nil),    // 25: registry.v1.CreateApiKeyResp
+	(*ListApiKeysReq)(nil),      // 26: registry.v1.ListApiKeysReq
+	(*ListApiKeysResp)(nil),     // 27: registry.v1.ListApiKeysResp
+	(*RevokeApiKeyReq)(nil),     // 28: registry.v1.RevokeApiKeyReq
+	(*RevokeApiKeyResp)(nil),    // 29: registry.v1.RevokeApiKeyResp
+	(*ListFilesReq)(nil),        // 30: registry.v1.ListFilesReq
+	(*ListFilesResp)(nil),       // 31: registry.v1.ListFilesResp
+	(*ListedFile)(nil),          // 32: registry.v1.ListedFile
+	(*GetUsageReq)(nil),         // 33: registry.v1.GetUsageReq
+	(*Usage)(nil),               // 34: registry.v1.Usage
+	(*SetQuotaReq)(nil),         // 35: registry.v1.SetQuotaReq
 }
 var file_proto_v1_registry_proto_depIdxs = []int32{
-	0, // 0: registry.v1.RegService.RegisterFile:input_type -> registry.v1.RegisterFileRequest
-	2, // 1: registry.v1.RegService.GetFile:input_type -> registry.v1.GetFileDataReq
-	1, // 2: registry.v1.RegService.RegisterFile:output_type -> registry.v1.RegisterFileResp
-	3, // 3: registry.v1.RegService.GetFile:output_type -> registry.v1.GetFileDataResp
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2,  // 0: registry.v1.UploadFileReq.meta:type_name -> registry.v1.FileMeta
+	13, // 1: registry.v1.CreateCollectionReq.members:type_name -> registry.v1.CollectionMember
+	16, // 2: registry.v1.GetCollectionResp.files:type_name -> registry.v1.CollectionFile
+	19, // 3: registry.v1.ScrubReportResp.damaged:type_name -> registry.v1.DamagedFile
+	23, // 4: registry.v1.CreateApiKeyResp.key:type_name -> registry.v1.ApiKey
+	23, // 5: registry.v1.ListApiKeysResp.keys:type_name -> registry.v1.ApiKey
+	32, // 6: registry.v1.ListFilesResp.files:type_name -> registry.v1.ListedFile
+	34, // 7: registry.v1.Usage.tenant:type_name -> registry.v1.Usage
+	0,  // 8: registry.v1.RegService.RegisterFile:input_type -> registry.v1.RegisterFileRequest
+	4,  // 9: registry.v1.RegService.GetFile:input_type -> registry.v1.GetFileDataReq
+	1,  // 10: registry.v1.RegService.UploadFile:input_type -> registry.v1.UploadFileReq
+	6,  // 11: registry.v1.RegService.DownloadFile:input_type -> registry.v1.DownloadFileReq
+	8,  // 12: registry.v1.RegService.DeleteFile:input_type -> registry.v1.DeleteFileReq
+	10, // 13: registry.v1.RegService.UpdateExpiry:input_type -> registry.v1.UpdateExpiryReq
+	12, // 14: registry.v1.RegService.CreateCollection:input_type -> registry.v1.CreateCollectionReq
+	14, // 15: registry.v1.RegService.GetCollection:input_type -> registry.v1.GetCollectionReq
+	22, // 16: registry.v1.RegService.Authenticate:input_type -> registry.v1.AuthenticateReq
+	30, // 17: registry.v1.RegService.ListFiles:input_type -> registry.v1.ListFilesReq
+	33, // 18: registry.v1.RegService.GetUsage:input_type -> registry.v1.GetUsageReq
+	17, // 19: registry.v1.AdminService.ScrubReport:input_type -> registry.v1.ScrubReportReq
+	20, // 20: registry.v1.AdminService.CollectGarbage:input_type -> registry.v1.CollectGarbageReq
+	24, // 21: registry.v1.AdminService.CreateApiKey:input_type -> registry.v1.CreateApiKeyReq
+	26, // 22: registry.v1.AdminService.ListApiKeys:input_type -> registry.v1.ListApiKeysReq
+	28, // 23: registry.v1.AdminService.RevokeApiKey:input_type -> registry.v1.RevokeApiKeyReq
+	35, // 24: registry.v1.AdminService.SetQuota:input_type -> registry.v1.SetQuotaReq
+	3,  // 25: registry.v1.RegService.RegisterFile:output_type -> registry.v1.RegisterFileResp
+	5,  // 26: registry.v1.RegService.GetFile:output_type -> registry.v1.GetFileDataResp
+	3,  // 27: registry.v1.RegService.UploadFile:output_type -> registry.v1.RegisterFileResp
+	7,  // 28: registry.v1.RegService.DownloadFile:output_type -> registry.v1.DownloadFileResp
+	9,  // 29: registry.v1.RegService.DeleteFile:output_type -> registry.v1.DeleteFileResp
+	11, // 30: registry.v1.RegService.UpdateExpiry:output_type -> registry.v1.UpdateExpiryResp
+	3,  // 31: registry.v1.RegService.CreateCollection:output_type -> registry.v1.RegisterFileResp
+	15, // 32: registry.v1.RegService.GetCollection:output_type -> registry.v1.GetCollectionResp
+	23, // 33: registry.v1.RegService.Authenticate:output_type -> registry.v1.ApiKey
+	31, // 34: registry.v1.RegService.ListFiles:output_type -> registry.v1.ListFilesResp
+	34, // 35: registry.v1.RegService.GetUsage:output_type -> registry.v1.Usage
+	18, // 36: registry.v1.AdminService.ScrubReport:output_type -> registry.v1.ScrubReportResp
+	21, // 37: registry.v1.AdminService.CollectGarbage:output_type -> registry.v1.CollectGarbageResp
+	25, // 38: registry.v1.AdminService.CreateApiKey:output_type -> registry.v1.CreateApiKeyResp
+	27, // 39: registry.v1.AdminService.ListApiKeys:output_type -> registry.v1.ListApiKeysResp
+	29, // 40: registry.v1.AdminService.RevokeApiKey:output_type -> registry.v1.RevokeApiKeyResp
+	34, // 41: registry.v1.AdminService.SetQuota:output_type -> registry.v1.Usage
+	25, // [25:42] is the sub-list for method output_type
+	8,  // [8:25] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_proto_v1_registry_proto_init() }
@@ -309,15 +2759,19 @@ func file_proto_v1_registry_proto_init() {
 	if File_proto_v1_registry_proto != nil {
 		return
 	}
+	file_proto_v1_registry_proto_msgTypes[1].OneofWrappers = []any{
+		(*UploadFileReq_Meta)(nil),
+		(*UploadFileReq_Chunk)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_v1_registry_proto_rawDesc), len(file_proto_v1_registry_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   36,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_proto_v1_registry_proto_goTypes,
 		DependencyIndexes: file_proto_v1_registry_proto_depIdxs,
@@ -19,16 +19,37 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	RegService_RegisterFile_FullMethodName = "/registry.v1.RegService/RegisterFile"
-	RegService_GetFile_FullMethodName      = "/registry.v1.RegService/GetFile"
+	RegService_RegisterFile_FullMethodName     = "/registry.v1.RegService/RegisterFile"
+	RegService_GetFile_FullMethodName          = "/registry.v1.RegService/GetFile"
+	RegService_UploadFile_FullMethodName       = "/registry.v1.RegService/UploadFile"
+	RegService_DownloadFile_FullMethodName     = "/registry.v1.RegService/DownloadFile"
+	RegService_DeleteFile_FullMethodName       = "/registry.v1.RegService/DeleteFile"
+	RegService_UpdateExpiry_FullMethodName     = "/registry.v1.RegService/UpdateExpiry"
+	RegService_CreateCollection_FullMethodName = "/registry.v1.RegService/CreateCollection"
+	RegService_GetCollection_FullMethodName    = "/registry.v1.RegService/GetCollection"
+	RegService_Authenticate_FullMethodName     = "/registry.v1.RegService/Authenticate"
+	RegService_ListFiles_FullMethodName        = "/registry.v1.RegService/ListFiles"
+	RegService_GetUsage_FullMethodName         = "/registry.v1.RegService/GetUsage"
 )
 
 // RegServiceClient is the client API for RegService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// вызовы идут в пространстве арендатора из метаданных tenant, без них - в пространстве по умолчанию.
+// неизвестный арендатор - NOT_FOUND
 type RegServiceClient interface {
 	RegisterFile(ctx context.Context, in *RegisterFileRequest, opts ...grpc.CallOption) (*RegisterFileResp, error)
 	GetFile(ctx context.Context, in *GetFileDataReq, opts ...grpc.CallOption) (*GetFileDataResp, error)
+	UploadFile(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadFileReq, RegisterFileResp], error)
+	DownloadFile(ctx context.Context, in *DownloadFileReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DownloadFileResp], error)
+	DeleteFile(ctx context.Context, in *DeleteFileReq, opts ...grpc.CallOption) (*DeleteFileResp, error)
+	UpdateExpiry(ctx context.Context, in *UpdateExpiryReq, opts ...grpc.CallOption) (*UpdateExpiryResp, error)
+	CreateCollection(ctx context.Context, in *CreateCollectionReq, opts ...grpc.CallOption) (*RegisterFileResp, error)
+	GetCollection(ctx context.Context, in *GetCollectionReq, opts ...grpc.CallOption) (*GetCollectionResp, error)
+	Authenticate(ctx context.Context, in *AuthenticateReq, opts ...grpc.CallOption) (*ApiKey, error)
+	ListFiles(ctx context.Context, in *ListFilesReq, opts ...grpc.CallOption) (*ListFilesResp, error)
+	GetUsage(ctx context.Context, in *GetUsageReq, opts ...grpc.CallOption) (*Usage, error)
 }
 
 type regServiceClient struct {
@@ -59,12 +80,126 @@ func (c *regServiceClient) GetFile(ctx context.Context, in *GetFileDataReq, opts
 	return out, nil
 }
 
+func (c *regServiceClient) UploadFile(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadFileReq, RegisterFileResp], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RegService_ServiceDesc.Streams[0], RegService_UploadFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadFileReq, RegisterFileResp]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RegService_UploadFileClient = grpc.ClientStreamingClient[UploadFileReq, RegisterFileResp]
+
+func (c *regServiceClient) DownloadFile(ctx context.Context, in *DownloadFileReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DownloadFileResp], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RegService_ServiceDesc.Streams[1], RegService_DownloadFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DownloadFileReq, DownloadFileResp]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RegService_DownloadFileClient = grpc.ServerStreamingClient[DownloadFileResp]
+
+func (c *regServiceClient) DeleteFile(ctx context.Context, in *DeleteFileReq, opts ...grpc.CallOption) (*DeleteFileResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteFileResp)
+	err := c.cc.Invoke(ctx, RegService_DeleteFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *regServiceClient) UpdateExpiry(ctx context.Context, in *UpdateExpiryReq, opts ...grpc.CallOption) (*UpdateExpiryResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateExpiryResp)
+	err := c.cc.Invoke(ctx, RegService_UpdateExpiry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *regServiceClient) CreateCollection(ctx context.Context, in *CreateCollectionReq, opts ...grpc.CallOption) (*RegisterFileResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterFileResp)
+	err := c.cc.Invoke(ctx, RegService_CreateCollection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *regServiceClient) GetCollection(ctx context.Context, in *GetCollectionReq, opts ...grpc.CallOption) (*GetCollectionResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCollectionResp)
+	err := c.cc.Invoke(ctx, RegService_GetCollection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *regServiceClient) Authenticate(ctx context.Context, in *AuthenticateReq, opts ...grpc.CallOption) (*ApiKey, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ApiKey)
+	err := c.cc.Invoke(ctx, RegService_Authenticate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *regServiceClient) ListFiles(ctx context.Context, in *ListFilesReq, opts ...grpc.CallOption) (*ListFilesResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFilesResp)
+	err := c.cc.Invoke(ctx, RegService_ListFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *regServiceClient) GetUsage(ctx context.Context, in *GetUsageReq, opts ...grpc.CallOption) (*Usage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Usage)
+	err := c.cc.Invoke(ctx, RegService_GetUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RegServiceServer is the server API for RegService service.
 // All implementations must embed UnimplementedRegServiceServer
 // for forward compatibility.
+//
+// вызовы идут в пространстве арендатора из метаданных tenant, без них - в пространстве по умолчанию.
+// неизвестный арендатор - NOT_FOUND
 type RegServiceServer interface {
 	RegisterFile(context.Context, *RegisterFileRequest) (*RegisterFileResp, error)
 	GetFile(context.Context, *GetFileDataReq) (*GetFileDataResp, error)
+	UploadFile(grpc.ClientStreamingServer[UploadFileReq, RegisterFileResp]) error
+	DownloadFile(*DownloadFileReq, grpc.ServerStreamingServer[DownloadFileResp]) error
+	DeleteFile(context.Context, *DeleteFileReq) (*DeleteFileResp, error)
+	UpdateExpiry(context.Context, *UpdateExpiryReq) (*UpdateExpiryResp, error)
+	CreateCollection(context.Context, *CreateCollectionReq) (*RegisterFileResp, error)
+	GetCollection(context.Context, *GetCollectionReq) (*GetCollectionResp, error)
+	Authenticate(context.Context, *AuthenticateReq) (*ApiKey, error)
+	ListFiles(context.Context, *ListFilesReq) (*ListFilesResp, error)
+	GetUsage(context.Context, *GetUsageReq) (*Usage, error)
 	mustEmbedUnimplementedRegServiceServer()
 }
 
@@ -81,6 +216,33 @@ func (UnimplementedRegServiceServer) RegisterFile(context.Context, *RegisterFile
 func (UnimplementedRegServiceServer) GetFile(context.Context, *GetFileDataReq) (*GetFileDataResp, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetFile not implemented")
 }
+func (UnimplementedRegServiceServer) UploadFile(grpc.ClientStreamingServer[UploadFileReq, RegisterFileResp]) error {
+	return status.Error(codes.Unimplemented, "method UploadFile not implemented")
+}
+func (UnimplementedRegServiceServer) DownloadFile(*DownloadFileReq, grpc.ServerStreamingServer[DownloadFileResp]) error {
+	return status.Error(codes.Unimplemented, "method DownloadFile not implemented")
+}
+func (UnimplementedRegServiceServer) DeleteFile(context.Context, *DeleteFileReq) (*DeleteFileResp, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteFile not implemented")
+}
+func (UnimplementedRegServiceServer) UpdateExpiry(context.Context, *UpdateExpiryReq) (*UpdateExpiryResp, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateExpiry not implemented")
+}
+func (UnimplementedRegServiceServer) CreateCollection(context.Context, *CreateCollectionReq) (*RegisterFileResp, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateCollection not implemented")
+}
+func (UnimplementedRegServiceServer) GetCollection(context.Context, *GetCollectionReq) (*GetCollectionResp, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCollection not implemented")
+}
+func (UnimplementedRegServiceServer) Authenticate(context.Context, *AuthenticateReq) (*ApiKey, error) {
+	return nil, status.Error(codes.Unimplemented, "method Authenticate not implemented")
+}
+func (UnimplementedRegServiceServer) ListFiles(context.Context, *ListFilesReq) (*ListFilesResp, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListFiles not implemented")
+}
+func (UnimplementedRegServiceServer) GetUsage(context.Context, *GetUsageReq) (*Usage, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetUsage not implemented")
+}
 func (UnimplementedRegServiceServer) mustEmbedUnimplementedRegServiceServer() {}
 func (UnimplementedRegServiceServer) testEmbeddedByValue()                    {}
 
@@ -138,6 +300,150 @@ func _RegService_GetFile_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RegService_UploadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RegServiceServer).UploadFile(&grpc.GenericServerStream[UploadFileReq, RegisterFileResp]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RegService_UploadFileServer = grpc.ClientStreamingServer[UploadFileReq, RegisterFileResp]
+
+func _RegService_DownloadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadFileReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RegServiceServer).DownloadFile(m, &grpc.GenericServerStream[DownloadFileReq, DownloadFileResp]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RegService_DownloadFileServer = grpc.ServerStreamingServer[DownloadFileResp]
+
+func _RegService_DeleteFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteFileReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegServiceServer).DeleteFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegService_DeleteFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegServiceServer).DeleteFile(ctx, req.(*DeleteFileReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegService_UpdateExpiry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateExpiryReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegServiceServer).UpdateExpiry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegService_UpdateExpiry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegServiceServer).UpdateExpiry(ctx, req.(*UpdateExpiryReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegService_CreateCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCollectionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegServiceServer).CreateCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegService_CreateCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegServiceServer).CreateCollection(ctx, req.(*CreateCollectionReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegService_GetCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCollectionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegServiceServer).GetCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegService_GetCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegServiceServer).GetCollection(ctx, req.(*GetCollectionReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegService_Authenticate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthenticateReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegServiceServer).Authenticate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegService_Authenticate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegServiceServer).Authenticate(ctx, req.(*AuthenticateReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegService_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilesReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegServiceServer).ListFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegService_ListFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegServiceServer).ListFiles(ctx, req.(*ListFilesReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegService_GetUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsageReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegServiceServer).GetUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RegService_GetUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegServiceServer).GetUsage(ctx, req.(*GetUsageReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // RegService_ServiceDesc is the grpc.ServiceDesc for RegService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,6 +459,343 @@ var RegService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetFile",
 			Handler:    _RegService_GetFile_Handler,
 		},
+		{
+			MethodName: "DeleteFile",
+			Handler:    _RegService_DeleteFile_Handler,
+		},
+		{
+			MethodName: "UpdateExpiry",
+			Handler:    _RegService_UpdateExpiry_Handler,
+		},
+		{
+			MethodName: "CreateCollection",
+			Handler:    _RegService_CreateCollection_Handler,
+		},
+		{
+			MethodName: "GetCollection",
+			Handler:    _RegService_GetCollection_Handler,
+		},
+		{
+			MethodName: "Authenticate",
+			Handler:    _RegService_Authenticate_Handler,
+		},
+		{
+			MethodName: "ListFiles",
+			Handler:    _RegService_ListFiles_Handler,
+		},
+		{
+			MethodName: "GetUsage",
+			Handler:    _RegService_GetUsage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadFile",
+			Handler:       _RegService_UploadFile_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadFile",
+			Handler:       _RegService_DownloadFile_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/v1/registry.proto",
+}
+
+const (
+	AdminService_ScrubReport_FullMethodName    = "/registry.v1.AdminService/ScrubReport"
+	AdminService_CollectGarbage_FullMethodName = "/registry.v1.AdminService/CollectGarbage"
+	AdminService_CreateApiKey_FullMethodName   = "/registry.v1.AdminService/CreateApiKey"
+	AdminService_ListApiKeys_FullMethodName    = "/registry.v1.AdminService/ListApiKeys"
+	AdminService_RevokeApiKey_FullMethodName   = "/registry.v1.AdminService/RevokeApiKey"
+	AdminService_SetQuota_FullMethodName       = "/registry.v1.AdminService/SetQuota"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// служебные вызовы для администратора, гейтвей их не использует. реестр отдает их
+// на отдельном адресе admin_listen, а не на общем с гейтвеем
+type AdminServiceClient interface {
+	ScrubReport(ctx context.Context, in *ScrubReportReq, opts ...grpc.CallOption) (*ScrubReportResp, error)
+	CollectGarbage(ctx context.Context, in *CollectGarbageReq, opts ...grpc.CallOption) (*CollectGarbageResp, error)
+	CreateApiKey(ctx context.Context, in *CreateApiKeyReq, opts ...grpc.CallOption) (*CreateApiKeyResp, error)
+	ListApiKeys(ctx context.Context, in *ListApiKeysReq, opts ...grpc.CallOption) (*ListApiKeysResp, error)
+	RevokeApiKey(ctx context.Context, in *RevokeApiKeyReq, opts ...grpc.CallOption) (*RevokeApiKeyResp, error)
+	SetQuota(ctx context.Context, in *SetQuotaReq, opts ...grpc.CallOption) (*Usage, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) ScrubReport(ctx context.Context, in *ScrubReportReq, opts ...grpc.CallOption) (*ScrubReportResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScrubReportResp)
+	err := c.cc.Invoke(ctx, AdminService_ScrubReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) CollectGarbage(ctx context.Context, in *CollectGarbageReq, opts ...grpc.CallOption) (*CollectGarbageResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CollectGarbageResp)
+	err := c.cc.Invoke(ctx, AdminService_CollectGarbage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) CreateApiKey(ctx context.Context, in *CreateApiKeyReq, opts ...grpc.CallOption) (*CreateApiKeyResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateApiKeyResp)
+	err := c.cc.Invoke(ctx, AdminService_CreateApiKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ListApiKeys(ctx context.Context, in *ListApiKeysReq, opts ...grpc.CallOption) (*ListApiKeysResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListApiKeysResp)
+	err := c.cc.Invoke(ctx, AdminService_ListApiKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RevokeApiKey(ctx context.Context, in *RevokeApiKeyReq, opts ...grpc.CallOption) (*RevokeApiKeyResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeApiKeyResp)
+	err := c.cc.Invoke(ctx, AdminService_RevokeApiKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) SetQuota(ctx context.Context, in *SetQuotaReq, opts ...grpc.CallOption) (*Usage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Usage)
+	err := c.cc.Invoke(ctx, AdminService_SetQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility.
+//
+// служебные вызовы для администратора, гейтвей их не использует. реестр отдает их
+// на отдельном адресе admin_listen, а не на общем с гейтвеем
+type AdminServiceServer interface {
+	ScrubReport(context.Context, *ScrubReportReq) (*ScrubReportResp, error)
+	CollectGarbage(context.Context, *CollectGarbageReq) (*CollectGarbageResp, error)
+	CreateApiKey(context.Context, *CreateApiKeyReq) (*CreateApiKeyResp, error)
+	ListApiKeys(context.Context, *ListApiKeysReq) (*ListApiKeysResp, error)
+	RevokeApiKey(context.Context, *RevokeApiKeyReq) (*RevokeApiKeyResp, error)
+	SetQuota(context.Context, *SetQuotaReq) (*Usage, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServiceServer struct{}
+
+func (UnimplementedAdminServiceServer) ScrubReport(context.Context, *ScrubReportReq) (*ScrubReportResp, error) {
+	return nil, status.Error(codes.Unimplemented, "method ScrubReport not implemented")
+}
+func (UnimplementedAdminServiceServer) CollectGarbage(context.Context, *CollectGarbageReq) (*CollectGarbageResp, error) {
+	return nil, status.Error(codes.Unimplemented, "method CollectGarbage not implemented")
+}
+func (UnimplementedAdminServiceServer) CreateApiKey(context.Context, *CreateApiKeyReq) (*CreateApiKeyResp, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateApiKey not implemented")
+}
+func (UnimplementedAdminServiceServer) ListApiKeys(context.Context, *ListApiKeysReq) (*ListApiKeysResp, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListApiKeys not implemented")
+}
+func (UnimplementedAdminServiceServer) RevokeApiKey(context.Context, *RevokeApiKeyReq) (*RevokeApiKeyResp, error) {
+	return nil, status.Error(codes.Unimplemented, "method RevokeApiKey not implemented")
+}
+func (UnimplementedAdminServiceServer) SetQuota(context.Context, *SetQuotaReq) (*Usage, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetQuota not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+func (UnimplementedAdminServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	// If the following call panics, it indicates UnimplementedAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_ScrubReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScrubReportReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ScrubReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_ScrubReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ScrubReport(ctx, req.(*ScrubReportReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_CollectGarbage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectGarbageReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).CollectGarbage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_CollectGarbage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).CollectGarbage(ctx, req.(*CollectGarbageReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_CreateApiKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateApiKeyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).CreateApiKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_CreateApiKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).CreateApiKey(ctx, req.(*CreateApiKeyReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ListApiKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListApiKeysReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListApiKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_ListApiKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListApiKeys(ctx, req.(*ListApiKeysReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RevokeApiKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeApiKeyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RevokeApiKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_RevokeApiKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RevokeApiKey(ctx, req.(*RevokeApiKeyReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_SetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetQuotaReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_SetQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetQuota(ctx, req.(*SetQuotaReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "registry.v1.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ScrubReport",
+			Handler:    _AdminService_ScrubReport_Handler,
+		},
+		{
+			MethodName: "CollectGarbage",
+			Handler:    _AdminService_CollectGarbage_Handler,
+		},
+		{
+			MethodName: "CreateApiKey",
+			Handler:    _AdminService_CreateApiKey_Handler,
+		},
+		{
+			MethodName: "ListApiKeys",
+			Handler:    _AdminService_ListApiKeys_Handler,
+		},
+		{
+			MethodName: "RevokeApiKey",
+			Handler:    _AdminService_RevokeApiKey_Handler,
+		},
+		{
+			MethodName: "SetQuota",
+			Handler:    _AdminService_SetQuota_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/v1/registry.proto",
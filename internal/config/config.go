@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// настройки реестра и гейтвея. значения собираются по порядку: умолчания, yaml-файл
+// (флаг -config или переменная CONFIG), переменные окружения, флаги командной строки -
+// каждый следующий источник перекрывает предыдущий. имя переменной окружения получается
+// из имени флага: -s3-endpoint - S3_ENDPOINT
+
+// настройки одного бинарника: умеют описать себя флагами и проверить себя
+type settings interface {
+	flags(fs *flag.FlagSet)
+	Validate() error
+}
+
+// команда перед флагами: пусто - запустить сервер, dump-config - напечатать итоговые настройки
+func Command(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+
+	return "", args
+}
+
+// собрать настройки c из всех источников. reset возвращает c к умолчаниям: флаги разбираются
+// дважды - сначала ради пути к файлу, потом поверх файла и окружения
+func load(name string, args []string, c settings, reset func()) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("CONFIG"), "path to a YAML config file")
+	c.flags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	reset()
+
+	if *path != "" {
+		if err := readFile(*path, c); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+
+		name := envName(f.Name)
+		if v, ok := os.LookupEnv(name); ok {
+			if err := f.Value.Set(v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %w", name, err))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return c.Validate()
+}
+
+// неизвестные ключи - ошибка: опечатка в имени настройки не должна молча оставлять умолчание
+func readFile(path string, c settings) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func envName(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// напечатать итоговые настройки в том же виде, в каком их читает -config. секреты скрыты
+func Dump(w io.Writer, c any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// длительность в человеческом виде (90s, 48h) и в yaml, и во флагах
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalYAML() (any, error) {
+	return d.String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.Set(node.Value)
+}
+
+// список через запятую во флагах и обычный список в yaml
+type List []string
+
+func (l List) String() string {
+	return strings.Join(l, ",")
+}
+
+func (l *List) Set(s string) error {
+	*l = nil
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+
+	return nil
+}
+
+// строка, которую нельзя показывать: ни в выводе dump-config, ни в справке по флагам
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return "***"
+}
+
+func (s *Secret) Set(v string) error {
+	*s = Secret(v)
+	return nil
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
+
+// адрес вида host:port, хост можно не указывать
+func checkAddr(name, addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfig(t, `
+listen: ":6000"
+db: file.db
+id_length: 8
+storage:
+  dir: /srv/files
+gc_interval: 2h
+`)
+
+	t.Setenv("CONFIG", path)
+	t.Setenv("DB", "env.db")
+	t.Setenv("ID_LENGTH", "9")
+
+	c, err := LoadRegistry([]string{"-id-length", "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// умолчание, файл, окружение поверх файла, флаг поверх окружения
+	if c.TmpDir != "data/tmp" || c.Listen != ":6000" || c.Storage.Dir != "/srv/files" {
+		t.Errorf("defaults or file not applied: %+v", c)
+	}
+	if c.DB != "env.db" {
+		t.Errorf("db = %q, want env.db", c.DB)
+	}
+	if c.IDLength != 10 {
+		t.Errorf("id_length = %d, want 10", c.IDLength)
+	}
+	if time.Duration(c.GCInterval) != 2*time.Hour {
+		t.Errorf("gc_interval = %v, want 2h", c.GCInterval)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		file string
+		want string
+	}{
+		{name: "unknown key", file: "lisen: \":1\"\n", want: "field lisen not found"},
+		{name: "bad duration", args: []string{"-max-ttl", "week"}, want: "invalid value"},
+		{name: "bad address", args: []string{"-listen", "50051"}, want: "listen"},
+		{name: "ttl over max", args: []string{"-default-ttl", "48h", "-max-ttl", "1h"}, want: "default_ttl must not exceed max_ttl"},
+		{name: "s3 without bucket", args: []string{"-s3-endpoint", "http://minio:9000"}, want: "storage.s3.bucket"},
+		{name: "extra argument", args: []string{"serve"}, want: "unexpected argument"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfig(t, tt.file)}, args...)
+			}
+
+			_, err := LoadRegistry(args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDump_HidesSecrets(t *testing.T) {
+	t.Setenv("S3_SECRET_KEY", "very-secret")
+
+	c, err := LoadRegistry([]string{"-s3-endpoint", "https://s3.example.com", "-s3-bucket", "files", "-s3-access-key", "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Storage.S3.SecretKey != "very-secret" {
+		t.Fatalf("secret key not loaded")
+	}
+
+	var buf bytes.Buffer
+	if err := Dump(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "very-secret") || !strings.Contains(out, `secret_key: '***'`) {
+		t.Errorf("secret leaked or missing:\n%s", out)
+	}
+	if !strings.Contains(out, "default_ttl: 48h0m0s") {
+		t.Errorf("durations not human-readable:\n%s", out)
+	}
+}
+
+func TestGateway_Defaults(t *testing.T) {
+	c, err := LoadGateway(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// гейтвей по умолчанию должен попадать в реестр с настройками по умолчанию
+	if _, port, _ := strings.Cut(c.Registry, ":"); ":"+port != DefaultRegistry().Listen {
+		t.Errorf("registry = %q, registry listens on %q", c.Registry, DefaultRegistry().Listen)
+	}
+}
+
+func TestGateway_JWTNeedsIssuerAndAudience(t *testing.T) {
+	_, err := LoadGateway([]string{"-jwt-jwks", "jwks.json", "-jwt-issuer", "https://issuer.test"})
+	if err == nil || !strings.Contains(err.Error(), "jwt.issuer and jwt.audience") {
+		t.Fatalf("err = %v, want a jwt error", err)
+	}
+
+	t.Setenv("JWT_AUDIENCE", "gofilesharing")
+	c, err := LoadGateway([]string{"-jwt-jwks", "jwks.json", "-jwt-issuer", "https://issuer.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.JWT.Audience != "gofilesharing" {
+		t.Errorf("audience = %q, want it from JWT_AUDIENCE", c.JWT.Audience)
+	}
+}
+
+func TestRegistry_Tenants(t *testing.T) {
+	path := writeConfig(t, `
+tenants:
+  sales:
+    max_size: 1048576
+    max_ttl: 24h
+    content_types: [application/pdf, image/*]
+  hr: {}
+`)
+
+	c, err := LoadRegistry([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.Tenants["sales"]; s.MaxSize != 1<<20 || time.Duration(s.MaxTTL) != 24*time.Hour || len(s.ContentTypes) != 2 {
+		t.Errorf("sales = %+v", s)
+	}
+	if _, ok := c.Tenants["hr"]; !ok {
+		t.Errorf("tenant without settings is missing")
+	}
+
+	_, err = LoadRegistry([]string{"-config", writeConfig(t, "tenants:\n  Sales: {}\n")})
+	if err == nil || !strings.Contains(err.Error(), `name "Sales"`) {
+		t.Errorf("err = %v, want an invalid name", err)
+	}
+}
+
+func TestRegistry_AdminListen(t *testing.T) {
+	c, err := LoadRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// по умолчанию администрирование доступно только с этой машины
+	if host, _, _ := strings.Cut(c.AdminListen, ":"); host != "127.0.0.1" {
+		t.Errorf("admin_listen = %q, want a loopback address", c.AdminListen)
+	}
+
+	_, err = LoadRegistry([]string{"-listen", ":9000", "-admin-listen", ":9000"})
+	if err == nil || !strings.Contains(err.Error(), "admin_listen must differ") {
+		t.Errorf("err = %v, want a shared listener error", err)
+	}
+}
+
+func TestGateway_RateLimit(t *testing.T) {
+	path := writeConfig(t, `
+rate_limit:
+  download: {per_minute: 30, burst: 5}
+  trusted_proxies: [10.0.0.0/8]
+`)
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1")
+	c, err := LoadGateway([]string{"-config", path, "-rate-info", "0", "-rate-auth", "30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RateLimit.Download != (Rate{PerMinute: 30, Burst: 5}) || c.RateLimit.Info.PerMinute != 0 || c.RateLimit.Upload.PerMinute != 20 || c.RateLimit.Auth.PerMinute != 30 {
+		t.Errorf("rate_limit = %+v", c.RateLimit)
+	}
+
+	proxies, err := ParsePrefixes(c.RateLimit.TrustedProxies)
+	if err != nil || len(proxies) != 2 || proxies[1].String() != "192.168.1.1/32" {
+		t.Errorf("trusted proxies = %v, %v", proxies, err)
+	}
+
+	_, err = LoadGateway([]string{"-trusted-proxies", "10.0.0.0/33"})
+	if err == nil || !strings.Contains(err.Error(), "rate_limit.trusted_proxies") {
+		t.Errorf("err = %v, want an invalid proxy", err)
+	}
+}
+
+func TestGateway_TenantHosts(t *testing.T) {
+	path := writeConfig(t, `
+tenants:
+  hosts:
+    Files.Sales.Example: sales
+    hr.example: hr
+`)
+
+	c, err := LoadGateway([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// хост запроса сравнивается в нижнем регистре, ключи из файла должны ему соответствовать
+	if len(c.Tenants.Hosts) != 2 || c.Tenants.Hosts["files.sales.example"] != "sales" || c.Tenants.Hosts["hr.example"] != "hr" {
+		t.Errorf("hosts = %v", c.Tenants.Hosts)
+	}
+
+	path = writeConfig(t, `
+tenants:
+  hosts:
+    files.example: sales
+    FILES.example: hr
+`)
+	_, err = LoadGateway([]string{"-config", path})
+	if err == nil || !strings.Contains(err.Error(), `"files.example" is listed more than once`) {
+		t.Errorf("err = %v, want a duplicate host", err)
+	}
+}
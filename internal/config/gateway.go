@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// настройки гейтвея
+type Gateway struct {
+	Listen        string   `yaml:"listen"`          // адрес http-сервера
+	Registry      string   `yaml:"registry"`        // адрес gRPC-сервера реестра
+	TmpDir        string   `yaml:"tmp_dir"`         // незавершенные загрузки и файлы форм
+	MaxUploadSize int64    `yaml:"max_upload_size"` // в байтах
+	UploadTTL     Duration `yaml:"upload_ttl"`      // сколько живет незавершенная возобновляемая загрузка
+
+	AnonymousUploads bool    `yaml:"anonymous_uploads"` // можно ли загружать файлы без ключа или токена
+	JWT              JWT     `yaml:"jwt"`
+	Tenants          Tenants `yaml:"tenants"`
+
+	RateLimit RateLimit `yaml:"rate_limit"`
+}
+
+// лимиты запросов на клиента: на владельца по ключу или токену, иначе на адрес
+type RateLimit struct {
+	Upload         Rate `yaml:"upload"`
+	Download       Rate `yaml:"download"`
+	Info           Rate `yaml:"info"`
+	Auth           Rate `yaml:"auth"`            // запросы с ключом или токеном на адрес, считаются до проверки ключа
+	TrustedProxies List `yaml:"trusted_proxies"` // адреса или подсети, которым можно верить в X-Forwarded-For
+}
+
+// per_minute 0 - без ограничений, burst 0 - столько же, сколько per_minute
+type Rate struct {
+	PerMinute int `yaml:"per_minute"`
+	Burst     int `yaml:"burst"`
+}
+
+// как гейтвей узнает арендатора запроса: по имени хоста, а если хост не из списка - по префиксу
+// пути /t/{name}/. без совпадений запрос идет в пространство по умолчанию
+type Tenants struct {
+	Hosts      Hosts `yaml:"hosts"`       // хост без порта - имя арендатора
+	PathPrefix bool  `yaml:"path_prefix"` // разрешить префикс /t/{name}/
+}
+
+// хосты арендаторов. гейтвей сравнивает хост запроса в нижнем регистре, так что ключи
+// приводятся к нему при чтении, а хосты, совпавшие после этого, - ошибка
+type Hosts map[string]string
+
+func (h *Hosts) UnmarshalYAML(node *yaml.Node) error {
+	var raw map[string]string
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+
+	*h = make(Hosts, len(raw))
+	for _, host := range slices.Sorted(maps.Keys(raw)) {
+		key := strings.ToLower(host)
+		if _, ok := (*h)[key]; ok {
+			return fmt.Errorf("tenants.hosts: %q is listed more than once", key)
+		}
+		(*h)[key] = raw[host]
+	}
+
+	return nil
+}
+
+// проверка токенов внешнего провайдера, пустой JWKS - токены не принимаются
+type JWT struct {
+	JWKS     string `yaml:"jwks"`     // путь к файлу или http(s) URL с открытыми ключами провайдера
+	Issuer   string `yaml:"issuer"`   // ожидаемый iss
+	Audience string `yaml:"audience"` // ожидаемый aud
+}
+
+func DefaultGateway() *Gateway {
+	return &Gateway{
+		Listen:        ":8080",
+		Registry:      "localhost:50051",
+		TmpDir:        "./data/tmp",
+		MaxUploadSize: 32 << 20,
+		UploadTTL:     Duration(24 * time.Hour),
+
+		AnonymousUploads: true,
+
+		RateLimit: RateLimit{
+			Upload:   Rate{PerMinute: 20},
+			Download: Rate{PerMinute: 120},
+			Info:     Rate{PerMinute: 60},
+			Auth:     Rate{PerMinute: 120},
+		},
+	}
+}
+
+// настройки гейтвея из файла, окружения и аргументов командной строки args (без имени программы)
+func LoadGateway(args []string) (*Gateway, error) {
+	c := DefaultGateway()
+	if err := load("gateway", args, c, func() { *c = *DefaultGateway() }); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Gateway) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Listen, "listen", c.Listen, "HTTP listen address")
+	fs.StringVar(&c.Registry, "registry", c.Registry, "registry gRPC address")
+	fs.StringVar(&c.TmpDir, "tmp-dir", c.TmpDir, "directory for unfinished uploads")
+	fs.Int64Var(&c.MaxUploadSize, "max-upload-size", c.MaxUploadSize, "maximum upload size in bytes")
+	fs.Var(&c.UploadTTL, "upload-ttl", "unfinished resumable uploads older than this are removed")
+	fs.BoolVar(&c.AnonymousUploads, "anonymous-uploads", c.AnonymousUploads, "allow uploads without an API key or token")
+
+	fs.StringVar(&c.JWT.JWKS, "jwt-jwks", c.JWT.JWKS, "JWKS file or URL of the token issuer, enables JWT authentication")
+	fs.StringVar(&c.JWT.Issuer, "jwt-issuer", c.JWT.Issuer, "expected token issuer")
+	fs.StringVar(&c.JWT.Audience, "jwt-audience", c.JWT.Audience, "expected token audience")
+
+	fs.BoolVar(&c.Tenants.PathPrefix, "tenant-path-prefix", c.Tenants.PathPrefix, "resolve the tenant from a /t/{name}/ path prefix")
+
+	fs.IntVar(&c.RateLimit.Upload.PerMinute, "rate-upload", c.RateLimit.Upload.PerMinute, "uploads per minute per client, 0 means unlimited")
+	fs.IntVar(&c.RateLimit.Download.PerMinute, "rate-download", c.RateLimit.Download.PerMinute, "downloads per minute per client, 0 means unlimited")
+	fs.IntVar(&c.RateLimit.Info.PerMinute, "rate-info", c.RateLimit.Info.PerMinute, "info and list requests per minute per client, 0 means unlimited")
+	fs.IntVar(&c.RateLimit.Auth.PerMinute, "rate-auth", c.RateLimit.Auth.PerMinute, "requests with an API key or token per minute per address, counted before the key is checked, 0 means unlimited")
+	fs.Var(&c.RateLimit.TrustedProxies, "trusted-proxies", "comma-separated proxy addresses or CIDRs whose X-Forwarded-For is trusted")
+}
+
+func (c *Gateway) Validate() error {
+	var errs []error
+
+	if err := checkAddr("listen", c.Listen); err != nil {
+		errs = append(errs, err)
+	}
+	if err := checkAddr("registry", c.Registry); err != nil {
+		errs = append(errs, err)
+	}
+	if c.TmpDir == "" {
+		errs = append(errs, fmt.Errorf("tmp_dir must be set"))
+	}
+	if c.MaxUploadSize <= 0 {
+		errs = append(errs, fmt.Errorf("max_upload_size must be positive"))
+	}
+	if c.UploadTTL <= 0 {
+		errs = append(errs, fmt.Errorf("upload_ttl must be positive"))
+	}
+	// без издателя и аудитории подошел бы любой токен того же провайдера, выданный кому угодно
+	if c.JWT.JWKS != "" && (c.JWT.Issuer == "" || c.JWT.Audience == "") {
+		errs = append(errs, fmt.Errorf("jwt.issuer and jwt.audience must be set with jwt.jwks"))
+	}
+	for name, r := range map[string]Rate{"upload": c.RateLimit.Upload, "download": c.RateLimit.Download, "info": c.RateLimit.Info, "auth": c.RateLimit.Auth} {
+		if r.PerMinute < 0 || r.Burst < 0 {
+			errs = append(errs, fmt.Errorf("rate_limit.%s must not be negative", name))
+		}
+	}
+	if _, err := ParsePrefixes(c.RateLimit.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("rate_limit.trusted_proxies: %w", err))
+	}
+	for host, name := range c.Tenants.Hosts {
+		if host == "" || !tenantName.MatchString(name) {
+			errs = append(errs, fmt.Errorf("tenants.hosts: %q: %q is not a valid tenant name", host, name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// адреса и подсети CIDR в виде подсетей, отдельный адрес - подсеть из одного адреса
+func ParsePrefixes(list []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, err
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+
+	return prefixes, nil
+}
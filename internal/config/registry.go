@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// настройки реестра
+type Registry struct {
+	Listen      string  `yaml:"listen"`       // адрес gRPC-сервера
+	AdminListen string  `yaml:"admin_listen"` // адрес AdminService, гейтвею он не нужен
+	DB          string  `yaml:"db"`           // путь к файлу sqlite
+	TmpDir      string  `yaml:"tmp_dir"`      // общая с гейтвеем папка для загрузок через диск
+	Storage     Storage `yaml:"storage"`
+
+	IDLength        int      `yaml:"id_length"`   // начальная длина короткого айди
+	DefaultTTL      Duration `yaml:"default_ttl"` // срок ссылки, если загружающий его не указал
+	MaxTTL          Duration `yaml:"max_ttl"`     // 0 - без ограничений
+	CleanupInterval Duration `yaml:"cleanup_interval"`
+	ScrubInterval   Duration `yaml:"scrub_interval"` // 0 - не проверять хранилище
+	GCInterval      Duration `yaml:"gc_interval"`    // 0 - не собирать мусор
+	GCMinAge        Duration `yaml:"gc_min_age"`
+	Quota           Quota    `yaml:"quota"` // квота по умолчанию для каждого владельца
+
+	Tenants map[string]Tenant `yaml:"tenants"` // арендаторы по имени, задаются только в файле
+}
+
+// настройки арендатора, нулевые значения - как у реестра в целом
+type Tenant struct {
+	MaxSize      int64    `yaml:"max_size"` // в байтах
+	DefaultTTL   Duration `yaml:"default_ttl"`
+	MaxTTL       Duration `yaml:"max_ttl"`
+	ContentTypes []string `yaml:"content_types"` // например image/png или image/*
+	Quota        Quota    `yaml:"quota"`         // на все файлы арендатора вместе
+}
+
+// имя арендатора попадает в путь /t/{name}/ и в метаданные запроса
+var tenantName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// квота владельца, 0 - без ограничений. отдельным владельцам квоту меняет AdminService.SetQuota
+type Quota struct {
+	MaxBytes int64 `yaml:"max_bytes"`
+	MaxFiles int64 `yaml:"max_files"`
+}
+
+// хранилище содержимого: S3-совместимое, если задан S3.Endpoint, иначе папка Dir
+type Storage struct {
+	Dir string `yaml:"dir"`
+	S3  S3     `yaml:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey Secret `yaml:"secret_key"`
+}
+
+const maxIDLength = 32 // столько же допускает сервис
+
+func DefaultRegistry() *Registry {
+	return &Registry{
+		Listen:          ":50051",
+		AdminListen:     "127.0.0.1:50052",
+		DB:              "storage.db",
+		TmpDir:          "data/tmp",
+		Storage:         Storage{Dir: "data/storage"},
+		IDLength:        5,
+		DefaultTTL:      Duration(48 * time.Hour),
+		MaxTTL:          Duration(30 * 24 * time.Hour),
+		CleanupInterval: Duration(time.Hour),
+		ScrubInterval:   Duration(7 * 24 * time.Hour),
+		GCInterval:      Duration(24 * time.Hour),
+		GCMinAge:        Duration(24 * time.Hour),
+	}
+}
+
+// настройки реестра из файла, окружения и аргументов командной строки args (без имени программы)
+func LoadRegistry(args []string) (*Registry, error) {
+	c := DefaultRegistry()
+	if err := load("registry", args, c, func() { *c = *DefaultRegistry() }); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Registry) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Listen, "listen", c.Listen, "gRPC listen address")
+	fs.StringVar(&c.AdminListen, "admin-listen", c.AdminListen, "gRPC listen address of the admin service, keep it private")
+	fs.StringVar(&c.DB, "db", c.DB, "path to the SQLite database")
+	fs.StringVar(&c.TmpDir, "tmp-dir", c.TmpDir, "directory shared with the gateway for uploads")
+	fs.StringVar(&c.Storage.Dir, "storage-dir", c.Storage.Dir, "directory for file contents when S3 is not configured")
+
+	fs.StringVar(&c.Storage.S3.Endpoint, "s3-endpoint", c.Storage.S3.Endpoint, "S3-compatible endpoint URL, enables S3 storage")
+	fs.StringVar(&c.Storage.S3.Region, "s3-region", c.Storage.S3.Region, "S3 region")
+	fs.StringVar(&c.Storage.S3.Bucket, "s3-bucket", c.Storage.S3.Bucket, "S3 bucket")
+	fs.StringVar(&c.Storage.S3.AccessKey, "s3-access-key", c.Storage.S3.AccessKey, "S3 access key")
+	fs.Var(&c.Storage.S3.SecretKey, "s3-secret-key", "S3 secret key")
+
+	fs.IntVar(&c.IDLength, "id-length", c.IDLength, "initial length of short IDs")
+	fs.Var(&c.DefaultTTL, "default-ttl", "link lifetime when the uploader does not set one")
+	fs.Var(&c.MaxTTL, "max-ttl", "maximum link lifetime, 0 allows links without expiry")
+	fs.Var(&c.CleanupInterval, "cleanup-interval", "longest sleep of the expiry cleanup")
+	fs.Var(&c.ScrubInterval, "scrub-interval", "storage integrity check interval, 0 disables it")
+	fs.Var(&c.GCInterval, "gc-interval", "garbage collection interval, 0 disables it")
+	fs.Var(&c.GCMinAge, "gc-min-age", "files younger than this are never collected as garbage")
+
+	fs.Int64Var(&c.Quota.MaxBytes, "quota-max-bytes", c.Quota.MaxBytes, "default per-owner storage quota in bytes, 0 means unlimited")
+	fs.Int64Var(&c.Quota.MaxFiles, "quota-max-files", c.Quota.MaxFiles, "default per-owner file count quota, 0 means unlimited")
+}
+
+// все ошибки разом, чтобы не чинить настройки по одной
+func (c *Registry) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if err := checkAddr("listen", c.Listen); err != nil {
+		errs = append(errs, err)
+	}
+	if err := checkAddr("admin_listen", c.AdminListen); err != nil {
+		errs = append(errs, err)
+	}
+	check(c.AdminListen != c.Listen, "admin_listen must differ from listen")
+	check(c.DB != "", "db must be set")
+	check(c.TmpDir != "", "tmp_dir must be set")
+
+	if s3 := c.Storage.S3; s3.Endpoint != "" {
+		u, err := url.Parse(s3.Endpoint)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"storage.s3.endpoint must be an http(s) URL")
+		check(s3.Bucket != "", "storage.s3.bucket must be set")
+		check(s3.AccessKey != "" && s3.SecretKey != "", "storage.s3 access and secret keys must be set")
+	} else {
+		check(c.Storage.Dir != "", "storage.dir must be set")
+	}
+
+	check(c.IDLength > 0 && c.IDLength <= maxIDLength, "id_length must be between 1 and %d", maxIDLength)
+	check(c.DefaultTTL > 0, "default_ttl must be positive")
+	check(c.MaxTTL >= 0, "max_ttl must not be negative")
+	check(c.MaxTTL == 0 || c.DefaultTTL <= c.MaxTTL, "default_ttl must not exceed max_ttl")
+	check(c.CleanupInterval > 0, "cleanup_interval must be positive")
+	check(c.ScrubInterval >= 0, "scrub_interval must not be negative")
+	check(c.Quota.MaxBytes >= 0 && c.Quota.MaxFiles >= 0, "quota limits must not be negative")
+	check(c.GCInterval >= 0, "gc_interval must not be negative")
+	check(c.GCMinAge > 0, "gc_min_age must be positive")
+
+	for name, t := range c.Tenants {
+		check(tenantName.MatchString(name), "tenants: name %q must be lowercase letters, digits and dashes", name)
+		check(t.MaxSize >= 0, "tenants.%s.max_size must not be negative", name)
+		check(t.DefaultTTL >= 0 && t.MaxTTL >= 0, "tenants.%s ttl must not be negative", name)
+		check(t.Quota.MaxBytes >= 0 && t.Quota.MaxFiles >= 0, "tenants.%s quota limits must not be negative", name)
+		for _, typ := range t.ContentTypes {
+			major, sub, ok := strings.Cut(typ, "/")
+			check(ok && major != "" && sub != "" && !strings.ContainsAny(typ, ", ;") && typ == strings.ToLower(typ),
+				"tenants.%s: content type %q should look like image/png or image/*", name, typ)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+	"github.com/kfcempoyee/gofilesharing/internal/jwt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ключи доступа к загрузке. клиент передает ключ в заголовке X-API-Key или как
+// Authorization: Bearer <ключ>, гейтвей проверяет его в реестре и передает айди ключа вместе
+// с загрузкой, а ограничения ключа (размер, срок, типы содержимого) проверяет реестр.
+// скачивание ключа не требует.
+// если настроен JWT, в Authorization: Bearer можно передать и токен внешнего провайдера:
+// гейтвей проверяет его сам, а субъект токена реестр записывает владельцем файла
+// (без токена владелец - сам ключ).
+// ключ при этом передается в X-API-Key
+
+type Auth struct {
+	Client    pb.RegServiceClient
+	JWT       *jwt.Verifier // nil - токены не принимаются, Bearer считается ключом
+	Anonymous bool          // можно ли загружать файлы без ключа или токена
+	Logger    *slog.Logger
+}
+
+// ключи, которые выдает реестр, начинаются с этого префикса, так их не спутать с JWT
+const apiKeyPrefix = "gfs_"
+
+// клиент, опознанный по ключу и/или токену. для анонимного клиента в контексте ничего нет
+type caller struct {
+	KeyID   string
+	MaxSize int64  // наибольший размер файла по ключу, 0 - без ограничений ключа
+	Subject string // субъект проверенного токена, пустая строка - без токена
+}
+
+type callerCtxKey struct{}
+
+func callerFrom(ctx context.Context) *caller {
+	c, _ := ctx.Value(callerCtxKey{}).(*caller)
+	return c
+}
+
+// префикс владельца, который вошел только по ключу. субъекты токенов с таким префиксом
+// не принимаются, иначе токен мог бы выдать себя за ключ и увидеть чужие файлы
+const keyOwnerPrefix = "key:"
+
+// от чьего имени клиент загружает и видит файлы: субъект токена, а без токена - ключ
+func (c *caller) owner() string {
+	if c.Subject != "" {
+		return c.Subject
+	}
+	if c.KeyID != "" {
+		return keyOwnerPrefix + c.KeyID
+	}
+
+	return ""
+}
+
+// параметры загрузки, которые ставит сам гейтвей: ключ и владелец.
+// у анонимного клиента оба пустые
+func callerOptions(r *http.Request) uploadOptions {
+	if c := callerFrom(r.Context()); c != nil {
+		return uploadOptions{KeyID: c.KeyID, Owner: c.owner()}
+	}
+
+	return uploadOptions{}
+}
+
+// ключ и токен из заголовков. без настроенного JWT все, что пришло в Bearer, считается ключом
+func (a *Auth) credentials(r *http.Request) (key, token string) {
+	key = r.Header.Get("X-API-Key")
+
+	bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if bearer = strings.TrimSpace(bearer); !ok || bearer == "" {
+		return key, ""
+	}
+
+	if a.JWT != nil && !strings.HasPrefix(bearer, apiKeyPrefix) {
+		return key, bearer
+	}
+	if key == "" {
+		key = bearer
+	}
+
+	return key, ""
+}
+
+// опознать клиента, если он передал ключ или токен. неверный ключ или токен - 401 на любой запрос:
+// клиент не должен думать, что работает по ключу или от своего имени, когда это не так
+func (a *Auth) middleware(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, token := a.credentials(r)
+		if key == "" && token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c := &caller{}
+
+		if token != "" {
+			claims, err := a.JWT.Verify(r.Context(), token)
+			if err == nil && strings.HasPrefix(claims.Subject, keyOwnerPrefix) {
+				err = fmt.Errorf("subject %q looks like an api key owner", claims.Subject)
+			}
+			if err != nil {
+				a.Logger.Warn("rejected token", "details", err)
+				unauthorized(w, "Invalid token.")
+				return
+			}
+			c.Subject = claims.Subject
+		}
+
+		if key != "" {
+			resp, err := a.Client.Authenticate(r.Context(), &pb.AuthenticateReq{Key: key})
+			if status.Code(err) == codes.Unauthenticated {
+				unauthorized(w, "Invalid API key.")
+				return
+			}
+			if status.Code(err) == codes.NotFound {
+				handleError(w, "Tenant not found.", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				a.Logger.Error("failed to check api key", "details", err)
+				handleError(w, "Server Error.", http.StatusInternalServerError)
+				return
+			}
+			c.KeyID, c.MaxSize = resp.Id, resp.MaxSizeBytes
+		}
+
+		ctx := context.WithValue(r.Context(), callerCtxKey{}, c)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// если анонимные загрузки выключены, загрузка без ключа или токена получает 401
+func (a *Auth) uploads(next http.HandlerFunc) http.HandlerFunc {
+	if a == nil || a.Anonymous {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if callerFrom(r.Context()) == nil {
+			unauthorized(w, "API key or token is required to upload files.")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="gofilesharing"`)
+	handleError(w, msg, http.StatusUnauthorized)
+}
@@ -0,0 +1,250 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// наборы: несколько файлов из одной формы под одной ссылкой. каждый файл загружается в реестр
+// как обычно, а затем реестр собирает их в набор, проверяя токены владельца. по ссылке набора
+// отдается список файлов, а каждый файл доступен по /get/{набор}/{файл}/
+
+// файл из формы, отложенный во временную папку. если он в форме один, он загружается со всеми
+// параметрами ссылки, как раньше, а если за ним идут другие - становится первым файлом набора
+type spooledFile struct {
+	name   string
+	cType  string
+	size   int64
+	digest uploadOptions // хеши из заголовков части формы
+	f      *os.File
+}
+
+// nil допустим, чтобы удаление можно было отложить до того, как файл появится
+func (s *spooledFile) remove() {
+	if s == nil {
+		return
+	}
+
+	s.f.Close()
+	_ = os.Remove(s.f.Name())
+}
+
+// записать файл во временную папку, nil - если ответ с ошибкой уже отправлен
+func (h *FileHandler) spool(w http.ResponseWriter, name, cType string, digest uploadOptions, body io.Reader) *spooledFile {
+	if err := os.MkdirAll(h.TmpDir, 0755); err != nil {
+		h.Logger.Error("failed to create tmp dir", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return nil
+	}
+
+	f, err := os.CreateTemp(h.TmpDir, "form-*")
+	if err != nil {
+		h.Logger.Error("failed to create tmp file", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return nil
+	}
+
+	s := &spooledFile{name: name, cType: cType, digest: digest, f: f}
+
+	s.size, err = io.Copy(f, body)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		s.remove()
+		h.Logger.Error("failed to read data", "details", err)
+
+		var mbErr *http.MaxBytesError
+		if errors.As(err, &mbErr) {
+			handleError(w, "File is too large.", http.StatusRequestEntityTooLarge)
+			return nil
+		}
+
+		handleError(w, "Failed to upload a file.", http.StatusInternalServerError)
+		return nil
+	}
+
+	return s
+}
+
+// загруженный файл набора
+type collectionMember struct {
+	ShortName   string `json:"short_name"`
+	Filename    string `json:"filename"`
+	deleteToken string
+}
+
+// параметры загрузки файла набора: имя ссылки относится к набору, а хеши - к самому файлу
+func (o uploadOptions) member(digest uploadOptions) uploadOptions {
+	o.ShortName = ""
+	o.SHA256, o.MD5 = digest.SHA256, digest.MD5
+	return o
+}
+
+// загрузить файл набора, nil - если ответ с ошибкой уже отправлен
+func (h *FileHandler) sendMember(w http.ResponseWriter, r *http.Request, name string, size int64, cType string, opts uploadOptions, body io.Reader) *collectionMember {
+	resp := h.upload(w, r, name, size, cType, opts, body)
+	if resp == nil {
+		return nil
+	}
+
+	return &collectionMember{ShortName: resp.ShortName, Filename: name, deleteToken: resp.DeleteToken}
+}
+
+// удалить файлы набора, который так и не был создан. клиент мог уже отключиться, поэтому
+// удаление не должно зависеть от отмены запроса
+func (h *FileHandler) dropMembers(ctx context.Context, members []*collectionMember) {
+	ctx = context.WithoutCancel(ctx)
+
+	for _, m := range members {
+		_, err := h.GRpcClient.DeleteFile(ctx, &pb.DeleteFileReq{ShortName: m.ShortName, DeleteToken: m.deleteToken})
+		if err != nil {
+			h.Logger.Error("failed to delete a collection file", "id", m.ShortName, "details", err)
+		}
+	}
+}
+
+// собрать загруженные файлы в набор и отдать клиенту его ссылку,
+// false - если ответ с ошибкой уже отправлен
+func (h *FileHandler) createCollection(w http.ResponseWriter, r *http.Request, opts uploadOptions, members []*collectionMember) bool {
+	req := &pb.CreateCollectionReq{
+		TtlSeconds: opts.TTL,
+		Password:   opts.Password,
+		ShortName:  opts.ShortName,
+		KeyId:      opts.KeyID,
+		Owner:      opts.Owner,
+	}
+	for _, m := range members {
+		req.Members = append(req.Members, &pb.CollectionMember{ShortName: m.ShortName, DeleteToken: m.deleteToken})
+	}
+
+	resp, err := h.GRpcClient.CreateCollection(r.Context(), req)
+	if err != nil {
+		h.handleUploadError(w, err)
+		return false
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		ShortName   string              `json:"short_name"`
+		DeleteToken string              `json:"delete_token"`
+		Files       []*collectionMember `json:"files"`
+	}{
+		ShortName:   resp.ShortName,
+		DeleteToken: resp.DeleteToken,
+		Files:       members,
+	})
+	return true
+}
+
+// отдать список файлов набора id. false - если набора с таким именем нет и ответ еще не отправлен
+func (h *FileHandler) listCollection(w http.ResponseWriter, r *http.Request, id string) bool {
+	resp, err := h.GRpcClient.GetCollection(r.Context(), &pb.GetCollectionReq{
+		ShortName: id,
+		Password:  filePassword(r),
+	})
+	if status.Code(err) == codes.NotFound {
+		return false
+	}
+
+	if err != nil {
+		h.handleFetchError(w, r, err)
+		return true
+	}
+
+	type file struct {
+		ShortName    string
+		Name         string
+		Size         int
+		ContentType  string
+		MaxDownloads int
+		Downloads    int
+		SHA256       string
+		Path         string // путь для скачивания файла из набора
+	}
+
+	files := make([]file, 0, len(resp.Files))
+	for _, f := range resp.Files {
+		files = append(files, file{
+			ShortName:    f.ShortName,
+			Name:         f.Filename,
+			Size:         int(f.SizeBytes),
+			ContentType:  f.ContentType,
+			MaxDownloads: int(f.MaxDownloads),
+			Downloads:    int(f.Downloads),
+			SHA256:       f.Sha256,
+			Path:         tenantPath(r, "/get/"+id+"/"+f.ShortName+"/"),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		ShortName string
+		ExpiresAt *time.Time
+		Files     []file
+	}{
+		ShortName: id,
+		ExpiresAt: unixTime(resp.ExpiresAt),
+		Files:     files,
+	})
+	return true
+}
+
+// скачать файл набора. пароль набора действует и для его файлов
+func (h *FileHandler) GetMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Not Found", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := h.shortName(w, r)
+	if id == "" {
+		return
+	}
+
+	member := h.pathName(w, r, "member")
+	if member == "" {
+		return
+	}
+
+	c, err := h.GRpcClient.GetCollection(r.Context(), &pb.GetCollectionReq{
+		ShortName: id,
+		Password:  filePassword(r),
+	})
+	if err != nil {
+		h.handleFetchError(w, r, err)
+		return
+	}
+
+	found := false
+	for _, f := range c.Files {
+		if f.ShortName == member {
+			found = true
+			break
+		}
+	}
+	if !found {
+		handleError(w, "File not found.", http.StatusNotFound)
+		return
+	}
+
+	resp, err := h.GRpcClient.GetFile(r.Context(), &pb.GetFileDataReq{
+		ShortName: member,
+		Password:  filePassword(r),
+	})
+	if err != nil {
+		h.handleFetchError(w, r, err)
+		return
+	}
+
+	h.serveFile(w, r, member, resp)
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var errInvalidDigest = errors.New("Digest and Content-MD5 should hold a base64 sha-256 or md5 of the file")
+
+// ожидаемые хеши файла из заголовков Digest ("sha-256=<base64>, md5=<base64>") и Content-MD5.
+// в /upload заголовки можно передать и у части File, тогда они важнее заголовков запроса
+func (o *uploadOptions) setDigest(header http.Header) error {
+	if v := header.Get("Content-MD5"); v != "" {
+		sum, err := decodeDigest(v, md5.Size)
+		if err != nil {
+			return err
+		}
+		o.MD5 = sum
+	}
+
+	for _, v := range header.Values("Digest") {
+		for _, item := range strings.Split(v, ",") {
+			alg, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+			if !ok {
+				return errInvalidDigest
+			}
+
+			// неизвестные алгоритмы пропускаем, как и предписывает RFC 3230
+			switch strings.ToLower(alg) {
+			case "sha-256":
+				sum, err := decodeDigest(value, sha256.Size)
+				if err != nil {
+					return err
+				}
+				o.SHA256 = sum
+			case "md5":
+				sum, err := decodeDigest(value, md5.Size)
+				if err != nil {
+					return err
+				}
+				o.MD5 = sum
+			}
+		}
+	}
+
+	return nil
+}
+
+// base64 (с двоеточиями по краям, как в RFC 9530, или без них)
+func decodeDigest(value string, size int) ([]byte, error) {
+	sum, err := base64.StdEncoding.DecodeString(strings.Trim(strings.TrimSpace(value), ":"))
+	if err != nil || len(sum) != size {
+		return nil, errInvalidDigest
+	}
+
+	return sum, nil
+}
+
+// значения заголовков скачивания: Digest для проверки целостности и ETag для кеширования и If-Range.
+// у файлов, загруженных до появления хешей, их нет
+func contentDigest(sha string) (digest, etag string) {
+	sum, err := hex.DecodeString(sha)
+	if err != nil || len(sum) != sha256.Size {
+		return "", ""
+	}
+
+	return "sha-256=" + base64.StdEncoding.EncodeToString(sum), `"` + sha + `"`
+}
+
+// ответом будет 304: у клиента уже есть эта версия файла, и качать содержимое не нужно
+func notModified(r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetDigest(t *testing.T) {
+	sha := sha256.Sum256([]byte("abc"))
+	sum := md5.Sum([]byte("abc"))
+	sha64 := base64.StdEncoding.EncodeToString(sha[:])
+	md64 := base64.StdEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		headers map[string][]string
+		sha     bool // ожидается sha-256
+		md5     bool // ожидается md5
+		err     bool
+	}{
+		{"no headers", nil, false, false, false},
+		{"sha-256", map[string][]string{"Digest": {"sha-256=" + sha64}}, true, false, false},
+		{"rfc 9530 colons", map[string][]string{"Digest": {"SHA-256=:" + sha64 + ":"}}, true, false, false},
+		{"both in one header", map[string][]string{"Digest": {"sha-256=" + sha64 + ", md5=" + md64}}, true, true, false},
+		{"several headers", map[string][]string{"Digest": {"sha-256=" + sha64, "md5=" + md64}}, true, true, false},
+		{"unknown algorithm skipped", map[string][]string{"Digest": {"unixsum=30637, sha-256=" + sha64}}, true, false, false},
+		{"content-md5", map[string][]string{"Content-Md5": {md64}}, false, true, false},
+
+		{"md5 as sha-256", map[string][]string{"Digest": {"sha-256=" + md64}}, false, false, true},
+		{"sha-256 as md5", map[string][]string{"Content-Md5": {sha64}}, false, false, true},
+		{"not base64", map[string][]string{"Digest": {"sha-256=not base64!"}}, false, false, true},
+		{"hex instead of base64", map[string][]string{"Digest": {"sha-256=" + hex.EncodeToString(sha[:])}}, false, false, true},
+		{"no value", map[string][]string{"Digest": {"sha-256"}}, false, false, true},
+		{"bad item after a good one", map[string][]string{"Digest": {"sha-256=" + sha64 + ", md5"}}, false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o uploadOptions
+			err := o.setDigest(http.Header(tt.headers))
+
+			if tt.err {
+				if err != errInvalidDigest {
+					t.Errorf("Expected errInvalidDigest, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if got := bytes.Equal(o.SHA256, sha[:]); got != tt.sha || (!tt.sha && o.SHA256 != nil) {
+				t.Errorf("Expected sha-256 %v, got %x", tt.sha, o.SHA256)
+			}
+			if got := bytes.Equal(o.MD5, sum[:]); got != tt.md5 || (!tt.md5 && o.MD5 != nil) {
+				t.Errorf("Expected md5 %v, got %x", tt.md5, o.MD5)
+			}
+		})
+	}
+}
+
+func TestContentDigest(t *testing.T) {
+	sha := sha256.Sum256([]byte("abc"))
+	hexSum := hex.EncodeToString(sha[:])
+
+	digest, etag := contentDigest(hexSum)
+	if digest != "sha-256="+base64.StdEncoding.EncodeToString(sha[:]) || etag != `"`+hexSum+`"` {
+		t.Errorf("Unexpected digest %q and etag %q", digest, etag)
+	}
+
+	// у старых файлов хеша нет, и заголовков тоже
+	for _, bad := range []string{"", "xyz", hexSum[:10]} {
+		if digest, etag := contentDigest(bad); digest != "" || etag != "" {
+			t.Errorf("Expected no headers for %q, got %q and %q", bad, digest, etag)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	const etag = `"abc"`
+
+	tests := []struct {
+		header string
+		etag   string
+		want   bool
+	}{
+		{"", etag, false},
+		{etag, etag, true},
+		{"W/" + etag, etag, true},
+		{`"other", ` + etag, etag, true},
+		{"*", etag, true},
+		{`"other"`, etag, false},
+		{"*", "", false}, // без хеша сравнивать не с чем
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			r.Header.Set("If-None-Match", tt.header)
+		}
+
+		if got := notModified(r, tt.etag); got != tt.want {
+			t.Errorf("If-None-Match %q with etag %q: expected %v, got %v", tt.header, tt.etag, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+	"google.golang.org/grpc"
+)
+
+// содержимое файла в реестре в виде io.ReadSeeker для http.ServeContent. смена позиции ничего
+// не запрашивает у реестра, поток DownloadFile открывается с текущей позиции при первом чтении,
+// так что Range-запросы проксируются без доступа к диску реестра
+type remoteFile struct {
+	client   pb.RegServiceClient
+	id       string
+	password string
+	size     int64
+
+	ctx       context.Context // контекст запроса: в его метаданных арендатор
+	pos       int64           // позиция, с которой будет следующее чтение
+	streamPos int64           // позиция, на которой стоит открытый поток
+	stream    grpc.ServerStreamingClient[pb.DownloadFileResp]
+	cancel    context.CancelFunc
+	buf       []byte
+}
+
+// открыть поток с offset и дождаться первого ответа, чтобы ошибка реестра всплыла сразу
+func (f *remoteFile) open(ctx context.Context, offset int64) error {
+	f.Close()
+	f.ctx = ctx
+
+	sCtx, cancel := context.WithCancel(ctx)
+	stream, err := f.client.DownloadFile(sCtx, &pb.DownloadFileReq{
+		ShortName: f.id,
+		Password:  f.password,
+		Offset:    offset,
+	})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	f.pos, f.streamPos, f.stream, f.cancel = offset, offset, stream, cancel
+
+	if offset >= f.size {
+		return nil
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	f.buf = resp.GetChunk()
+	return nil
+}
+
+func (f *remoteFile) Read(p []byte) (int, error) {
+	if f.pos >= f.size {
+		return 0, io.EOF
+	}
+
+	// открытый поток пригоден только для своей позиции
+	if f.stream == nil || f.streamPos != f.pos {
+		ctx := f.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if err := f.open(ctx, f.pos); err != nil {
+			return 0, err
+		}
+	}
+
+	for len(f.buf) == 0 {
+		resp, err := f.stream.Recv()
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF // реестр отдал меньше, чем заявлено в метаданных
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		f.buf = resp.GetChunk()
+	}
+
+	n := copy(p, f.buf)
+	f.buf = f.buf[n:]
+	f.pos += int64(n)
+	f.streamPos = f.pos
+	return n, nil
+}
+
+func (f *remoteFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.size
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	f.pos = offset
+	return offset, nil
+}
+
+func (f *remoteFile) Close() error {
+	if f.cancel != nil {
+		f.cancel()
+	}
+
+	f.stream, f.cancel, f.buf = nil, nil, nil
+	return nil
+}
+
+// смещения диапазонов Range-запроса, разобранные так же, как их разберет http.ServeContent.
+// ok == false - заголовок испорчен или ни один диапазон не попадает в файл, и ServeContent
+// ответит 416, не читая содержимое. пустой заголовок - ни одного диапазона
+func rangeStarts(header string, size int64) ([]int64, bool) {
+	if header == "" {
+		return nil, true
+	}
+
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return nil, false
+	}
+
+	var starts []int64
+	noOverlap := false
+	for ra := range strings.SplitSeq(spec, ",") {
+		ra = strings.TrimSpace(ra)
+		if ra == "" {
+			continue
+		}
+
+		first, last, ok := strings.Cut(ra, "-")
+		if !ok {
+			return nil, false
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+		// суффикс: последние n байт
+		if first == "" {
+			if last == "" || last[0] == '-' {
+				return nil, false
+			}
+
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, false
+			}
+
+			starts = append(starts, size-min(n, size))
+			continue
+		}
+
+		start, err := strconv.ParseInt(first, 10, 64)
+		if err != nil || start < 0 {
+			return nil, false
+		}
+		if last != "" {
+			end, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || start > end {
+				return nil, false
+			}
+		}
+
+		if start >= size {
+			noOverlap = true
+			continue
+		}
+
+		starts = append(starts, start)
+	}
+
+	if noOverlap && len(starts) == 0 {
+		return nil, false
+	}
+
+	return starts, true
+}
@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+	"google.golang.org/grpc"
+)
+
+// реестр с одним файлом, который запоминает, с каких позиций открывались потоки.
+// скачивание засчитывает реестр при открытии потока, так что нет потока - нет и скачивания
+type fileRegistry struct {
+	pb.RegServiceClient
+	data  string
+	opens []int64
+}
+
+func (f *fileRegistry) GetFile(ctx context.Context, in *pb.GetFileDataReq, opts ...grpc.CallOption) (*pb.GetFileDataResp, error) {
+	sum := sha256.Sum256([]byte(f.data))
+	return &pb.GetFileDataResp{
+		Filename:    "a.txt",
+		SizeBytes:   int64(len(f.data)),
+		ContentType: "text/plain",
+		Sha256:      hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+func (f *fileRegistry) DownloadFile(ctx context.Context, in *pb.DownloadFileReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[pb.DownloadFileResp], error) {
+	f.opens = append(f.opens, in.Offset)
+	return &chunkStream{chunks: []string{f.data[in.Offset:]}}, nil
+}
+
+// поток, который отдает куски по порядку, а потом io.EOF
+type chunkStream struct {
+	grpc.ClientStream
+	chunks []string
+}
+
+func (s *chunkStream) Recv() (*pb.DownloadFileResp, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return &pb.DownloadFileResp{Chunk: []byte(chunk)}, nil
+}
+
+func TestRangeStarts(t *testing.T) {
+	tests := []struct {
+		header string
+		starts []int64
+		ok     bool
+	}{
+		{"", nil, true},
+		{"bytes=", nil, true},
+		{"bytes=0-", []int64{0}, true},
+		{"bytes=4-6", []int64{4}, true},
+		{"bytes=4-100", []int64{4}, true},
+		{"bytes= 4 - 6 ", []int64{4}, true},
+		{"bytes=-3", []int64{7}, true},
+		{"bytes=-20", []int64{0}, true},
+		{"bytes=0-1,5-6", []int64{0, 5}, true},
+		{"bytes=0-1,", []int64{0}, true},
+		{"bytes=10-, 2-3", []int64{2}, true},
+
+		// ни один диапазон не попадает в файл
+		{"bytes=10-", nil, false},
+		{"bytes=10-20,30-", nil, false},
+
+		// испорченные заголовки
+		{"items=0-1", nil, false},
+		{"bytes=5-2", nil, false},
+		{"bytes=abc-", nil, false},
+		{"bytes=1-x", nil, false},
+		{"bytes=-", nil, false},
+		{"bytes=--1", nil, false},
+		{"bytes=-1-2", nil, false},
+		{"bytes=5", nil, false},
+		{"bytes=0-1,oops", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			starts, ok := rangeStarts(tt.header, 10)
+			if ok != tt.ok || !slices.Equal(starts, tt.starts) {
+				t.Errorf("Expected %v, %v, got %v, %v", tt.starts, tt.ok, starts, ok)
+			}
+		})
+	}
+}
+
+func TestGetFile_Conditional(t *testing.T) {
+	sum := sha256.Sum256([]byte("0123456789"))
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		code    int
+		body    string
+		opens   []int64 // с каких позиций открывались потоки
+	}{
+		{"whole file", "GET", nil, http.StatusOK, "0123456789", []int64{0}},
+		{"head", "HEAD", nil, http.StatusOK, "", nil},
+		{"head with range", "HEAD", map[string]string{"Range": "bytes=4-"}, http.StatusPartialContent, "", nil},
+		{"not modified", "GET", map[string]string{"If-None-Match": etag}, http.StatusNotModified, "", nil},
+		{"not modified weak", "GET", map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified, "", nil},
+		{"modified", "GET", map[string]string{"If-None-Match": `"other"`}, http.StatusOK, "0123456789", []int64{0}},
+		{"range", "GET", map[string]string{"Range": "bytes=4-"}, http.StatusPartialContent, "456789", []int64{4}},
+		{"suffix", "GET", map[string]string{"Range": "bytes=-3"}, http.StatusPartialContent, "789", []int64{7}},
+		{"bounded range", "GET", map[string]string{"Range": "bytes=2-4"}, http.StatusPartialContent, "234", []int64{2}},
+		{"unsatisfiable", "GET", map[string]string{"Range": "bytes=100-"}, http.StatusRequestedRangeNotSatisfiable, "", nil},
+		{"malformed", "GET", map[string]string{"Range": "garbage"}, http.StatusRequestedRangeNotSatisfiable, "", nil},
+		{"multiple ranges", "GET", map[string]string{"Range": "bytes=0-1,5-6"}, http.StatusOK, "0123456789", []int64{0}},
+		{"if-range match", "GET", map[string]string{"Range": "bytes=4-", "If-Range": etag}, http.StatusPartialContent, "456789", []int64{4}},
+		{"if-range mismatch", "GET", map[string]string{"Range": "bytes=4-", "If-Range": `"other"`}, http.StatusOK, "0123456789", []int64{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &fileRegistry{data: "0123456789"}
+			h := &FileHandler{GRpcClient: reg, Logger: slog.New(slog.DiscardHandler)}
+
+			r := httptest.NewRequest(tt.method, "/get/abc/", nil)
+			r.SetPathValue("id", "abc")
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			w := httptest.NewRecorder()
+			h.GetFile(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("Expected %d, got %d: %s", tt.code, w.Code, w.Body)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, w.Body)
+			}
+			if !slices.Equal(reg.opens, tt.opens) {
+				t.Errorf("Expected streams from %v, got %v", tt.opens, reg.opens)
+			}
+		})
+	}
+}
@@ -2,25 +2,29 @@ package gateway
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
-	"github.com/google/uuid"
 	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type FileHandler struct {
-	TmpDir     string
-	GRpcClient pb.RegServiceClient
-	Logger     *slog.Logger
+	TmpDir        string        // локальная папка гейтвея для незавершенных возобновляемых загрузок
+	MaxUploadSize int64         // 0 - по умолчанию, 32 МБ
+	UploadTTL     time.Duration // сколько живет незавершенная возобновляемая загрузка, 0 - сутки
+	GRpcClient    pb.RegServiceClient
+	Logger        *slog.Logger
+
+	locks uploadLocks // блокировки возобновляемых загрузок
 }
 
 type ErrorResponse struct {
@@ -38,47 +42,129 @@ func handleError(w http.ResponseWriter, msg string, status int) {
 
 const idRegexp = `^[a-zA-Z0-9]+$`
 
-func (h *FileHandler) fetchFile(w http.ResponseWriter, r *http.Request) *pb.GetFileDataResp {
-	path := strings.TrimSpace(r.PathValue("id"))
+const (
+	maxUploadSize = 32 << 20 // 32 МБ, если MaxUploadSize не задан
+	chunkSize     = 64 << 10 // размер одного сообщения при передаче файла в реестр
+	maxFieldSize  = 1 << 10  // ограничение на текстовые поля формы
+)
+
+func (h *FileHandler) maxUpload() int64 {
+	if h.MaxUploadSize > 0 {
+		return h.MaxUploadSize
+	}
+
+	return maxUploadSize
+}
+
+// наибольший размер загрузки с учетом ключа клиента, когда размер файла известен заранее.
+// окончательно размер по ключу проверяет реестр, здесь - только чтобы не принимать заведомо лишние байты
+func (h *FileHandler) uploadLimit(r *http.Request) int64 {
+	limit := h.maxUpload()
+	if c := callerFrom(r.Context()); c != nil && c.MaxSize > 0 {
+		limit = min(limit, c.MaxSize)
+	}
+
+	return limit
+}
+
+// короткое имя из пути или пустая строка, если ответ с ошибкой уже отправлен
+func (h *FileHandler) shortName(w http.ResponseWriter, r *http.Request) string {
+	return h.pathName(w, r, "id")
+}
+
+// короткое имя из параметра пути name (ссылка или файл набора)
+func (h *FileHandler) pathName(w http.ResponseWriter, r *http.Request, name string) string {
+	path := strings.TrimSpace(r.PathValue(name))
 	if ok, _ := regexp.MatchString(idRegexp, path); !ok {
 		h.Logger.Error("request not handled: invalid link.")
 		handleError(w, "File link should contain only letters and digits.", http.StatusBadRequest)
-		return nil
+		return ""
 	}
 
-	resp, err := h.GRpcClient.GetFile(r.Context(), &pb.GetFileDataReq{ShortName: path})
-	if err != nil {
+	return path
+}
 
-		st, ok := status.FromError(err)
-		if !ok {
-			h.Logger.Error("failed to call rpc.", "details", err)
-			handleError(w, "Server Error.", http.StatusInternalServerError)
-			return nil
-		}
+func (h *FileHandler) fetchFile(w http.ResponseWriter, r *http.Request) *pb.GetFileDataResp {
+	path := h.shortName(w, r)
+	if path == "" {
+		return nil
+	}
 
-		h.Logger.Error("rpc error",
-			"code", st.Code(),
-			"msg", st.Message(),
-			"details", st.Details(),
-		)
+	resp, err := h.GRpcClient.GetFile(r.Context(), &pb.GetFileDataReq{
+		ShortName: path,
+		Password:  filePassword(r),
+	})
 
-		switch st.Code() {
-		case codes.DeadlineExceeded:
-			handleError(w, "Link is not valid or expired.", http.StatusNotFound)
-		case codes.NotFound:
-			handleError(w, "File not found.", http.StatusNotFound)
-		default:
-			handleError(w, "Service Internal error.", http.StatusInternalServerError)
-		}
+	// по той же ссылке может быть набор файлов, тогда вместо файла отдается его список
+	if status.Code(err) == codes.NotFound && h.listCollection(w, r, path) {
+		return nil
+	}
 
+	if err != nil {
+		h.handleFetchError(w, r, err)
 		return nil
 	}
 
 	return resp
 }
 
+// время из ответа реестра, для бессрочной ссылки (0) - nil, в json это null
+func unixTime(sec int64) *time.Time {
+	if sec == 0 {
+		return nil
+	}
+
+	t := time.Unix(sec, 0).UTC()
+	return &t
+}
+
+// пароль защищенного файла: только заголовок X-File-Password. в адресе пароль осел бы
+// в логах прокси, истории браузера и заголовке Referer
+func filePassword(r *http.Request) string {
+	return r.Header.Get("X-File-Password")
+}
+
+// превращает ошибку rpc при получении файла в http-ответ
+func (h *FileHandler) handleFetchError(w http.ResponseWriter, r *http.Request, err error) {
+	st, ok := status.FromError(err)
+	if !ok {
+		h.Logger.Error("failed to call rpc.", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	h.Logger.Error("rpc error",
+		"code", st.Code(),
+		"msg", st.Message(),
+		"details", st.Details(),
+	)
+
+	switch st.Code() {
+	case codes.DeadlineExceeded:
+		handleError(w, "Link is not valid or expired.", http.StatusNotFound)
+	case codes.NotFound:
+		handleError(w, "File not found.", http.StatusNotFound)
+	case codes.OutOfRange:
+		handleError(w, "Requested range is not satisfiable.", http.StatusRequestedRangeNotSatisfiable)
+	case codes.FailedPrecondition:
+		handleError(w, "Download limit reached, link is no longer available.", http.StatusGone)
+	case codes.PermissionDenied:
+		if filePassword(r) == "" {
+			handleError(w, "File is protected with a password.", http.StatusUnauthorized)
+			return
+		}
+		handleError(w, "Wrong password.", http.StatusForbidden)
+	case codes.ResourceExhausted:
+		handleError(w, "Too many wrong passwords, try again later.", http.StatusTooManyRequests)
+	case codes.DataLoss:
+		handleError(w, "File content is damaged on the server and cannot be served.", http.StatusInternalServerError)
+	default:
+		handleError(w, "Service Internal error.", http.StatusInternalServerError)
+	}
+}
+
 func (h *FileHandler) GetFile(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
 		http.Error(w, "Not Found", http.StatusMethodNotAllowed)
 		return
 	}
@@ -88,9 +174,164 @@ func (h *FileHandler) GetFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.serveFile(w, r, strings.TrimSpace(r.PathValue("id")), resp)
+}
+
+// отдать содержимое файла id, данные которого уже получены от реестра
+func (h *FileHandler) serveFile(w http.ResponseWriter, r *http.Request, id string, resp *pb.GetFileDataResp) {
+	content := &remoteFile{
+		client:   h.GRpcClient,
+		id:       id,
+		password: filePassword(r),
+		size:     resp.SizeBytes,
+		ctx:      r.Context(),
+	}
+	defer content.Close()
+
+	digest, etag := contentDigest(resp.Sha256)
+
+	// поток открываем заранее, чтобы ошибку реестра можно было отдать статусом, а не оборванным телом.
+	// для HEAD и ответа 304 содержимое не нужно, и скачивание не засчитывается
+	if r.Method == http.MethodGet && !notModified(r, etag) {
+		// при несовпавшем If-Range диапазон игнорируется и файл отдается целиком
+		rng := r.Header.Get("Range")
+		if ifRange := r.Header.Get("If-Range"); ifRange != "" && ifRange != etag {
+			rng = ""
+		}
+
+		// на неудовлетворимый диапазон ServeContent ответит 416 сам, и поток не открывается:
+		// иначе испорченный заголовок сжег бы ссылку с лимитом скачиваний
+		starts, ok := rangeStarts(rng, resp.SizeBytes)
+
+		// несколько диапазонов отдаем целым файлом (стандарт это разрешает): по одному потоку на
+		// диапазон исчерпанная по пути ссылка оборвала бы ответ на середине
+		if len(starts) > 1 {
+			r = r.Clone(r.Context())
+			r.Header.Del("Range")
+			starts = nil
+		}
+
+		if ok {
+			var offset int64
+			if len(starts) == 1 {
+				offset = starts[0]
+			}
+
+			if err := content.open(r.Context(), offset); err != nil {
+				h.handleFetchError(w, r, err)
+				return
+			}
+		}
+	}
+
 	w.Header().Set("Content-Type", resp.ContentType)
 	w.Header().Set("Content-Disposition", "attachment; filename=\""+resp.Filename+"\"")
-	http.ServeFile(w, r, resp.StPath)
+	if etag != "" {
+		w.Header().Set("Digest", digest)
+		w.Header().Set("ETag", etag)
+	}
+	http.ServeContent(w, r, resp.Filename, time.Time{}, content)
+}
+
+// токен владельца из заголовка X-Delete-Token или параметра ?token=,
+// пустая строка - если токена нет и ответ с ошибкой уже отправлен
+func deleteToken(w http.ResponseWriter, r *http.Request) string {
+	token := r.Header.Get("X-Delete-Token")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+
+	if token == "" {
+		handleError(w, "Delete token is required.", http.StatusUnauthorized)
+	}
+
+	return token
+}
+
+// превращает ошибку rpc при управлении файлом владельцем в http-ответ
+func (h *FileHandler) handleOwnerError(w http.ResponseWriter, err error) {
+	st, ok := status.FromError(err)
+	if !ok {
+		h.Logger.Error("failed to call rpc.", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	h.Logger.Error("rpc error",
+		"code", st.Code(),
+		"msg", st.Message(),
+	)
+
+	switch st.Code() {
+	case codes.NotFound:
+		handleError(w, "File not found.", http.StatusNotFound)
+	case codes.DeadlineExceeded:
+		handleError(w, "Link is not valid or expired.", http.StatusNotFound)
+	case codes.PermissionDenied:
+		handleError(w, "Wrong delete token.", http.StatusForbidden)
+	case codes.InvalidArgument:
+		handleError(w, st.Message(), http.StatusBadRequest)
+	default:
+		handleError(w, "Service Internal error.", http.StatusInternalServerError)
+	}
+}
+
+// удалить файл по токену владельца
+func (h *FileHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	path := h.shortName(w, r)
+	if path == "" {
+		return
+	}
+
+	token := deleteToken(w, r)
+	if token == "" {
+		return
+	}
+
+	_, err := h.GRpcClient.DeleteFile(r.Context(), &pb.DeleteFileReq{ShortName: path, DeleteToken: token})
+	if err != nil {
+		h.handleOwnerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// поменять срок жизни ссылки по токену владельца, новый срок в поле ttl (как при загрузке).
+// пределы те же, что при загрузке: сервера, арендатора и ключа, по которому загружен файл
+func (h *FileHandler) UpdateExpiry(w http.ResponseWriter, r *http.Request) {
+	path := h.shortName(w, r)
+	if path == "" {
+		return
+	}
+
+	token := deleteToken(w, r)
+	if token == "" {
+		return
+	}
+
+	ttl, err := parseTTL(r.FormValue("ttl"))
+	if err != nil {
+		handleError(w, "Invalid upload parameter: "+err.Error()+".", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.GRpcClient.UpdateExpiry(r.Context(), &pb.UpdateExpiryReq{
+		ShortName:   path,
+		DeleteToken: token,
+		TtlSeconds:  ttl,
+	})
+	if err != nil {
+		h.handleOwnerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		ExpiresAt *time.Time
+	}{
+		ExpiresAt: unixTime(resp.ExpiresAt),
+	})
 }
 
 func (h *FileHandler) GetInfo(w http.ResponseWriter, r *http.Request) {
@@ -101,19 +342,28 @@ func (h *FileHandler) GetInfo(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(struct {
-		Name        string
-		Size        int
-		ContentType string
+		Name         string
+		Size         int
+		ContentType  string
+		ExpiresAt    *time.Time
+		MaxDownloads int
+		Downloads    int
+		SHA256       string
 	}{
-		Name:        resp.Filename,
-		Size:        int(resp.SizeBytes),
-		ContentType: resp.ContentType,
+		Name:         resp.Filename,
+		Size:         int(resp.SizeBytes),
+		ContentType:  resp.ContentType,
+		ExpiresAt:    unixTime(resp.ExpiresAt),
+		MaxDownloads: int(resp.MaxDownloads),
+		Downloads:    int(resp.Downloads),
+		SHA256:       resp.Sha256,
 	})
 }
 
+// загрузить файлы из формы. один файл получает свою ссылку, как раньше, а несколько файлов
+// становятся набором под одной ссылкой, к которому относятся параметры ссылки из формы
 func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
-	const maxUploadSize = 32 << 20 // 32 кБ
-	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUpload())
 
 	reader, err := r.MultipartReader()
 	if err != nil {
@@ -122,6 +372,25 @@ func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	opts := callerOptions(r)
+	if err := opts.setDigest(r.Header); err != nil {
+		handleError(w, "Invalid upload parameter: "+err.Error()+".", http.StatusBadRequest)
+		return
+	}
+
+	// первый файл откладывается на диск, пока не станет ясно, один ли он в форме
+	var first *spooledFile
+	defer func() { first.remove() }()
+
+	// уже загруженные файлы набора. если набор собрать не удалось, они удаляются
+	var members []*collectionMember
+	done := false
+	defer func() {
+		if !done {
+			h.dropMembers(r.Context(), members)
+		}
+	}()
+
 	for {
 		part, err := reader.NextPart()
 
@@ -136,61 +405,200 @@ func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// файл должен лежать в поле File формы
-		if part.FormName() == "File" {
+		// параметры загрузки должны идти в форме до файла
+		if part.FormName() != "File" && part.FileName() == "" {
+			value, _ := io.ReadAll(io.LimitReader(part, maxFieldSize))
+			if err := opts.set(part.FormName(), string(value)); err != nil {
+				handleError(w, "Invalid upload parameter: "+err.Error()+".", http.StatusBadRequest)
+				return
+			}
 
-			// для определения типа файла читаем первые 512 байт файла (сигнатуру)
-			snBuff := make([]byte, 512)
+			continue
+		}
+
+		// дальше - сам файл: поле File или любое поле с именем файла.
+		// хеши из заголовков части относятся только к этому файлу
+		var digest uploadOptions
+		if err := digest.setDigest(http.Header(part.Header)); err != nil {
+			handleError(w, "Invalid upload parameter: "+err.Error()+".", http.StatusBadRequest)
+			return
+		}
 
-			n, _ := part.Read(snBuff)
-			// тут определяем тип контента и кладём в переменную
-			cType := http.DetectContentType(snBuff[:n])
+		cType, body, ok := sniffContent(w, part)
+		if !ok {
+			return
+		}
 
-			if cType == "application/ms-executable" {
-				handleError(w, "This type of files is not available.", http.StatusInternalServerError)
+		if first == nil && len(members) == 0 {
+			if first = h.spool(w, part.FileName(), cType, digest, body); first == nil {
 				return
 			}
+			continue
+		}
 
-			tmpName := uuid.New().String()
-			tmpDir, _ := os.Create(filepath.Join(h.TmpDir, tmpName))
+		// второй файл - значит, это набор, и отложенный файл загружается первым
+		if first != nil {
+			m := h.sendMember(w, r, first.name, first.size, first.cType, opts.member(first.digest), first.f)
+			if m == nil {
+				return
+			}
+			members = append(members, m)
 
-			// склеиваем буфер с первыми байтами и следующую часть
-			fullReader := io.MultiReader(bytes.NewReader(snBuff[:n]), part)
+			first.remove()
+			first = nil
+		}
 
-			size, _ := io.Copy(tmpDir, fullReader)
-			tmpDir.Close()
+		m := h.sendMember(w, r, part.FileName(), 0, cType, opts.member(digest), body)
+		if m == nil {
+			return
+		}
+		members = append(members, m)
+	}
 
-			resp, err := h.GRpcClient.RegisterFile(r.Context(), &pb.RegisterFileRequest{
-				TmpName:     tmpName,
-				Filename:    part.FileName(),
-				SizeBytes:   size,
-				ContentType: cType,
-			})
+	switch {
+	case first != nil:
+		if digest := first.digest; digest.SHA256 != nil || digest.MD5 != nil {
+			opts.SHA256, opts.MD5 = digest.SHA256, digest.MD5
+		}
+		h.sendFile(w, r, first.name, first.size, first.cType, opts, first.f)
+	case len(members) > 0:
+		done = h.createCollection(w, r, opts, members)
+	default:
+		handleError(w, "No file in the form.", http.StatusBadRequest)
+	}
+}
 
-			if err != nil {
-				st, ok := status.FromError(err)
+// тип содержимого по первым 512 байтам (сигнатуре) и поток, в который они возвращены.
+// false - если такой тип запрещен и ответ с ошибкой уже отправлен
+func sniffContent(w http.ResponseWriter, r io.Reader) (string, io.Reader, bool) {
+	snBuff := make([]byte, 512)
 
-				if !ok {
-					h.Logger.Error("failed to call rpc", "detail", err)
-					handleError(w, "Server Error.", http.StatusInternalServerError)
-					return
-				}
+	n, _ := io.ReadFull(r, snBuff)
+	// тут определяем тип контента и кладём в переменную
+	cType := http.DetectContentType(snBuff[:n])
 
-				h.Logger.Error(
-					"rpc error",
-					"code", st.Code(),
-					"details", st.Details(),
-				)
+	if cType == "application/ms-executable" {
+		handleError(w, "This type of files is not available.", http.StatusInternalServerError)
+		return "", nil, false
+	}
 
-				switch st.Code() {
-				case codes.Internal:
-					handleError(w, "Uploading failed due to server error.", http.StatusInternalServerError)
-				}
+	// склеиваем буфер с первыми байтами и следующую часть
+	return cType, io.MultiReader(bytes.NewReader(snBuff[:n]), r), true
+}
 
-				return
+// передать файл в реестр потоком и отдать клиенту короткое имя,
+// false - если ответ с ошибкой уже отправлен
+func (h *FileHandler) sendFile(w http.ResponseWriter, r *http.Request, name string, size int64, cType string, opts uploadOptions, body io.Reader) bool {
+	resp := h.upload(w, r, name, size, cType, opts, body)
+	if resp == nil {
+		return false
+	}
+
+	json.NewEncoder(w).Encode(resp)
+	return true
+}
+
+// передать файл в реестр потоком, nil - если ответ с ошибкой уже отправлен
+func (h *FileHandler) upload(w http.ResponseWriter, r *http.Request, name string, size int64, cType string, opts uploadOptions, body io.Reader) *pb.RegisterFileResp {
+	// отмена контекста обрывает поток, и реестр не регистрирует недописанный файл
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	stream, err := h.GRpcClient.UploadFile(ctx)
+	if err != nil {
+		h.handleUploadError(w, err)
+		return nil
+	}
+
+	err = stream.Send(&pb.UploadFileReq{
+		Data: &pb.UploadFileReq_Meta{Meta: &pb.FileMeta{
+			Filename:     name,
+			SizeBytes:    size,
+			ContentType:  cType,
+			TtlSeconds:   opts.TTL,
+			MaxDownloads: opts.MaxDownloads,
+			Password:     opts.Password,
+			ShortName:    opts.ShortName,
+			Sha256:       opts.SHA256,
+			Md5:          opts.MD5,
+			KeyId:        opts.KeyID,
+			Owner:        opts.Owner,
+		}},
+	})
+
+	buf := make([]byte, chunkSize)
+	for err == nil {
+		n, rErr := body.Read(buf)
+		if n > 0 {
+			// при ошибке отправки настоящий статус вернет CloseAndRecv
+			if err = stream.Send(&pb.UploadFileReq{Data: &pb.UploadFileReq_Chunk{Chunk: buf[:n]}}); err != nil {
+				break
 			}
+		}
+
+		if rErr == io.EOF {
+			break
+		}
+
+		if rErr != nil {
+			h.Logger.Error("failed to read data", "details", rErr)
+
+			var mbErr *http.MaxBytesError
+			if errors.As(rErr, &mbErr) {
+				handleError(w, "File is too large.", http.StatusRequestEntityTooLarge)
+				return nil
+			}
+
+			handleError(w, "Failed to upload a file.", http.StatusInternalServerError)
+			return nil
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		h.handleUploadError(w, err)
+		return nil
+	}
+
+	return resp
+}
+
+// превращает ошибку rpc при загрузке в http-ответ
+func (h *FileHandler) handleUploadError(w http.ResponseWriter, err error) {
+	st, ok := status.FromError(err)
+
+	if !ok {
+		h.Logger.Error("failed to call rpc", "detail", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	h.Logger.Error(
+		"rpc error",
+		"code", st.Code(),
+		"details", st.Details(),
+	)
 
-			json.NewEncoder(w).Encode(resp)
+	switch st.Code() {
+	case codes.InvalidArgument:
+		handleError(w, st.Message(), http.StatusBadRequest)
+	case codes.AlreadyExists:
+		handleError(w, st.Message(), http.StatusConflict)
+	case codes.Unauthenticated:
+		unauthorized(w, st.Message())
+	case codes.PermissionDenied:
+		handleError(w, st.Message(), http.StatusForbidden)
+	case codes.NotFound:
+		// неизвестный арендатор или пропавший файл будущего набора
+		handleError(w, st.Message(), http.StatusNotFound)
+	case codes.ResourceExhausted:
+		// файл не влез в место по ключу или в квоте - 413, кончилась квота на число файлов - 429
+		code := http.StatusRequestEntityTooLarge
+		if quotaSubject(st) == "files" {
+			code = http.StatusTooManyRequests
 		}
+		handleError(w, st.Message(), code)
+	default:
+		handleError(w, "Uploading failed due to server error.", http.StatusInternalServerError)
 	}
 }
@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// список своих загрузок: GET /files?name=report&type=image/*&expires_after=...&expires_before=...
+// время - RFC 3339 или дата вида 2006-01-02. страницы идут от новых файлов к старым, следующая
+// страница - ?page=<NextPage из ответа>. без ключа или токена своих файлов нет, поэтому 401
+
+var errInvalidTime = errors.New("time should be RFC 3339 or a date like 2006-01-02")
+
+func (h *FileHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
+	opts := callerOptions(r)
+	if opts.Owner == "" {
+		unauthorized(w, "API key or token is required to list files.")
+		return
+	}
+
+	q := r.URL.Query()
+	req := &pb.ListFilesReq{
+		Owner:       opts.Owner,
+		NamePrefix:  q.Get("name"),
+		ContentType: q.Get("type"),
+		PageToken:   q.Get("page"),
+	}
+
+	var err error
+	if req.ExpiresAfter, err = parseListTime(q.Get("expires_after")); err != nil {
+		handleError(w, "Invalid expires_after: "+err.Error()+".", http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresBefore, err = parseListTime(q.Get("expires_before")); err != nil {
+		handleError(w, "Invalid expires_before: "+err.Error()+".", http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n <= 0 {
+			handleError(w, "Invalid limit: should be a positive number.", http.StatusBadRequest)
+			return
+		}
+		req.PageSize = int32(n)
+	}
+
+	resp, err := h.GRpcClient.ListFiles(r.Context(), req)
+	if status.Code(err) == codes.InvalidArgument {
+		handleError(w, "Invalid file filter.", http.StatusBadRequest)
+		return
+	}
+	if status.Code(err) == codes.NotFound {
+		handleError(w, "Tenant not found.", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.Logger.Error("failed to list files", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	type file struct {
+		ShortName    string
+		Name         string
+		Size         int
+		ContentType  string
+		CreatedAt    *time.Time
+		ExpiresAt    *time.Time
+		MaxDownloads int
+		Downloads    int
+		Protected    bool
+		Collection   string // набор, в который входит файл, пустая строка - отдельный файл
+		Path         string
+	}
+
+	files := make([]file, 0, len(resp.Files))
+	for _, f := range resp.Files {
+		path := tenantPath(r, "/get/"+f.ShortName+"/")
+		if f.CollectionId != "" {
+			path = tenantPath(r, "/get/"+f.CollectionId+"/"+f.ShortName+"/")
+		}
+
+		files = append(files, file{
+			ShortName:    f.ShortName,
+			Name:         f.Filename,
+			Size:         int(f.SizeBytes),
+			ContentType:  f.ContentType,
+			CreatedAt:    unixTime(f.CreatedAt),
+			ExpiresAt:    unixTime(f.ExpiresAt),
+			MaxDownloads: int(f.MaxDownloads),
+			Downloads:    int(f.Downloads),
+			Protected:    f.Protected,
+			Collection:   f.CollectionId,
+			Path:         path,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Files    []file
+		NextPage string // пустая строка - страница последняя
+	}{
+		Files:    files,
+		NextPage: resp.NextPageToken,
+	})
+}
+
+// время из параметра запроса в unix-секундах, пустая строка - 0 (не задано)
+func parseListTime(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Unix(), nil
+		}
+	}
+
+	return 0, errInvalidTime
+}
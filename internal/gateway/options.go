@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// параметры загрузки, которые клиент передает полями формы перед полем File
+// (или ключами Upload-Metadata при возобновляемой загрузке)
+type uploadOptions struct {
+	TTL          int64  // секунды, 0 - срок по умолчанию, -1 - бессрочно
+	MaxDownloads int64  // сколько раз можно скачать файл, 0 - без ограничений
+	Password     string // пароль на скачивание, пустая строка - без пароля
+	ShortName    string // желаемое короткое имя ссылки, пустая строка - случайное
+	SHA256       []byte // ожидаемые хеши содержимого из заголовков Digest и Content-MD5,
+	MD5          []byte // их проверяет реестр
+	KeyID        string // ключ, по которому идет загрузка. ставит гейтвей, а не клиент
+	Owner        string // субъект токена загружающего, тоже ставит гейтвей
+}
+
+var (
+	errInvalidTTL   = errors.New("ttl should be a duration like 1h, 1d, 7d or never")
+	errInvalidLimit = errors.New("max_downloads should be a non-negative number")
+	errInvalidName  = errors.New("name should contain only letters and digits")
+)
+
+// применить одно поле формы, неизвестные поля игнорируются
+func (o *uploadOptions) set(name, value string) error {
+	switch name {
+	case "ttl":
+		ttl, err := parseTTL(value)
+		if err != nil {
+			return err
+		}
+		o.TTL = ttl
+	case "max_downloads":
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || n < 0 {
+			return errInvalidLimit
+		}
+		o.MaxDownloads = n
+	case "password":
+		o.Password = value
+	case "name":
+		// длину, зарезервированные слова и то, что загрузка идет по ключу или токену, проверяет реестр
+		if ok, _ := regexp.MatchString(idRegexp, value); !ok {
+			return errInvalidName
+		}
+		o.ShortName = value
+	}
+
+	return nil
+}
+
+// срок жизни ссылки: "never", дни вида "7d" или длительность в формате time.ParseDuration
+func parseTTL(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+
+	switch {
+	case s == "":
+		return 0, nil
+	case s == "never":
+		return -1, nil
+	case strings.HasSuffix(s, "d"):
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, errInvalidTTL
+		}
+		return int64(days) * 24 * 60 * 60, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Second {
+		return 0, errInvalidTTL
+	}
+
+	return int64(d / time.Second), nil
+}
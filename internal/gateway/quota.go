@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// квота владельца: сколько места и файлов он занимает и сколько ему разрешено (0 - без ограничений).
+// истекшие файлы занимают место, пока реестр их не удалит. у арендатора своя общая квота,
+// она отдается в поле Tenant
+
+func (h *FileHandler) GetQuota(w http.ResponseWriter, r *http.Request) {
+	opts := callerOptions(r)
+	if opts.Owner == "" {
+		unauthorized(w, "API key or token is required to see the quota.")
+		return
+	}
+
+	resp, err := h.GRpcClient.GetUsage(r.Context(), &pb.GetUsageReq{Owner: opts.Owner})
+	if status.Code(err) == codes.NotFound {
+		handleError(w, "Tenant not found.", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.Logger.Error("failed to get usage", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	type usage struct {
+		Bytes    int64
+		Files    int64
+		MaxBytes int64
+		MaxFiles int64
+	}
+	toJSON := func(u *pb.Usage) *usage {
+		return &usage{Bytes: u.Bytes, Files: u.Files, MaxBytes: u.MaxBytes, MaxFiles: u.MaxFiles}
+	}
+
+	body := struct {
+		*usage
+		Tenant *usage `json:",omitempty"` // только в пространстве арендатора
+	}{usage: toJSON(resp)}
+	if resp.Tenant != nil {
+		body.Tenant = toJSON(resp.Tenant)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// какая квота нарушена по деталям ответа реестра: bytes или files.
+// пустая строка - дело не в квоте (например, файл больше, чем разрешает ключ)
+func quotaSubject(st *status.Status) string {
+	for _, d := range st.Details() {
+		if qf, ok := d.(*errdetails.QuotaFailure); ok && len(qf.Violations) > 0 {
+			return qf.Violations[0].Subject
+		}
+	}
+
+	return ""
+}
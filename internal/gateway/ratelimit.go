@@ -0,0 +1,252 @@
+package gateway
+
+import (
+	"container/list"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// ограничение частоты запросов по алгоритму token bucket: у каждого клиента на каждый вид
+// запросов своя корзина на Burst запросов, которая пополняется со скоростью PerMinute.
+// клиент с ключом или токеном считается по владельцу, остальные - по адресу. за доверенными
+// прокси адрес клиента берется из X-Forwarded-For, иначе его мог бы подменить кто угодно.
+// у IPv6 считается вся подсеть /64, которую провайдер обычно выдает одному клиенту целиком.
+// сверх лимита - 429 и Retry-After. запросы с ключом или токеном вдобавок считаются по адресу
+// еще до проверки ключа: иначе перебор ключей получал бы 401 без ограничений, а каждая
+// попытка стоила бы реестру поиска ключа
+
+type RateLimit struct {
+	Upload   Rate // новые загрузки, куски возобновляемой загрузки не считаются
+	Download Rate // скачивание файлов, наборов и архивов
+	Info     Rate // информация о файле, списки, квота и действия владельца
+	Auth     Rate // запросы с ключом или токеном на адрес, в том числе с неверным ключом
+
+	TrustedProxies []netip.Prefix // адреса прокси, которым можно верить в X-Forwarded-For
+	Logger         *slog.Logger
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element // значения - *bucket
+	recent  list.List                // корзины от последней использованной к самой давней
+}
+
+// PerMinute == 0 - без ограничений. Burst == 0 - столько же, сколько PerMinute
+type Rate struct {
+	PerMinute int
+	Burst     int
+}
+
+type bucket struct {
+	key     string
+	tokens  float64
+	last    time.Time // когда tokens пересчитаны в последний раз
+	full    time.Time // когда корзина наполнится, если клиент больше не придет
+	limited bool      // последний запрос отклонен, чтобы не писать в лог каждый отказ
+}
+
+// больше корзин в памяти не держится: самая давно использованная уступает место новой.
+// переменная, а не константа, чтобы тесты не заводили сотню тысяч клиентов
+var maxTrackedClients = 100000
+
+// клиент передается реестру в метаданных, чтобы тот считал попытки ввода пароля по клиенту,
+// а не по всем вместе. значение экранируется: в метаданных допустим только ascii
+const clientMetadata = "client"
+
+// работает и без ограничений: nil-лимитер просто не доверяет ни одному прокси
+func (l *RateLimit) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := metadata.AppendToOutgoingContext(r.Context(), clientMetadata, url.QueryEscape(l.client(r)))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ставится перед проверкой ключа, так что владелец еще неизвестен и считается адрес
+func (l *RateLimit) auth(next http.Handler) http.Handler {
+	if l == nil || l.Auth.PerMinute == 0 {
+		return next
+	}
+
+	limited := l.limit("auth", l.Auth, next.ServeHTTP)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") == "" && r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limited(w, r)
+	})
+}
+
+func (l *RateLimit) uploads(next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+
+	return l.limit("upload", l.Upload, next)
+}
+
+func (l *RateLimit) downloads(next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+
+	return l.limit("download", l.Download, next)
+}
+
+func (l *RateLimit) info(next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+
+	return l.limit("info", l.Info, next)
+}
+
+// корзины разных видов запросов не пересекаются: ключ корзины - вид запроса и клиент
+func (l *RateLimit) limit(kind string, rate Rate, next http.HandlerFunc) http.HandlerFunc {
+	if rate.PerMinute == 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := l.client(r)
+
+		wait, ok := l.take(kind+" "+client, rate, time.Now())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			handleError(w, "Too many requests.", http.StatusTooManyRequests)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// взять токен из корзины клиента. если токенов нет - через сколько появится следующий
+func (l *RateLimit) take(key string, rate Rate, now time.Time) (time.Duration, bool) {
+	burst := float64(rate.Burst)
+	if rate.Burst == 0 {
+		burst = float64(rate.PerMinute)
+	}
+	perSecond := float64(rate.PerMinute) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = make(map[string]*list.Element)
+	}
+
+	l.evict(now)
+
+	e, ok := l.buckets[key]
+	if ok {
+		l.recent.MoveToFront(e)
+	} else {
+		if len(l.buckets) >= maxTrackedClients {
+			l.remove(l.recent.Back())
+		}
+
+		e = l.recent.PushFront(&bucket{key: key, tokens: burst, last: now})
+		l.buckets[key] = e
+	}
+	b := e.Value.(*bucket)
+
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		if !b.limited && l.Logger != nil {
+			l.Logger.Warn("rate limit exceeded", "client", key)
+		}
+		b.limited = true
+		return time.Duration((1 - b.tokens) / perSecond * float64(time.Second)), false
+	}
+
+	b.tokens--
+	b.limited = false
+	b.full = now.Add(time.Duration((burst - b.tokens) / perSecond * float64(time.Second)))
+	return 0, true
+}
+
+// выкинуть с конца очереди корзины, которые уже наполнились: такой клиент ничем не отличается
+// от нового. каждая корзина выкидывается один раз, так что на запрос это в среднем O(1)
+func (l *RateLimit) evict(now time.Time) {
+	for e := l.recent.Back(); e != nil && now.After(e.Value.(*bucket).full); e = l.recent.Back() {
+		l.remove(e)
+	}
+}
+
+func (l *RateLimit) remove(e *list.Element) {
+	delete(l.buckets, e.Value.(*bucket).key)
+	l.recent.Remove(e)
+}
+
+// кого считать: владельца по ключу или токену, а без них - адрес
+func (l *RateLimit) client(r *http.Request) string {
+	if c := callerFrom(r.Context()); c != nil {
+		if owner := c.owner(); owner != "" {
+			return owner
+		}
+	}
+
+	ip := l.clientIP(r)
+	if !ip.IsValid() {
+		return r.RemoteAddr
+	}
+	if ip.Is6() {
+		return netip.PrefixFrom(ip, 64).Masked().String()
+	}
+
+	return ip.String()
+}
+
+// адрес клиента. X-Forwarded-For читается справа налево: каждый прокси дописывает адрес,
+// от которого получил запрос, так что первый недоверенный адрес с конца - настоящий клиент
+func (l *RateLimit) clientIP(r *http.Request) netip.Addr {
+	addr, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	ip := addr.Addr().Unmap()
+	if !l.trusted(ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+
+		ip = hop.Unmap()
+		if !l.trusted(ip) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func (l *RateLimit) trusted(ip netip.Addr) bool {
+	if l == nil {
+		return false
+	}
+
+	for _, p := range l.TrustedProxies {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,248 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimit_Take(t *testing.T) {
+	l := &RateLimit{}
+	rate := Rate{PerMinute: 60, Burst: 2}
+	now := time.Now()
+
+	// корзина нового клиента полная
+	for i := 0; i < 2; i++ {
+		if _, ok := l.take("info a", rate, now); !ok {
+			t.Fatalf("Expected request %d to pass", i)
+		}
+	}
+
+	wait, ok := l.take("info a", rate, now)
+	if ok || wait != time.Second {
+		t.Errorf("Expected rejection with 1s wait, got %v, %v", wait, ok)
+	}
+
+	// другие клиенты и виды запросов считаются отдельно
+	if _, ok := l.take("info b", rate, now); !ok {
+		t.Error("Expected another client to pass")
+	}
+	if _, ok := l.take("download a", rate, now); !ok {
+		t.Error("Expected another kind to pass")
+	}
+
+	// отказ токенов не тратит, и ждать остается меньше
+	wait, ok = l.take("info a", rate, now.Add(500*time.Millisecond))
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("Expected rejection with 500ms wait, got %v, %v", wait, ok)
+	}
+
+	if _, ok := l.take("info a", rate, now.Add(time.Second)); !ok {
+		t.Error("Expected a refilled token")
+	}
+	if _, ok := l.take("info a", rate, now.Add(time.Second)); ok {
+		t.Error("Expected only one token after 1s")
+	}
+
+	// корзина не копит больше Burst
+	later := now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if _, ok := l.take("info a", rate, later); !ok {
+			t.Fatalf("Expected request %d to pass after an hour", i)
+		}
+	}
+	if _, ok := l.take("info a", rate, later); ok {
+		t.Error("Expected burst to cap the bucket")
+	}
+}
+
+func TestRateLimit_Eviction(t *testing.T) {
+	defer func(n int) { maxTrackedClients = n }(maxTrackedClients)
+	maxTrackedClients = 3
+
+	l := &RateLimit{}
+	rate := Rate{PerMinute: 60, Burst: 1}
+	now := time.Now()
+
+	// корзин больше предела не бывает, место уступает самая давно использованная
+	for i := 0; i < 5; i++ {
+		l.take("info "+strconv.Itoa(i), rate, now)
+	}
+	if len(l.buckets) != 3 || l.recent.Len() != 3 {
+		t.Fatalf("Expected 3 buckets, got %d", len(l.buckets))
+	}
+
+	l.take("info 2", rate, now) // 2 теперь использована последней
+	l.take("info 5", rate, now)
+	for _, key := range []string{"info 0", "info 1", "info 3"} {
+		if _, ok := l.buckets[key]; ok {
+			t.Errorf("Expected %s to be evicted", key)
+		}
+	}
+	for _, key := range []string{"info 2", "info 4", "info 5"} {
+		if _, ok := l.buckets[key]; !ok {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+
+	// вытесненный клиент считается новым, а оставшийся - нет
+	if _, ok := l.take("info 2", rate, now); ok {
+		t.Error("Expected a kept client to stay limited")
+	}
+
+	// наполнившиеся корзины выкидываются и без нехватки места
+	l.take("info 6", rate, now.Add(time.Minute))
+	if len(l.buckets) != 1 {
+		t.Errorf("Expected full buckets to be swept, got %d", len(l.buckets))
+	}
+}
+
+func TestRateLimit_ClientIP(t *testing.T) {
+	l := &RateLimit{TrustedProxies: []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"direct", "1.2.3.4:5000", nil, "1.2.3.4"},
+		{"untrusted remote ignores header", "1.2.3.4:5000", []string{"9.9.9.9"}, "1.2.3.4"},
+		{"trusted proxy", "10.0.0.1:5000", []string{"1.2.3.4"}, "1.2.3.4"},
+		{"spoofed hops before the client", "10.0.0.1:5000", []string{"9.9.9.9, 1.2.3.4"}, "1.2.3.4"},
+		{"chain of proxies", "10.0.0.1:5000", []string{"1.2.3.4, 10.0.0.2, 10.0.0.3"}, "1.2.3.4"},
+		{"several headers", "10.0.0.1:5000", []string{"9.9.9.9", "1.2.3.4, 10.0.0.2"}, "1.2.3.4"},
+		{"garbage stops the walk", "10.0.0.1:5000", []string{"1.2.3.4, bogus, 10.0.0.2"}, "10.0.0.2"},
+		{"no header", "10.0.0.1:5000", nil, "10.0.0.1"},
+		{"mapped address", "[::ffff:1.2.3.4]:5000", nil, "1.2.3.4"},
+		{"ipv6 proxy", "[fd00::1]:5000", []string{"2001:db8::1"}, "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			if got := l.clientIP(r).String(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRateLimit_Client(t *testing.T) {
+	l := &RateLimit{}
+
+	client := func(remote string, c *caller) string {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remote
+		if c != nil {
+			r = r.WithContext(context.WithValue(r.Context(), callerCtxKey{}, c))
+		}
+		return l.client(r)
+	}
+
+	// вся подсеть /64 - один клиент
+	if a, b := client("[2001:db8::1]:1", nil), client("[2001:db8::ffff:2]:2", nil); a != b || a != "2001:db8::/64" {
+		t.Errorf("Expected one /64 client, got %s and %s", a, b)
+	}
+	if a, b := client("[2001:db8::1]:1", nil), client("[2001:db8:0:1::1]:1", nil); a == b {
+		t.Errorf("Expected different /64 to differ, got %s", a)
+	}
+
+	// с ключом или токеном считается владелец, а не адрес
+	if got := client("1.2.3.4:1", &caller{Subject: "alice"}); got != "alice" {
+		t.Errorf("Expected token subject, got %s", got)
+	}
+	if got := client("1.2.3.4:1", &caller{KeyID: "k1"}); got != keyOwnerPrefix+"k1" {
+		t.Errorf("Expected key owner, got %s", got)
+	}
+	if got := client("1.2.3.4:1", &caller{}); got != "1.2.3.4" {
+		t.Errorf("Expected address for an anonymous caller, got %s", got)
+	}
+}
+
+func TestRateLimit_ClientMetadata(t *testing.T) {
+	var got []string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		md, _ := metadata.FromOutgoingContext(r.Context())
+		got = md.Get(clientMetadata)
+	})
+
+	// и без лимитера реестр узнает клиента, имя владельца экранируется
+	var l *RateLimit
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "1.2.3.4:5000"
+	r = r.WithContext(context.WithValue(r.Context(), callerCtxKey{}, &caller{Subject: "алиса"}))
+	l.middleware(next).ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(got) != 1 || got[0] != url.QueryEscape("алиса") {
+		t.Errorf("Expected escaped subject, got %v", got)
+	}
+}
+
+// реестр, который не знает ни одного ключа и считает проверки
+type rejectingRegistry struct {
+	pb.RegServiceClient
+	calls int
+}
+
+func (r *rejectingRegistry) Authenticate(ctx context.Context, in *pb.AuthenticateReq, opts ...grpc.CallOption) (*pb.ApiKey, error) {
+	r.calls++
+	return nil, status.Error(codes.Unauthenticated, "Invalid API key.")
+}
+
+func TestRateLimit_InvalidKeys(t *testing.T) {
+	reg := &rejectingRegistry{}
+	auth := &Auth{Client: reg, Logger: slog.New(slog.DiscardHandler)}
+	l := &RateLimit{Auth: Rate{PerMinute: 60, Burst: 3}}
+	h := l.auth(auth.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	serve := func(key string) int {
+		r := httptest.NewRequest("GET", "/files", nil)
+		r.RemoteAddr = "1.2.3.4:5000"
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// перебор ключей тратит токены адреса, и сверх лимита реестр уже не спрашивают
+	for i := 0; i < 3; i++ {
+		if code := serve("gfs_guess" + strconv.Itoa(i)); code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401 for guess %d, got %d", i, code)
+		}
+	}
+	if code := serve("gfs_guess"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 after the burst, got %d", code)
+	}
+	if reg.calls != 3 {
+		t.Errorf("Expected 3 key lookups, got %d", reg.calls)
+	}
+
+	// запросы без ключа этот лимит не трогает
+	if code := serve(""); code != http.StatusOK {
+		t.Errorf("Expected an anonymous request to pass, got %d", code)
+	}
+}
@@ -0,0 +1,457 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// возобновляемая загрузка по мотивам протокола tus: клиент создает загрузку заранее известного
+// размера, досылает куски через PATCH с указанием смещения, узнает прогресс через HEAD и,
+// когда все байты на месте, завершает загрузку. только после этого файл передается в реестр.
+
+const (
+	tusVersion     = "1.0.0"
+	offsetCType    = "application/offset+octet-stream"
+	sessionInfoExt = ".json"
+	sessionDataExt = ".part"
+
+	uploadTTL     = 24 * time.Hour // сколько живет незавершенная загрузка, если UploadTTL не задан
+	sweepInterval = time.Hour      // как часто удаляются брошенные загрузки
+)
+
+// метаданные незавершенной загрузки, лежат рядом с данными во временной папке,
+// поэтому переживают перезапуск гейтвея
+type uploadSession struct {
+	ID        string
+	Filename  string
+	Length    int64
+	Options   uploadOptions
+	Tenant    string
+	CreatedAt time.Time
+}
+
+// блокировки на каждую загрузку, чтобы два PATCH на одну загрузку не писали одновременно.
+// блокировка живет, пока ее кто-то держит или ждет, иначе каждая загрузка оставляла бы ее в памяти
+type uploadLocks struct {
+	mu    sync.Mutex
+	locks map[string]*uploadLock
+}
+
+type uploadLock struct {
+	sync.Mutex
+	refs int // сколько запросов держат или ждут блокировку
+}
+
+func (l *uploadLocks) lock(id string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*uploadLock)
+	}
+
+	m, ok := l.locks[id]
+	if !ok {
+		m = &uploadLock{}
+		l.locks[id] = m
+	}
+	m.refs++
+	l.mu.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+
+		l.mu.Lock()
+		if m.refs--; m.refs == 0 {
+			delete(l.locks, id)
+		}
+		l.mu.Unlock()
+	}
+}
+
+func (h *FileHandler) uploadTTL() time.Duration {
+	if h.UploadTTL > 0 {
+		return h.UploadTTL
+	}
+
+	return uploadTTL
+}
+
+func (h *FileHandler) sessionPath(id, ext string) string {
+	return filepath.Join(h.TmpDir, id+ext)
+}
+
+// прочитать метаданные загрузки, айди проверяется, чтобы из пути нельзя было выйти за пределы папки
+func (h *FileHandler) loadSession(id string) (*uploadSession, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, os.ErrNotExist
+	}
+
+	data, err := os.ReadFile(h.sessionPath(id, sessionInfoExt))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &uploadSession{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// текущее смещение загрузки - это просто размер уже записанных данных
+func (h *FileHandler) sessionOffset(id string) (int64, error) {
+	st, err := os.Stat(h.sessionPath(id, sessionDataExt))
+	if err != nil {
+		return 0, err
+	}
+
+	return st.Size(), nil
+}
+
+func (h *FileHandler) removeSession(id string) {
+	_ = os.Remove(h.sessionPath(id, sessionInfoExt))
+	_ = os.Remove(h.sessionPath(id, sessionDataExt))
+}
+
+// удалить загрузки, созданные раньше, чем UploadTTL назад, и данные без метаданных того же возраста.
+// реестр чистит папку гейтвея, только если делит с ним диск, так что гейтвей убирает за собой сам
+func (h *FileHandler) sweepSessions(now time.Time) {
+	entries, err := os.ReadDir(h.TmpDir)
+	if err != nil {
+		h.Logger.Error("failed to list uploads", "details", err)
+		return
+	}
+
+	before := now.Add(-h.uploadTTL())
+	for _, e := range entries {
+		id, ext := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())), filepath.Ext(e.Name())
+		if e.IsDir() || uuid.Validate(id) != nil {
+			continue // файлы форм и чужие файлы не трогаем
+		}
+
+		// метаданные, которые не читаются (их, возможно, как раз пишут), и данные без метаданных
+		// стареют по времени изменения
+		var expired bool
+		switch ext {
+		case sessionInfoExt:
+			if s, err := h.loadSession(id); err == nil {
+				expired = s.CreatedAt.Before(before)
+			} else {
+				expired = modifiedBefore(e, before)
+			}
+		case sessionDataExt:
+			if _, err := os.Stat(h.sessionPath(id, sessionInfoExt)); errors.Is(err, os.ErrNotExist) {
+				expired = modifiedBefore(e, before)
+			}
+		}
+		if !expired {
+			continue
+		}
+
+		unlock := h.locks.lock(id)
+		h.removeSession(id)
+		unlock()
+
+		if ext == sessionInfoExt {
+			h.Logger.Info("removed an abandoned upload", "upload", id)
+		}
+	}
+}
+
+func modifiedBefore(e os.DirEntry, before time.Time) bool {
+	info, err := e.Info()
+	return err == nil && info.ModTime().Before(before)
+}
+
+// запуск периодической уборки брошенных загрузок, по аналогии с очисткой в реестре
+func (h *FileHandler) StartSweeper(ctx context.Context) {
+	h.Logger.Info("starting upload sweeper", "upload_ttl", h.uploadTTL())
+
+	go func() {
+		ti := time.NewTicker(min(sweepInterval, h.uploadTTL()))
+		defer ti.Stop()
+
+		for {
+			select {
+			case t := <-ti.C:
+				h.sweepSessions(t)
+			case <-ctx.Done():
+				h.Logger.Info("upload sweeper stopped due to cancelled context", "", ctx.Err())
+				return
+			}
+		}
+	}()
+}
+
+// разбирает заголовок Upload-Metadata вида "filename ZmlsZS50eHQ=,key value"
+func parseUploadMetadata(header string) map[string]string {
+	meta := make(map[string]string)
+
+	for pair := range strings.SplitSeq(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		meta[key] = string(decoded)
+	}
+
+	return meta
+}
+
+// загрузка, найденная по пути запроса, или nil, если ответ уже отправлен.
+// продолжить загрузку можно только с тем же ключом и токеном того же пользователя
+// и у того же арендатора, с которыми она создана, и не позже UploadTTL после создания
+func (h *FileHandler) fetchSession(w http.ResponseWriter, r *http.Request) *uploadSession {
+	s, err := h.loadSession(r.PathValue("uid"))
+	c := callerOptions(r)
+	if err == nil && (s.Options.KeyID != c.KeyID || s.Options.Owner != c.Owner || s.Tenant != tenantFrom(r.Context()).Name) {
+		err = os.ErrNotExist
+	}
+	// просроченную загрузку вот-вот удалит уборка
+	if err == nil && time.Since(s.CreatedAt) > h.uploadTTL() {
+		err = os.ErrNotExist
+	}
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			h.Logger.Error("failed to load upload", "details", err)
+			handleError(w, "Server Error.", http.StatusInternalServerError)
+			return nil
+		}
+
+		handleError(w, "Upload not found.", http.StatusNotFound)
+		return nil
+	}
+
+	return s
+}
+
+// POST: создать загрузку, размер передается в Upload-Length
+func (h *FileHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		handleError(w, "Upload-Length header is required.", http.StatusBadRequest)
+		return
+	}
+
+	if length > h.uploadLimit(r) {
+		handleError(w, "File is too large.", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	meta := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	s := uploadSession{
+		ID:        uuid.New().String(),
+		Filename:  meta["filename"],
+		Length:    length,
+		Options:   callerOptions(r),
+		Tenant:    tenantFrom(r.Context()).Name,
+		CreatedAt: time.Now(),
+	}
+
+	// параметры загрузки передаются теми же ключами, что и поля формы в /upload
+	for key, value := range meta {
+		if err := s.Options.set(key, value); err != nil {
+			handleError(w, "Invalid upload parameter: "+err.Error()+".", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// ожидаемый хеш можно передать при создании загрузки или при ее завершении
+	if err := s.Options.setDigest(r.Header); err != nil {
+		handleError(w, "Invalid upload parameter: "+err.Error()+".", http.StatusBadRequest)
+		return
+	}
+
+	data, _ := json.Marshal(s)
+	if err := os.WriteFile(h.sessionPath(s.ID, sessionInfoExt), data, 0644); err != nil {
+		h.Logger.Error("failed to create upload", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	f, err := os.Create(h.sessionPath(s.ID, sessionDataExt))
+	if err != nil {
+		h.removeSession(s.ID)
+		h.Logger.Error("failed to create upload", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+	f.Close()
+
+	w.Header().Set("Location", tenantPath(r, "/upload/resumable/"+s.ID+"/"))
+	w.Header().Set("Upload-Offset", "0")
+	w.Header().Set("Upload-Expires", s.CreatedAt.Add(h.uploadTTL()).UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// HEAD: узнать, сколько байт уже принято
+func (h *FileHandler) UploadStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+
+	s := h.fetchSession(w, r)
+	if s == nil {
+		return
+	}
+
+	offset, err := h.sessionOffset(s.ID)
+	if err != nil {
+		h.Logger.Error("failed to stat upload", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(s.Length, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// PATCH: дописать кусок, Upload-Offset должен совпадать с уже принятым размером
+func (h *FileHandler) UploadChunk(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	if r.Header.Get("Content-Type") != offsetCType {
+		handleError(w, "Content-Type should be "+offsetCType+".", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	s := h.fetchSession(w, r)
+	if s == nil {
+		return
+	}
+
+	unlock := h.locks.lock(s.ID)
+	defer unlock()
+
+	offset, err := h.sessionOffset(s.ID)
+	if err != nil {
+		h.Logger.Error("failed to stat upload", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	clientOffset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		handleError(w, "Upload-Offset header is required.", http.StatusBadRequest)
+		return
+	}
+
+	if clientOffset != offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+		handleError(w, "Upload-Offset does not match the uploaded size.", http.StatusConflict)
+		return
+	}
+
+	f, err := os.OpenFile(h.sessionPath(s.ID, sessionDataExt), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		h.Logger.Error("failed to open upload", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	// больше заявленного размера не принимаем. если соединение оборвалось, то все, что успело
+	// дойти, остается на диске, и клиент продолжит с нового смещения
+	written, err := io.Copy(f, io.LimitReader(r.Body, s.Length-offset))
+	f.Close()
+	offset += written
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	if err != nil {
+		h.Logger.Error("upload chunk interrupted", "upload", s.ID, "details", err)
+		handleError(w, "Failed to upload a chunk.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE: отменить загрузку и удалить принятые данные
+func (h *FileHandler) CancelUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	s := h.fetchSession(w, r)
+	if s == nil {
+		return
+	}
+
+	unlock := h.locks.lock(s.ID)
+	defer unlock()
+
+	h.removeSession(s.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST .../finish/: когда все байты приняты, регистрируем файл в реестре
+func (h *FileHandler) FinishUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	s := h.fetchSession(w, r)
+	if s == nil {
+		return
+	}
+
+	unlock := h.locks.lock(s.ID)
+	defer unlock()
+
+	offset, err := h.sessionOffset(s.ID)
+	if err != nil {
+		h.Logger.Error("failed to stat upload", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	if offset != s.Length {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+		handleError(w, "Upload is not complete yet.", http.StatusConflict)
+		return
+	}
+
+	if err := s.Options.setDigest(r.Header); err != nil {
+		handleError(w, "Invalid upload parameter: "+err.Error()+".", http.StatusBadRequest)
+		return
+	}
+
+	f, err := os.Open(h.sessionPath(s.ID, sessionDataExt))
+	if err != nil {
+		h.Logger.Error("failed to open upload", "details", err)
+		handleError(w, "Server Error.", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	snBuff := make([]byte, 512)
+	n, _ := io.ReadFull(f, snBuff)
+
+	cType := http.DetectContentType(snBuff[:n])
+	if cType == "application/ms-executable" {
+		h.removeSession(s.ID)
+		handleError(w, "This type of files is not available.", http.StatusInternalServerError)
+		return
+	}
+
+	fullReader := io.MultiReader(bytes.NewReader(snBuff[:n]), f)
+	if h.sendFile(w, r, s.Filename, s.Length, cType, s.Options, fullReader) {
+		h.removeSession(s.ID)
+	}
+}
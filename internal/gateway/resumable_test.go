@@ -0,0 +1,221 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newUploadHandler(t *testing.T) *FileHandler {
+	return &FileHandler{TmpDir: t.TempDir(), Logger: slog.New(slog.DiscardHandler)}
+}
+
+// запрос к загрузке id от имени клиента c у арендатора tenantName
+func uploadRequest(method, id, body string, c *caller, tenantName string) *http.Request {
+	r := httptest.NewRequest(method, "/upload/resumable/"+id+"/", strings.NewReader(body))
+	r.SetPathValue("uid", id)
+
+	ctx := context.WithValue(r.Context(), tenantCtxKey{}, tenant{Name: tenantName})
+	if c != nil {
+		ctx = context.WithValue(ctx, callerCtxKey{}, c)
+	}
+
+	return r.WithContext(ctx)
+}
+
+func createUpload(t *testing.T, h *FileHandler, length string, c *caller, tenantName string) string {
+	r := uploadRequest(http.MethodPost, "", "", c, tenantName)
+	r.Header.Set("Upload-Length", length)
+
+	w := httptest.NewRecorder()
+	h.CreateUpload(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	id := strings.Trim(strings.TrimPrefix(w.Header().Get("Location"), "/upload/resumable/"), "/")
+	if _, err := uuid.Parse(id); err != nil {
+		t.Fatalf("Unexpected Location %q", w.Header().Get("Location"))
+	}
+
+	return id
+}
+
+func patchChunk(h *FileHandler, id, offset, body string) *httptest.ResponseRecorder {
+	r := uploadRequest(http.MethodPatch, id, body, nil, "")
+	r.Header.Set("Content-Type", offsetCType)
+	r.Header.Set("Upload-Offset", offset)
+
+	w := httptest.NewRecorder()
+	h.UploadChunk(w, r)
+	return w
+}
+
+func uploadStatus(h *FileHandler, id string, c *caller, tenantName string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.UploadStatus(w, uploadRequest(http.MethodHead, id, "", c, tenantName))
+	return w
+}
+
+func TestResumable_Protocol(t *testing.T) {
+	h := newUploadHandler(t)
+
+	for _, length := range []string{"", "0", "-1", "abc"} {
+		r := uploadRequest(http.MethodPost, "", "", nil, "")
+		r.Header.Set("Upload-Length", length)
+		w := httptest.NewRecorder()
+		if h.CreateUpload(w, r); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for Upload-Length %q, got %d", length, w.Code)
+		}
+	}
+
+	id := createUpload(t, h, "10", nil, "")
+
+	r := uploadRequest(http.MethodPatch, id, "01234", nil, "")
+	r.Header.Set("Upload-Offset", "0")
+	w := httptest.NewRecorder()
+	if h.UploadChunk(w, r); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 without Content-Type, got %d", w.Code)
+	}
+
+	if w := patchChunk(h, id, "0", "01234"); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "5" {
+		t.Fatalf("Expected 204 at offset 5, got %d at %s", w.Code, w.Header().Get("Upload-Offset"))
+	}
+
+	// повтор уже принятого куска - конфликт, и клиент узнает, откуда продолжать
+	if w := patchChunk(h, id, "0", "01234"); w.Code != http.StatusConflict || w.Header().Get("Upload-Offset") != "5" {
+		t.Errorf("Expected 409 with offset 5, got %d with %s", w.Code, w.Header().Get("Upload-Offset"))
+	}
+
+	w = uploadStatus(h, id, nil, "")
+	if w.Code != http.StatusOK || w.Header().Get("Upload-Offset") != "5" || w.Header().Get("Upload-Length") != "10" {
+		t.Errorf("Expected 200 with 5 of 10, got %d with %s of %s", w.Code, w.Header().Get("Upload-Offset"), w.Header().Get("Upload-Length"))
+	}
+
+	// незаконченную загрузку завершить нельзя
+	w = httptest.NewRecorder()
+	h.FinishUpload(w, uploadRequest(http.MethodPost, id, "", nil, ""))
+	if w.Code != http.StatusConflict || w.Header().Get("Upload-Offset") != "5" {
+		t.Errorf("Expected 409 with offset 5, got %d with %s", w.Code, w.Header().Get("Upload-Offset"))
+	}
+
+	// лишнее сверх заявленного размера отбрасывается
+	if w := patchChunk(h, id, "5", "56789extra"); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "10" {
+		t.Errorf("Expected 204 at offset 10, got %d at %s", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	data, err := os.ReadFile(h.sessionPath(id, sessionDataExt))
+	if err != nil || string(data) != "0123456789" {
+		t.Errorf("Expected truncated data, got %q, %v", data, err)
+	}
+
+	w = httptest.NewRecorder()
+	h.CancelUpload(w, uploadRequest(http.MethodDelete, id, "", nil, ""))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 on cancel, got %d", w.Code)
+	}
+	if w := uploadStatus(h, id, nil, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after cancel, got %d", w.Code)
+	}
+
+	// блокировки не копятся после завершения запросов
+	if len(h.locks.locks) != 0 {
+		t.Errorf("Expected no locks left, got %d", len(h.locks.locks))
+	}
+}
+
+func TestResumable_SessionOwner(t *testing.T) {
+	h := newUploadHandler(t)
+	key := &caller{KeyID: "k1"}
+	id := createUpload(t, h, "10", key, "sales")
+
+	tests := []struct {
+		name   string
+		id     string
+		caller *caller
+		tenant string
+		want   int
+	}{
+		{"same key and tenant", id, &caller{KeyID: "k1"}, "sales", http.StatusOK},
+		{"anonymous", id, nil, "sales", http.StatusNotFound},
+		{"other key", id, &caller{KeyID: "k2"}, "sales", http.StatusNotFound},
+		{"token instead of key", id, &caller{Subject: "alice"}, "sales", http.StatusNotFound},
+		{"key and token", id, &caller{KeyID: "k1", Subject: "alice"}, "sales", http.StatusNotFound},
+		{"other tenant", id, key, "hr", http.StatusNotFound},
+		{"default tenant", id, key, "", http.StatusNotFound},
+		{"not a uuid", "../" + id, key, "sales", http.StatusNotFound},
+		{"unknown upload", uuid.New().String(), key, "sales", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := uploadStatus(h, tt.id, tt.caller, tt.tenant); w.Code != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestResumable_Sweep(t *testing.T) {
+	h := newUploadHandler(t)
+	h.UploadTTL = time.Hour
+
+	fresh := createUpload(t, h, "10", nil, "")
+	stale := createUpload(t, h, "10", nil, "")
+
+	// загрузка, созданная два часа назад
+	s, err := h.loadSession(stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CreatedAt = time.Now().Add(-2 * time.Hour)
+	data, _ := json.Marshal(s)
+	if err := os.WriteFile(h.sessionPath(stale, sessionInfoExt), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// просроченную загрузку нельзя продолжить и до уборки
+	if w := uploadStatus(h, stale, nil, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a stale upload, got %d", w.Code)
+	}
+
+	// данные без метаданных стареют по времени изменения, чужие файлы не трогаются
+	orphan := filepath.Join(h.TmpDir, uuid.New().String()+sessionDataExt)
+	other := filepath.Join(h.TmpDir, "form-upload.tmp")
+	old := time.Now().Add(-2 * time.Hour)
+	for _, path := range []string{orphan, other} {
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h.sweepSessions(time.Now())
+
+	for _, path := range []string{h.sessionPath(stale, sessionInfoExt), h.sessionPath(stale, sessionDataExt), orphan} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got %v", filepath.Base(path), err)
+		}
+	}
+	for _, path := range []string{h.sessionPath(fresh, sessionInfoExt), h.sessionPath(fresh, sessionDataExt), other} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept, got %v", filepath.Base(path), err)
+		}
+	}
+
+	// через UploadTTL уходит и свежая
+	h.sweepSessions(time.Now().Add(2 * time.Hour))
+	if _, err := os.Stat(h.sessionPath(fresh, sessionInfoExt)); !os.IsNotExist(err) {
+		t.Errorf("Expected the fresh upload to expire, got %v", err)
+	}
+}
@@ -9,22 +9,57 @@ type FileProvider interface {
 	GetFile(w http.ResponseWriter, r *http.Request)
 	UploadFile(w http.ResponseWriter, r *http.Request)
 	GetInfo(w http.ResponseWriter, r *http.Request)
+	DeleteFile(w http.ResponseWriter, r *http.Request)
+	UpdateExpiry(w http.ResponseWriter, r *http.Request)
+	GetMember(w http.ResponseWriter, r *http.Request)
+	DownloadZip(w http.ResponseWriter, r *http.Request)
+	ListFiles(w http.ResponseWriter, r *http.Request)
+	GetQuota(w http.ResponseWriter, r *http.Request)
+
+	// возобновляемая загрузка
+	CreateUpload(w http.ResponseWriter, r *http.Request)
+	UploadStatus(w http.ResponseWriter, r *http.Request)
+	UploadChunk(w http.ResponseWriter, r *http.Request)
+	CancelUpload(w http.ResponseWriter, r *http.Request)
+	FinishUpload(w http.ResponseWriter, r *http.Request)
 }
 
 type FileRouter struct {
-	h FileProvider
+	h       FileProvider
+	auth    *Auth      // nil - ключи не проверяются, загружать может любой
+	tenants *Tenants   // nil - все запросы в пространстве по умолчанию
+	limits  *RateLimit // nil - частота запросов не ограничивается
 }
 
-func NewRouter(handler FileProvider) *FileRouter {
-	return &FileRouter{h: handler}
+func NewRouter(handler FileProvider, auth *Auth, tenants *Tenants, limits *RateLimit) *FileRouter {
+	return &FileRouter{h: handler, auth: auth, tenants: tenants, limits: limits}
 }
 
 func (r *FileRouter) Route(logger *slog.Logger) http.Handler {
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/get/{id}/", r.h.GetFile)
-	mux.HandleFunc("/get/{id}/info/", r.h.GetInfo)
-	mux.HandleFunc("/upload", r.h.UploadFile)
+	// перебор коротких ссылок упирается в лимиты скачиваний и информации
+	mux.HandleFunc("/get/{id}/", r.limits.downloads(r.h.GetFile))
+	mux.HandleFunc("/get/{id}/info/", r.limits.info(r.h.GetInfo))
+	mux.HandleFunc("/get/{id}/{member}/", r.limits.downloads(r.h.GetMember))
+	mux.HandleFunc("DELETE /get/{id}/{$}", r.limits.info(r.h.DeleteFile))
+	mux.HandleFunc("PATCH /get/{id}/{$}", r.limits.info(r.h.UpdateExpiry))
+	mux.HandleFunc("/upload", r.limits.uploads(r.auth.uploads(r.h.UploadFile)))
+
+	mux.HandleFunc("GET /zip/{$}", r.limits.downloads(r.h.DownloadZip))
+	mux.HandleFunc("GET /zip/{id}/{$}", r.limits.downloads(r.h.DownloadZip))
+
+	mux.HandleFunc("GET /files", r.limits.info(r.h.ListFiles))
+	mux.HandleFunc("GET /quota", r.limits.info(r.h.GetQuota))
+
+	// лимит загрузок считает только создание загрузки, куски идут в уже разрешенную
+	mux.HandleFunc("POST /upload/resumable/{$}", r.limits.uploads(r.auth.uploads(r.h.CreateUpload)))
+	mux.HandleFunc("HEAD /upload/resumable/{uid}/{$}", r.auth.uploads(r.h.UploadStatus))
+	mux.HandleFunc("PATCH /upload/resumable/{uid}/{$}", r.auth.uploads(r.h.UploadChunk))
+	mux.HandleFunc("DELETE /upload/resumable/{uid}/{$}", r.auth.uploads(r.h.CancelUpload))
+	mux.HandleFunc("POST /upload/resumable/{uid}/finish/{$}", r.auth.uploads(r.h.FinishUpload))
 
-	return enableCORS(loggingMiddleware(logger, mux))
+	// арендатор нужен уже при проверке ключа: ключ действует только в своем пространстве.
+	// лимит на адрес стоит перед проверкой ключа, чтобы перебор ключей не проходил бесплатно
+	return enableCORS(loggingMiddleware(logger, r.tenants.middleware(r.limits.auth(r.auth.middleware(r.limits.middleware(mux))))))
 }
@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// арендаторы: у каждого свое пространство коротких ссылок в реестре. гейтвей узнает арендатора
+// по имени хоста, а если хост не из списка - по префиксу пути /t/{name}/, который срезается
+// перед маршрутизацией. имя уходит в реестр в метаданных каждого вызова, а проверяет его
+// уже реестр: неизвестный арендатор - 404. без совпадений запрос идет в пространство по умолчанию
+
+type Tenants struct {
+	Hosts      map[string]string // хост без порта - имя арендатора
+	PathPrefix bool              // разрешить префикс /t/{name}/
+}
+
+const (
+	tenantPathPrefix = "/t/"
+	tenantMetadata   = "tenant" // так же называется ключ метаданных в реестре
+)
+
+// арендатор запроса и префикс, с которым клиент его указал. ссылки в ответах получают тот же
+// префикс, иначе они вели бы в пространство по умолчанию
+type tenant struct {
+	Name   string
+	Prefix string // /t/{name} или пустая строка, если арендатор определен по хосту
+}
+
+type tenantCtxKey struct{}
+
+func tenantFrom(ctx context.Context) tenant {
+	t, _ := ctx.Value(tenantCtxKey{}).(tenant)
+	return t
+}
+
+// путь ссылки внутри пространства арендатора запроса
+func tenantPath(r *http.Request, path string) string {
+	return tenantFrom(r.Context()).Prefix + path
+}
+
+func (t *Tenants) middleware(next http.Handler) http.Handler {
+	if t == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tn tenant
+		if name, ok := t.Hosts[requestHost(r)]; ok {
+			tn.Name = name
+		} else if rest, ok := strings.CutPrefix(r.URL.Path, tenantPathPrefix); ok && t.PathPrefix {
+			name, path, _ := strings.Cut(rest, "/")
+			if name == "" {
+				handleError(w, "Tenant not found.", http.StatusNotFound)
+				return
+			}
+
+			tn = tenant{Name: name, Prefix: tenantPathPrefix + name}
+			r = withPath(r, "/"+path)
+		}
+
+		if tn.Name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), tenantCtxKey{}, tn)
+		ctx = metadata.AppendToOutgoingContext(ctx, tenantMetadata, tn.Name)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// хост запроса без порта и в нижнем регистре
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(host)
+}
+
+// копия запроса с другим путем, как в http.StripPrefix
+func withPath(r *http.Request, path string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = path
+	r2.URL.RawPath = ""
+
+	return r2
+}
@@ -0,0 +1,201 @@
+package gateway
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+)
+
+// архив нескольких файлов собирается на лету: содержимое каждого файла идет из реестра прямо
+// в ответ, на диск гейтвея ничего не пишется
+
+const maxZipFiles = 100 // сколько коротких имен можно перечислить в одном запросе
+
+// файл в реестре и имя, под которым он ляжет в архив
+type zipEntry struct {
+	id   string
+	name string
+	size int64
+}
+
+// GET /zip/{id}/ - архив набора, GET /zip/?files=a,b,c - архив перечисленных файлов.
+// пароль, если он нужен, один на все файлы
+func (h *FileHandler) DownloadZip(w http.ResponseWriter, r *http.Request) {
+	var entries []zipEntry
+	var archive string
+
+	if r.PathValue("id") != "" {
+		archive = h.shortName(w, r)
+		if archive == "" {
+			return
+		}
+
+		entries = h.collectionEntries(w, r, archive)
+	} else {
+		archive = "files"
+		entries = h.listedEntries(w, r)
+	}
+
+	if entries == nil {
+		return
+	}
+
+	zipNames(entries)
+	h.streamZip(w, r, archive+".zip", entries)
+}
+
+// файлы набора, nil - если ответ с ошибкой уже отправлен
+func (h *FileHandler) collectionEntries(w http.ResponseWriter, r *http.Request, id string) []zipEntry {
+	resp, err := h.GRpcClient.GetCollection(r.Context(), &pb.GetCollectionReq{
+		ShortName: id,
+		Password:  filePassword(r),
+	})
+	if err != nil {
+		h.handleFetchError(w, r, err)
+		return nil
+	}
+
+	entries := make([]zipEntry, 0, len(resp.Files))
+	for _, f := range resp.Files {
+		entries = append(entries, zipEntry{id: f.ShortName, name: f.Filename, size: f.SizeBytes})
+	}
+
+	return entries
+}
+
+// файлы из параметра files (через запятую или несколькими параметрами), повторы отбрасываются.
+// nil - если ответ с ошибкой уже отправлен
+func (h *FileHandler) listedEntries(w http.ResponseWriter, r *http.Request) []zipEntry {
+	var ids []string
+	seen := make(map[string]bool)
+
+	for _, param := range r.URL.Query()["files"] {
+		for id := range strings.SplitSeq(param, ",") {
+			id = strings.TrimSpace(id)
+			if id == "" || seen[id] {
+				continue
+			}
+
+			if ok, _ := regexp.MatchString(idRegexp, id); !ok {
+				handleError(w, "File link should contain only letters and digits.", http.StatusBadRequest)
+				return nil
+			}
+
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		handleError(w, "List files to download in the files parameter.", http.StatusBadRequest)
+		return nil
+	}
+	if len(ids) > maxZipFiles {
+		handleError(w, fmt.Sprintf("No more than %d files can be downloaded at once.", maxZipFiles), http.StatusBadRequest)
+		return nil
+	}
+
+	// метаданные всех файлов берем заранее, чтобы ошибка пришла статусом, а не оборванным архивом
+	entries := make([]zipEntry, 0, len(ids))
+	for _, id := range ids {
+		resp, err := h.GRpcClient.GetFile(r.Context(), &pb.GetFileDataReq{
+			ShortName: id,
+			Password:  filePassword(r),
+		})
+		if err != nil {
+			h.handleFetchError(w, r, err)
+			return nil
+		}
+
+		entries = append(entries, zipEntry{id: id, name: resp.Filename, size: resp.SizeBytes})
+	}
+
+	return entries
+}
+
+// имена файлов внутри архива: без путей (чтобы архив не распаковался за пределы папки)
+// и без повторов, в том числе отличающихся только регистром. повторы получают номер: a (1).txt
+func zipNames(entries []zipEntry) {
+	taken := make(map[string]bool, len(entries))
+
+	for i := range entries {
+		name := strings.TrimSpace(strings.NewReplacer("/", "_", "\\", "_").Replace(entries[i].name))
+		if name == "" || name == "." || name == ".." {
+			name = entries[i].id
+		}
+
+		ext := path.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		for n := 1; taken[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s (%d)%s", base, n, ext)
+		}
+
+		taken[strings.ToLower(name)] = true
+		entries[i].name = name
+	}
+}
+
+// записать архив в ответ. поток каждого файла открывается до его заголовка в архиве: ошибку
+// первого файла еще можно отдать статусом, а после начала ответа остается только оборвать его
+func (h *FileHandler) streamZip(w http.ResponseWriter, r *http.Request, filename string, entries []zipEntry) {
+	zw := zip.NewWriter(w)
+	started := false
+	modified := time.Now()
+
+	start := func() {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+		started = true
+	}
+
+	for _, e := range entries {
+		content := &remoteFile{
+			client:   h.GRpcClient,
+			id:       e.id,
+			password: filePassword(r),
+			size:     e.size,
+		}
+
+		if err := content.open(r.Context(), 0); err != nil {
+			content.Close()
+			if !started {
+				h.handleFetchError(w, r, err)
+				return
+			}
+
+			// клиент не должен принять недописанный архив за целый
+			h.Logger.Error("failed to add a file to zip", "id", e.id, "details", err)
+			panic(http.ErrAbortHandler)
+		}
+
+		if !started {
+			start()
+		}
+
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: modified})
+		if err == nil {
+			_, err = io.Copy(fw, content)
+		}
+		content.Close()
+
+		if err != nil {
+			h.Logger.Error("failed to add a file to zip", "id", e.id, "details", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	if !started {
+		start() // пустой набор - пустой архив
+	}
+
+	if err := zw.Close(); err != nil {
+		h.Logger.Error("failed to finish zip", "details", err)
+	}
+}
@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestZipNames(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{"unique", []string{"a.txt", "b.txt"}, []string{"a.txt", "b.txt"}},
+		{"duplicates", []string{"a.txt", "a.txt", "a.txt"}, []string{"a.txt", "a (1).txt", "a (2).txt"}},
+		{"case only", []string{"Report.PDF", "report.pdf"}, []string{"Report.PDF", "report (1).pdf"}},
+		{"numbered name already taken", []string{"a (1).txt", "a.txt", "a.txt"}, []string{"a (1).txt", "a.txt", "a (2).txt"}},
+		{"no extension", []string{"README", "readme"}, []string{"README", "readme (1)"}},
+		{"path separators", []string{"../../etc/passwd", `..\boot.ini`, "dir/a.txt"}, []string{".._.._etc_passwd", ".._boot.ini", "dir_a.txt"}},
+		{"dots and blanks", []string{".", "..", "  "}, []string{"id0", "id1", "id2"}},
+		{"separator clash", []string{"a/b.txt", "a_b.txt"}, []string{"a_b.txt", "a_b (1).txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := make([]zipEntry, len(tt.in))
+			for i, name := range tt.in {
+				entries[i] = zipEntry{id: "id" + strconv.Itoa(i), name: name}
+			}
+
+			zipNames(entries)
+
+			for i, e := range entries {
+				if e.name != tt.want[i] {
+					t.Errorf("Entry %d: expected %q, got %q", i, tt.want[i], e.name)
+				}
+			}
+		})
+	}
+}
+
+// реестр с несколькими файлами. поток файла из fail сразу отвечает ошибкой, как реестр
+// для исчерпанной или истекшей ссылки
+type zipRegistry struct {
+	pb.RegServiceClient
+	files map[string]string
+	fail  map[string]error
+}
+
+func (z *zipRegistry) GetFile(ctx context.Context, in *pb.GetFileDataReq, opts ...grpc.CallOption) (*pb.GetFileDataResp, error) {
+	data, ok := z.files[in.ShortName]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "File not found.")
+	}
+
+	return &pb.GetFileDataResp{Filename: in.ShortName + ".txt", SizeBytes: int64(len(data))}, nil
+}
+
+func (z *zipRegistry) DownloadFile(ctx context.Context, in *pb.DownloadFileReq, opts ...grpc.CallOption) (grpc.ServerStreamingClient[pb.DownloadFileResp], error) {
+	if err := z.fail[in.ShortName]; err != nil {
+		return &failingStream{err: err}, nil
+	}
+
+	return &chunkStream{chunks: []string{z.files[in.ShortName][in.Offset:]}}, nil
+}
+
+// ошибка реестра приходит с первым ответом потока
+type failingStream struct {
+	grpc.ClientStream
+	err error
+}
+
+func (s *failingStream) Recv() (*pb.DownloadFileResp, error) {
+	return nil, s.err
+}
+
+func zipRequest(h *FileHandler, files string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.DownloadZip(w, httptest.NewRequest("GET", "/zip/?files="+files, nil))
+	return w
+}
+
+func TestDownloadZip(t *testing.T) {
+	exhausted := status.Error(codes.FailedPrecondition, "Download limit reached.")
+	reg := &zipRegistry{
+		files: map[string]string{"a": "aaa", "b": "bb", "gone": "x"},
+		fail:  map[string]error{"gone": exhausted},
+	}
+	h := &FileHandler{GRpcClient: reg, Logger: slog.New(slog.DiscardHandler)}
+
+	w := zipRequest(h, "a,b,a")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("Expected a zip, got %d: %s", w.Code, w.Body)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Broken archive: %v", err)
+	}
+	got := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		got[f.Name] = string(data)
+	}
+	if len(got) != 2 || got["a.txt"] != "aaa" || got["b.txt"] != "bb" {
+		t.Errorf("Expected a.txt and b.txt once, got %v", got)
+	}
+
+	// файл, который подвел до первого заголовка, дает статус, а не оборванный архив
+	w = zipRequest(h, "gone,a")
+	if w.Code != http.StatusGone || w.Header().Get("Content-Type") == "application/zip" {
+		t.Errorf("Expected 410 without a zip, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// неизвестный файл отсекается еще при сборе метаданных
+	if w := zipRequest(h, "a,missing"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing file, got %d", w.Code)
+	}
+
+	// после начала архива остается только оборвать ответ
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("Expected ErrAbortHandler for a later failure, got %v", p)
+		}
+	}()
+	zipRequest(h, "a,gone")
+}
@@ -0,0 +1,174 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// проверка JWT, которые выдает внешний провайдер (OIDC). поддерживаются только асимметричные
+// подписи RS256 и ES256: гейтвей знает лишь открытые ключи провайдера из JWKS
+
+var (
+	ErrMalformed  = errors.New("malformed token")
+	ErrAlgorithm  = errors.New("unsupported signing algorithm")
+	ErrUnknownKey = errors.New("unknown signing key")
+	ErrSignature  = errors.New("invalid token signature")
+	ErrExpired    = errors.New("token expired")
+	ErrClaims     = errors.New("invalid token claims") // чужой издатель или аудитория, токен еще не действует
+)
+
+const leeway = time.Minute // допустимое расхождение часов с провайдером
+
+// поля токена, которые нужны гейтвею
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  Audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+}
+
+// aud по стандарту может быть и строкой, и массивом строк
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = Audience{one}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+
+	*a = many
+	return nil
+}
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+
+	return json.Marshal([]string(a))
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// проверяет подпись токена ключами из Keys и то, что токен выдан Issuer для Audience
+type Verifier struct {
+	Keys     *KeySet
+	Issuer   string
+	Audience string
+}
+
+// проверить токен и вернуть его поля. токен без exp или sub не принимается
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodePart(parts[0], &h); err != nil {
+		return nil, err
+	}
+	if h.Alg != "RS256" && h.Alg != "ES256" {
+		return nil, ErrAlgorithm
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	key, err := v.Keys.Key(ctx, h.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(h.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var c Claims
+	if err := decodePart(parts[1], &c); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case c.ExpiresAt == 0 || now.After(time.Unix(c.ExpiresAt, 0).Add(leeway)):
+		return nil, ErrExpired
+	case c.NotBefore != 0 && now.Add(leeway).Before(time.Unix(c.NotBefore, 0)):
+		return nil, ErrClaims
+	case c.Issuer != v.Issuer || !slices.Contains(c.Audience, v.Audience) || c.Subject == "":
+		return nil, ErrClaims
+	}
+
+	return &c, nil
+}
+
+func decodePart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformed
+	}
+
+	return nil
+}
+
+// алгоритм из заголовка должен подходить к типу ключа, иначе RSA-ключ можно было бы
+// выдать за ключ другого алгоритма
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrAlgorithm
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) != nil {
+			return ErrSignature
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve.Params().Name != "P-256" {
+			return ErrAlgorithm
+		}
+		// подпись - это r и s по 32 байта подряд, а не ASN.1
+		if len(sig) != 64 {
+			return ErrSignature
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrSignature
+		}
+	default:
+		return ErrAlgorithm
+	}
+
+	return nil
+}
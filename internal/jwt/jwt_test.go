@@ -0,0 +1,234 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://issuer.test"
+	testAudience = "gofilesharing"
+)
+
+// локальный издатель: пара ключей и JWKS в файле, без настоящего провайдера
+func newSigner(t *testing.T, kind, kid string) *Signer {
+	t.Helper()
+
+	var s Signer
+	var err error
+	switch kind {
+	case "rsa":
+		s.Key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ec":
+		s.Key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.KeyID = kid
+	return &s
+}
+
+func writeJWKS(t *testing.T, s *Signer) string {
+	t.Helper()
+
+	data, err := s.JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newVerifier(t *testing.T, source string) *Verifier {
+	t.Helper()
+
+	keys, err := NewKeySet(context.Background(), source)
+	if err != nil {
+		t.Fatalf("NewKeySet failed: %v", err)
+	}
+	return &Verifier{Keys: keys, Issuer: testIssuer, Audience: testAudience}
+}
+
+func validClaims() Claims {
+	return Claims{
+		Issuer:    testIssuer,
+		Subject:   "alice",
+		Audience:  Audience{"other", testAudience},
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		IssuedAt:  time.Now().Unix(),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	for _, kind := range []string{"rsa", "ec"} {
+		t.Run(kind, func(t *testing.T) {
+			s := newSigner(t, kind, "k1")
+			v := newVerifier(t, writeJWKS(t, s))
+			ctx := context.Background()
+
+			token, err := s.Sign(validClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := v.Verify(ctx, token)
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+			if c.Subject != "alice" {
+				t.Errorf("subject = %q, want alice", c.Subject)
+			}
+
+			tests := []struct {
+				name   string
+				modify func(c *Claims)
+				want   error
+			}{
+				{"wrong issuer", func(c *Claims) { c.Issuer = "https://evil.test" }, ErrClaims},
+				{"wrong audience", func(c *Claims) { c.Audience = Audience{"other"} }, ErrClaims},
+				{"no subject", func(c *Claims) { c.Subject = "" }, ErrClaims},
+				{"expired", func(c *Claims) { c.ExpiresAt = time.Now().Add(-2 * leeway).Unix() }, ErrExpired},
+				{"no expiry", func(c *Claims) { c.ExpiresAt = 0 }, ErrExpired},
+				{"not yet valid", func(c *Claims) { c.NotBefore = time.Now().Add(time.Hour).Unix() }, ErrClaims},
+			}
+			for _, tt := range tests {
+				c := validClaims()
+				tt.modify(&c)
+
+				token, err := s.Sign(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := v.Verify(ctx, token); !errors.Is(err, tt.want) {
+					t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+				}
+			}
+
+			// подмена полей ломает подпись
+			parts := strings.Split(token, ".")
+			forged := validClaims()
+			forged.Subject = "mallory"
+			payload, _ := json.Marshal(forged)
+			parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+			if _, err := v.Verify(ctx, strings.Join(parts, ".")); !errors.Is(err, ErrSignature) {
+				t.Errorf("forged payload: err = %v, want ErrSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerify_RejectsForeignKeysAndAlgorithms(t *testing.T) {
+	s := newSigner(t, "rsa", "k1")
+	v := newVerifier(t, writeJWKS(t, s))
+	ctx := context.Background()
+
+	// тот же kid, но чужой ключ
+	other := newSigner(t, "rsa", "k1")
+	token, _ := other.Sign(validClaims())
+	if _, err := v.Verify(ctx, token); !errors.Is(err, ErrSignature) {
+		t.Errorf("foreign key: err = %v, want ErrSignature", err)
+	}
+
+	// незнакомый kid
+	unknown := newSigner(t, "ec", "k2")
+	token, _ = unknown.Sign(validClaims())
+	if _, err := v.Verify(ctx, token); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown kid: err = %v, want ErrUnknownKey", err)
+	}
+
+	// alg none и HS256 не принимаются, даже если подпись пустая или посчитана открытым ключом
+	good, _ := s.Sign(validClaims())
+	parts := strings.Split(good, ".")
+	for _, alg := range []string{"none", "HS256"} {
+		h := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"` + alg + `","kid":"k1"}`))
+		if _, err := v.Verify(ctx, h+"."+parts[1]+"."); !errors.Is(err, ErrAlgorithm) {
+			t.Errorf("alg %s: err = %v, want ErrAlgorithm", alg, err)
+		}
+	}
+
+	// ES256 в заголовке при RSA-ключе с тем же kid
+	h := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","kid":"k1"}`))
+	if _, err := v.Verify(ctx, h+"."+parts[1]+"."+parts[2]); !errors.Is(err, ErrAlgorithm) {
+		t.Errorf("alg confusion: err = %v, want ErrAlgorithm", err)
+	}
+
+	if _, err := v.Verify(ctx, "not-a-token"); !errors.Is(err, ErrMalformed) {
+		t.Errorf("garbage: err = %v, want ErrMalformed", err)
+	}
+}
+
+func TestKeySet_URLRotation(t *testing.T) {
+	first := newSigner(t, "rsa", "old")
+	second := newSigner(t, "ec", "new")
+
+	var current atomic.Pointer[Signer]
+	current.Store(first)
+	var fetches atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		data, _ := current.Load().JWKS()
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	v := newVerifier(t, srv.URL)
+	ctx := context.Background()
+
+	token, _ := first.Sign(validClaims())
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Fatalf("Verify with the initial key failed: %v", err)
+	}
+
+	// провайдер сменил ключ, но перечитывать набор чаще minRefresh нельзя
+	current.Store(second)
+	token, _ = second.Sign(validClaims())
+	if _, err := v.Verify(ctx, token); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("err = %v, want ErrUnknownKey before refresh", err)
+	}
+
+	v.Keys.mu.Lock()
+	v.Keys.fetched = time.Now().Add(-minRefresh)
+	v.Keys.mu.Unlock()
+
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Fatalf("Verify after rotation failed: %v", err)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", n)
+	}
+}
+
+func TestParseJWKS_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"not json":     `{`,
+		"no keys":      `{"keys":[]}`,
+		"only enc key": `{"keys":[{"kty":"RSA","use":"enc","n":"AQAB","e":"AQAB"}]}`,
+		"short rsa":    `{"keys":[{"kty":"RSA","kid":"a","n":"AQAB","e":"AQAB"}]}`,
+		"off curve":    `{"keys":[{"kty":"EC","crv":"P-256","kid":"a","x":"` + strings.Repeat("A", 43) + `","y":"` + strings.Repeat("A", 43) + `"}]}`,
+	} {
+		if _, err := ParseJWKS([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
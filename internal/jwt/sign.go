@@ -0,0 +1,101 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// выпуск токенов для локального тестового издателя и тестов: в работе токены выдает провайдер,
+// а гейтвей их только проверяет
+
+type Signer struct {
+	Key   crypto.Signer // *rsa.PrivateKey (RS256) или *ecdsa.PrivateKey на P-256 (ES256)
+	KeyID string
+}
+
+func (s *Signer) alg() (string, error) {
+	switch k := s.Key.(type) {
+	case *rsa.PrivateKey:
+		return "RS256", nil
+	case *ecdsa.PrivateKey:
+		if k.Curve.Params().Name == "P-256" {
+			return "ES256", nil
+		}
+	}
+
+	return "", ErrAlgorithm
+}
+
+// подписать токен с полями c
+func (s *Signer) Sign(c Claims) (string, error) {
+	alg, err := s.alg()
+	if err != nil {
+		return "", err
+	}
+
+	h, err := json.Marshal(header{Alg: alg, Kid: s.KeyID, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := s.Key.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, ss *big.Int
+		r, ss, err = ecdsa.Sign(rand.Reader, k, digest[:])
+		if err == nil {
+			sig = make([]byte, 64)
+			r.FillBytes(sig[:32])
+			ss.FillBytes(sig[32:])
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// открытый ключ издателя в виде JWKS - то, что читает гейтвей
+func (s *Signer) JWKS() ([]byte, error) {
+	alg, err := s.alg()
+	if err != nil {
+		return nil, err
+	}
+
+	k := jwk{Kid: s.KeyID, Use: "sig", Alg: alg}
+	switch pub := s.Key.Public().(type) {
+	case *rsa.PublicKey:
+		k.Kty = "RSA"
+		k.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		k.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		point, err := pub.Bytes() // 0x04, x, y
+		if err != nil {
+			return nil, err
+		}
+		k.Kty, k.Crv = "EC", "P-256"
+		k.X = base64.RawURLEncoding.EncodeToString(point[1:33])
+		k.Y = base64.RawURLEncoding.EncodeToString(point[33:])
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return json.MarshalIndent(jwks{Keys: []jwk{k}}, "", "  ")
+}
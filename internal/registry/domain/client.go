@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+type clientCtxKey struct{}
+
+// контекст вызова от имени клиента гейтвея: владельца по ключу или токену, а без них - адреса.
+// по клиенту считаются попытки ввода пароля, остальным он не нужен
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientCtxKey{}, client)
+}
+
+// клиент из контекста, пустая строка - гейтвей его не передал, и все такие вызовы - один клиент
+func ClientFrom(ctx context.Context) string {
+	client, _ := ctx.Value(clientCtxKey{}).(string)
+	return client
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // отдельный файл с ошибками, вынесено в отдельный файл на сервисном слое. в хендлер поступают
 // ошибки именно отсюда. если надо будет добавить обрабатывать больше ошибок - просто
@@ -11,4 +14,41 @@ var (
 	ErrExpired   = errors.New("link expired")   // сслыка недействительна
 	ErrInRepo    = errors.New("repo error")
 	ErrInService = errors.New("error in service")
+
+	ErrSizeMismatch = errors.New("file size mismatch")    // пришло не столько байт, сколько было заявлено
+	ErrInvalidRange = errors.New("invalid range of file") // запрошенный кусок за пределами файла
+	ErrBlobNotFound = errors.New("blob not found")        // в хранилище нет содержимого файла
+	ErrInvalidTTL   = errors.New("invalid ttl")           // срок жизни больше разрешенного сервером
+
+	ErrDownloadsExhausted = errors.New("download limit reached") // файл уже скачали разрешенное число раз
+	ErrInvalidLimit       = errors.New("invalid download limit") // отрицательный лимит скачиваний
+
+	ErrWrongPassword   = errors.New("wrong password")     // пароль не указан или не подходит
+	ErrTooManyAttempts = errors.New("too many attempts")  // слишком много неверных паролей подряд
+	ErrInvalidPassword = errors.New("invalid password")   // пароль не подходит для хеширования (длиннее 72 байт)
+	ErrWrongToken      = errors.New("wrong delete token") // токен владельца не указан или не подходит
+
+	ErrConflict    = errors.New("short name is already taken") // запись с таким айди уже есть
+	ErrInvalidName = errors.New("invalid short name")          // имя не подходит по формату или зарезервировано
+
+	ErrDigestMismatch = errors.New("digest mismatch")   // хеш содержимого не совпал с заявленным клиентом
+	ErrCorrupted      = errors.New("file is corrupted") // проверка нашла, что содержимое пропало или испорчено
+
+	ErrInvalidCollection = errors.New("invalid collection") // пустой набор, повтор файла или файл уже в другом наборе
+
+	ErrUnauthenticated = errors.New("invalid api key")             // ключа нет или он отозван
+	ErrInvalidKey      = errors.New("invalid api key policy")      // отрицательные ограничения или кривой тип содержимого
+	ErrTooLarge        = errors.New("file is too large")           // файл больше, чем разрешают ключ или арендатор
+	ErrTypeNotAllowed  = errors.New("content type is not allowed") // ключ или арендатор не разрешают такой тип содержимого
+
+	// свое короткое имя выбирает только загрузка по ключу или токену. errors.Is с ErrUnauthenticated верно
+	ErrNameNeedsAuth = fmt.Errorf("%w: short name requires an api key or a token", ErrUnauthenticated)
+
+	ErrInvalidFilter = errors.New("invalid file filter") // кривой курсор, размер страницы или шаблон типа
+
+	ErrQuotaBytes   = errors.New("storage quota exceeded")    // файл не помещается в квоту владельца
+	ErrQuotaFiles   = errors.New("file count quota exceeded") // у владельца уже столько файлов, сколько разрешено
+	ErrInvalidQuota = errors.New("invalid quota")             // отрицательные ограничения или квота без владельца
+
+	ErrUnknownTenant = errors.New("unknown tenant") // арендатора нет в настройках реестра
 )
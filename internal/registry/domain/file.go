@@ -10,4 +10,149 @@ type File struct {
 	Size         int64
 	ContentType  string
 	CreatedAt    time.Time
+	ExpiresAt    time.Time // нулевое время - ссылка бессрочная
+	MaxDownloads int64     // сколько раз файл можно скачать, 0 - без ограничений
+	Downloads    int64     // сколько раз файл уже скачали
+	PasswordHash string    // bcrypt-хеш пароля, пустая строка - файл без пароля
+	TokenHash    string    // sha256 токена владельца, пустая строка - удалить файл нельзя
+	ContentHash  string    // sha256 содержимого в hex, по нему одинаковые файлы делят один объект в хранилище
+	Integrity    string    // результат последней проверки содержимого, IntegrityOK - все в порядке
+	CollectionID string    // набор, в который входит файл, пустая строка - отдельный файл
+	Owner        string    // кто загрузил файл: субъект токена или key:<айди ключа>, пустая строка - аноним
+	Tenant       string    // пространство ссылок, в котором лежит файл
+	KeyID        string    // ключ, по которому загружен файл: его ограничения действуют и при смене срока
+}
+
+// набор файлов под одной ссылкой. пароль и срок у набора общие, файлы истекают вместе с ним
+type Collection struct {
+	ID           string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time // нулевое время - набор бессрочный
+	PasswordHash string
+	TokenHash    string
+	Owner        string
+	Tenant       string
+	KeyID        string // ключ, по которому создан набор
+	Files        []*File
+}
+
+// файл, который загружающий добавляет в набор, и его токен владельца как доказательство прав
+type MemberRef struct {
+	ID    string
+	Token string
+}
+
+// данные о создаваемом наборе: параметры ссылки как у UploadMeta и уже загруженные файлы
+type CollectionMeta struct {
+	TTL       time.Duration
+	Password  string
+	ShortName string
+	Members   []MemberRef
+	KeyID     string // ключ, по которому создается набор, пустая строка - анонимно
+	Owner     string // пользователь, от имени которого создается набор
+}
+
+// состояния содержимого по итогам проверки хранилища
+const (
+	IntegrityOK        = ""          // не проверялось или совпало с записанным хешем
+	IntegrityMissing   = "missing"   // объекта нет в хранилище
+	IntegrityCorrupted = "corrupted" // хеш содержимого не совпал с записанным
+)
+
+// объект в хранилище и хеш, с которым его содержимое записывалось
+type Blob struct {
+	Key  string
+	Hash string // пустая строка - файл загружен до появления хешей, проверяется только наличие
+}
+
+// итоги прохода проверки хранилища
+type ScrubReport struct {
+	StartedAt  time.Time
+	FinishedAt time.Time // нулевое время - проход еще идет
+	Checked    int       // сколько объектов проверено
+	Missing    int
+	Corrupted  int
+}
+
+// объект, найденный в самом хранилище, а не в бд
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// итоги прохода сборки мусора. в режиме DryRun найденное только перечисляется, но не удаляется
+type GCReport struct {
+	StartedAt     time.Time
+	FinishedAt    time.Time
+	DryRun        bool
+	TmpFiles      []string // забытые временные файлы загрузок
+	OrphanBlobs   []string // объекты хранилища, на которые не ссылается ни одна запись
+	DanglingFiles []string // записи, содержимого которых нет в хранилище, в виде domain.QualifiedID
+	FreedBytes    int64    // сколько места освобождено (или освободилось бы) на временных файлах и объектах
+}
+
+// лимит скачиваний исчерпан, ссылка больше не действует
+func (f *File) Exhausted() bool {
+	return f.MaxDownloads > 0 && f.Downloads >= f.MaxDownloads
+}
+
+// данные о загружаемом файле, которые присылает гейтвей вместе с потоком байт
+type UploadMeta struct {
+	Name         string
+	Size         int64 // 0, если размер заранее неизвестен
+	ContentType  string
+	TTL          time.Duration // 0 - срок по умолчанию, NoExpiry - бессрочно
+	MaxDownloads int64         // 0 - без ограничений
+	Password     string        // пустая строка - файл без пароля
+	ShortName    string        // желаемое короткое имя, пустая строка - сгенерировать случайное
+	SHA256       []byte        // ожидаемый хеш содержимого, пусто - не проверять
+	MD5          []byte        // ожидаемый md5 содержимого, пусто - не проверять
+	KeyID        string        // ключ, по которому идет загрузка, пустая строка - анонимная загрузка
+	Owner        string        // кто загружает: субъект токена или key:<айди ключа>, пустая строка - аноним
+}
+
+// условия выборки файлов. пустые поля выборку не ограничивают
+type FileFilter struct {
+	Owner         string
+	NamePrefix    string    // начало исходного имени файла
+	ContentType   string    // точный тип или шаблон вида image/*
+	ExpiresAfter  time.Time // ссылки, истекающие не раньше, бессрочные тоже подходят
+	ExpiresBefore time.Time // ссылки, истекающие не позже, бессрочные не подходят
+	Cursor        string    // где закончилась предыдущая страница, пустая строка - с начала
+	Limit         int       // размер страницы
+}
+
+// срок жизни для ссылки, которая не должна истекать
+const NoExpiry time.Duration = -1
+
+// результат загрузки: короткое имя и секретный токен владельца для удаления файла
+type UploadResult struct {
+	ID          string
+	DeleteToken string
+}
+
+// ключ доступа к загрузке. сам ключ показывается один раз при создании, в бд лежит его sha256.
+// нулевые ограничения - без ограничений сверх настроек сервера
+type APIKey struct {
+	ID           string
+	Name         string
+	KeyHash      string
+	CreatedAt    time.Time
+	MaxSize      int64         // наибольший размер файла в байтах
+	MaxTTL       time.Duration // наибольший срок ссылки, бессрочные ссылки при этом запрещены
+	ContentTypes []string      // разрешенные типы содержимого, допускаются шаблоны вида image/*
+	Tenant       string        // арендатор, в пространство которого можно загружать по ключу
+}
+
+// квота владельца на его записи, нулевые поля - без ограничений
+type Quota struct {
+	MaxBytes int64
+	MaxFiles int64
+}
+
+// сколько занимают записи владельца. истекшие записи учитываются, пока их не удалит очистка
+type Usage struct {
+	Bytes int64
+	Files int64
 }
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// арендатор - отдельное пространство коротких ссылок со своими настройками. короткие имена
+// уникальны только внутри арендатора, и один арендатор не видит файлов другого.
+// пустое имя - пространство по умолчанию, в нем лежат все файлы, загруженные до арендаторов.
+// нулевые настройки - как у сервера в целом
+type Tenant struct {
+	Name         string
+	MaxSize      int64         // наибольший размер файла в байтах
+	DefaultTTL   time.Duration // срок ссылки, если загружающий его не указал
+	MaxTTL       time.Duration // наибольший срок ссылки, бессрочные ссылки при этом запрещены
+	ContentTypes []string      // разрешенные типы содержимого, допускаются шаблоны вида image/*
+	Quota        Quota         // на все файлы арендатора вместе
+}
+
+type tenantCtxKey struct{}
+
+// контекст запроса в пространстве арендатора name. репозиторий ищет и сохраняет записи
+// только в пространстве из контекста
+func WithTenant(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, name)
+}
+
+// арендатор из контекста, пустая строка - пространство по умолчанию
+func TenantFrom(ctx context.Context) string {
+	name, _ := ctx.Value(tenantCtxKey{}).(string)
+	return name
+}
+
+// айди файла или набора вместе с арендатором для логов и отчетов, где встречаются записи
+// разных арендаторов: tenant/id, а в пространстве по умолчанию - просто id
+func QualifiedID(tenant, id string) string {
+	if tenant == "" {
+		return id
+	}
+
+	return tenant + "/" + id
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// то, что нужно служебным вызовам от сервиса
+type AdminServiceInterface interface {
+	Scrub(ctx context.Context) (*domain.ScrubReport, error)
+	ScrubReport(ctx context.Context) (*domain.ScrubReport, []*domain.File, error)
+	CollectGarbage(ctx context.Context, dryRun bool, minAge time.Duration) (*domain.GCReport, error)
+	CreateKey(ctx context.Context, k domain.APIKey) (*domain.APIKey, string, error)
+	ListKeys(ctx context.Context) ([]*domain.APIKey, error)
+	RevokeKey(ctx context.Context, id string) error
+	SetQuota(ctx context.Context, owner string, q *domain.Quota) error
+	Usage(ctx context.Context, owner string) (domain.Usage, domain.Quota, error)
+}
+
+// хендлер служебного сервиса, регистрируется на том же grpc-сервере
+type AdminHandler struct {
+	service AdminServiceInterface
+	pb.UnimplementedAdminServiceServer
+}
+
+func NewAdminHandler(s AdminServiceInterface) *AdminHandler {
+	return &AdminHandler{
+		service: s,
+	}
+}
+
+// итоги проверки хранилища и список испорченных файлов
+func (h *AdminHandler) ScrubReport(ctx context.Context, req *pb.ScrubReportReq) (*pb.ScrubReportResp, error) {
+	if req.GetRun() {
+		if _, err := h.service.Scrub(ctx); err != nil {
+			return nil, status.Error(codes.Internal, "Scrub failed.")
+		}
+	}
+
+	report, damaged, err := h.service.ScrubReport(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Internal Error.")
+	}
+
+	resp := &pb.ScrubReportResp{}
+	if report != nil {
+		resp.StartedAt = unixOrZero(report.StartedAt)
+		resp.FinishedAt = unixOrZero(report.FinishedAt)
+		resp.Checked = int64(report.Checked)
+		resp.Missing = int64(report.Missing)
+		resp.Corrupted = int64(report.Corrupted)
+	}
+
+	for _, f := range damaged {
+		resp.Damaged = append(resp.Damaged, &pb.DamagedFile{
+			ShortName:  f.ID,
+			StorageKey: f.StoragePath,
+			State:      f.Integrity,
+			Tenant:     f.Tenant,
+		})
+	}
+
+	return resp, nil
+}
+
+// сборка мусора по запросу, в режиме dry_run - только отчет о найденном
+func (h *AdminHandler) CollectGarbage(ctx context.Context, req *pb.CollectGarbageReq) (*pb.CollectGarbageResp, error) {
+	if req.GetMinAgeSeconds() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid min age.")
+	}
+
+	report, err := h.service.CollectGarbage(ctx, req.GetDryRun(), time.Duration(req.GetMinAgeSeconds())*time.Second)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Garbage collection failed.")
+	}
+
+	return &pb.CollectGarbageResp{
+		StartedAt:     unixOrZero(report.StartedAt),
+		FinishedAt:    unixOrZero(report.FinishedAt),
+		DryRun:        report.DryRun,
+		TmpFiles:      report.TmpFiles,
+		OrphanBlobs:   report.OrphanBlobs,
+		DanglingFiles: report.DanglingFiles,
+		FreedBytes:    report.FreedBytes,
+	}, nil
+}
+
+// выдать ключ доступа к загрузке. сам ключ есть только в этом ответе
+func (h *AdminHandler) CreateApiKey(ctx context.Context, req *pb.CreateApiKeyReq) (*pb.CreateApiKeyResp, error) {
+	k, secret, err := h.service.CreateKey(ctx, domain.APIKey{
+		Name:         req.GetName(),
+		MaxSize:      req.GetMaxSizeBytes(),
+		MaxTTL:       time.Duration(req.GetMaxTtlSeconds()) * time.Second,
+		ContentTypes: req.GetContentTypes(),
+		Tenant:       req.GetTenant(),
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidKey) {
+			return nil, status.Error(codes.InvalidArgument, "Limits should not be negative, content types should look like image/png or image/*.")
+		}
+		if errors.Is(err, domain.ErrUnknownTenant) {
+			return nil, status.Error(codes.InvalidArgument, "Unknown tenant.")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal Error.")
+	}
+
+	return &pb.CreateApiKeyResp{Key: apiKey(k), Secret: secret}, nil
+}
+
+func (h *AdminHandler) ListApiKeys(ctx context.Context, req *pb.ListApiKeysReq) (*pb.ListApiKeysResp, error) {
+	keys, err := h.service.ListKeys(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Internal Error.")
+	}
+
+	resp := &pb.ListApiKeysResp{}
+	for _, k := range keys {
+		resp.Keys = append(resp.Keys, apiKey(k))
+	}
+
+	return resp, nil
+}
+
+// отозвать ключ: загрузки по нему сразу перестают проходить
+func (h *AdminHandler) RevokeApiKey(ctx context.Context, req *pb.RevokeApiKeyReq) (*pb.RevokeApiKeyResp, error) {
+	if err := h.service.RevokeKey(ctx, req.GetId()); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "API key not found.")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal Error.")
+	}
+
+	return &pb.RevokeApiKeyResp{}, nil
+}
+
+// задать владельцу отдельную квоту или вернуть ему квоту по умолчанию
+func (h *AdminHandler) SetQuota(ctx context.Context, req *pb.SetQuotaReq) (*pb.Usage, error) {
+	var q *domain.Quota
+	if !req.GetReset_() {
+		q = &domain.Quota{MaxBytes: req.GetMaxBytes(), MaxFiles: req.GetMaxFiles()}
+	}
+
+	if err := h.service.SetQuota(ctx, req.GetOwner(), q); err != nil {
+		if errors.Is(err, domain.ErrInvalidQuota) {
+			return nil, status.Error(codes.InvalidArgument, "Owner should be set, limits should not be negative.")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal Error.")
+	}
+
+	u, quota, err := h.service.Usage(ctx, req.GetOwner())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Internal Error.")
+	}
+
+	return usage(u, quota), nil
+}
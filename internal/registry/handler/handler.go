@@ -3,20 +3,35 @@ package handler
 import (
 	"context"
 	"errors"
+	"io"
+	"time"
 
 	pb "github.com/kfcempoyee/gofilesharing/gen/registry/proto/v1"
 	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 // интерфейс сервиса
 type FileServiceInterface interface {
-	Upload(ctx context.Context, uuid string, name string, size int64, contentType string) (string, error)
-	Get(ctx context.Context, id string) (*domain.File, error)
+	Upload(ctx context.Context, uuid string, meta domain.UploadMeta) (*domain.UploadResult, error)
+	Store(ctx context.Context, meta domain.UploadMeta, r io.Reader) (*domain.UploadResult, error)
+	Get(ctx context.Context, id, password string) (*domain.File, error)
+	Open(ctx context.Context, id, password string, offset, length int64) (io.ReadCloser, error)
+	Delete(ctx context.Context, id, token string) error
+	UpdateExpiry(ctx context.Context, id, token string, ttl time.Duration) (time.Time, error)
+	CreateCollection(ctx context.Context, meta domain.CollectionMeta) (*domain.UploadResult, error)
+	GetCollection(ctx context.Context, id, password string) (*domain.Collection, error)
+	Authenticate(ctx context.Context, secret string) (*domain.APIKey, error)
+	ListFiles(ctx context.Context, filter domain.FileFilter) ([]*domain.File, string, error)
+	Usage(ctx context.Context, owner string) (domain.Usage, domain.Quota, error)
+	TenantUsage(ctx context.Context) (domain.Usage, domain.Quota, error)
 	StartCleanup(ctx context.Context)
 }
 
+const chunkSize = 64 << 10 // размер одного сообщения при отдаче файла
+
 // сам хендлер должен принять сервис и структуру для совместимости
 type GrpcHandler struct {
 	service FileServiceInterface
@@ -30,9 +45,202 @@ func NewGRPCHandler(s FileServiceInterface) *GrpcHandler {
 	}
 }
 
-// взять путь к файлу в памяти по его короткому айди
+// ошибки поиска файла одинаково переводятся в статусы и для метаданных, и для содержимого
+func fileError(err error) error {
+	if errors.Is(err, domain.ErrNotFound) {
+		return status.Error(codes.NotFound, "File not found.")
+	}
+
+	if errors.Is(err, domain.ErrExpired) {
+		return status.Error(codes.DeadlineExceeded, "Link expired")
+	}
+
+	if errors.Is(err, domain.ErrInvalidRange) {
+		return status.Error(codes.OutOfRange, "Invalid range.")
+	}
+
+	if errors.Is(err, domain.ErrDownloadsExhausted) {
+		return status.Error(codes.FailedPrecondition, "Download limit reached.")
+	}
+
+	if errors.Is(err, domain.ErrWrongPassword) {
+		return status.Error(codes.PermissionDenied, "Wrong password.")
+	}
+
+	if errors.Is(err, domain.ErrTooManyAttempts) {
+		return status.Error(codes.ResourceExhausted, "Too many wrong passwords, try again later.")
+	}
+
+	if errors.Is(err, domain.ErrCorrupted) {
+		return status.Error(codes.DataLoss, "File content is missing or corrupted.")
+	}
+
+	return status.Error(codes.Internal, "Internal Error.")
+}
+
+// ошибки сохранения файла, общие для RegisterFile и UploadFile
+func uploadError(err error) error {
+	if errors.Is(err, domain.ErrSizeMismatch) {
+		return status.Error(codes.InvalidArgument, "File size mismatch.")
+	}
+
+	if errors.Is(err, domain.ErrInvalidTTL) {
+		return status.Error(codes.InvalidArgument, "Requested expiry is not allowed.")
+	}
+
+	if errors.Is(err, domain.ErrInvalidLimit) {
+		return status.Error(codes.InvalidArgument, "Download limit should not be negative.")
+	}
+
+	if errors.Is(err, domain.ErrInvalidPassword) {
+		return status.Error(codes.InvalidArgument, "Password should not be longer than 72 bytes.")
+	}
+
+	if errors.Is(err, domain.ErrInvalidName) {
+		return status.Error(codes.InvalidArgument, "Short name should be 3 to 64 letters and digits and not a reserved word.")
+	}
+
+	if errors.Is(err, domain.ErrDigestMismatch) {
+		return status.Error(codes.InvalidArgument, "Digest mismatch, file was corrupted in transit.")
+	}
+
+	if errors.Is(err, domain.ErrConflict) {
+		return status.Error(codes.AlreadyExists, "Short name is already taken.")
+	}
+
+	if errors.Is(err, domain.ErrNameNeedsAuth) {
+		return status.Error(codes.Unauthenticated, "Choosing a short name requires an API key or a token.")
+	}
+
+	if errors.Is(err, domain.ErrUnauthenticated) {
+		return status.Error(codes.Unauthenticated, "Invalid API key.")
+	}
+
+	if errors.Is(err, domain.ErrTooLarge) {
+		return status.Error(codes.ResourceExhausted, "File is larger than allowed.")
+	}
+
+	if errors.Is(err, domain.ErrTypeNotAllowed) {
+		return status.Error(codes.PermissionDenied, "This type of files is not allowed.")
+	}
+
+	if errors.Is(err, domain.ErrQuotaBytes) {
+		return quotaError("bytes", "Storage quota exceeded.")
+	}
+
+	if errors.Is(err, domain.ErrQuotaFiles) {
+		return quotaError("files", "File count quota exceeded.")
+	}
+
+	return status.Error(codes.Internal, "Internal Error")
+}
+
+// превышение квоты: по subject гейтвей отличает его от слишком большого для ключа файла
+// и место от числа файлов
+func quotaError(subject, msg string) error {
+	st, err := status.New(codes.ResourceExhausted, msg).WithDetails(&errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{Subject: subject, Description: msg}},
+	})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+
+	return st.Err()
+}
+
+// срок жизни из запроса: секунды, 0 - по умолчанию, -1 - бессрочно
+func ttl(seconds int64) time.Duration {
+	if seconds < 0 {
+		return domain.NoExpiry
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// unix-время истечения для ответа, 0 - бессрочно
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+// unix-время из запроса, 0 - время не задано
+func unixOrNone(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(sec, 0)
+}
+
+// взять данные файла по его короткому айди
 func (h *GrpcHandler) GetFile(ctx context.Context, req *pb.GetFileDataReq) (*pb.GetFileDataResp, error) {
-	file, err := h.service.Get(ctx, req.GetShortName())
+	file, err := h.service.Get(ctx, req.GetShortName(), req.GetPassword())
+	if err != nil {
+		return nil, fileError(err)
+	}
+
+	return &pb.GetFileDataResp{
+		Filename:     file.OriginalName,
+		SizeBytes:    file.Size,
+		ContentType:  file.ContentType,
+		ExpiresAt:    unixOrZero(file.ExpiresAt),
+		MaxDownloads: file.MaxDownloads,
+		Downloads:    file.Downloads,
+		Sha256:       file.ContentHash,
+	}, nil
+}
+
+// сохранить файл из временного пути в память и записать в бд
+func (h *GrpcHandler) RegisterFile(ctx context.Context, req *pb.RegisterFileRequest) (*pb.RegisterFileResp, error) {
+	res, err := h.service.Upload(
+		ctx,
+		req.GetTmpName(),
+		domain.UploadMeta{
+			Name:         req.GetFilename(),
+			Size:         req.GetSizeBytes(),
+			ContentType:  req.GetContentType(),
+			TTL:          ttl(req.GetTtlSeconds()),
+			MaxDownloads: req.GetMaxDownloads(),
+			Password:     req.GetPassword(),
+			ShortName:    req.GetShortName(),
+			SHA256:       req.GetSha256(),
+			MD5:          req.GetMd5(),
+			KeyID:        req.GetKeyId(),
+			Owner:        req.GetOwner(),
+		},
+	)
+
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	return &pb.RegisterFileResp{ShortName: res.ID, DeleteToken: res.DeleteToken}, nil
+}
+
+// удалить файл по токену владельца
+func (h *GrpcHandler) DeleteFile(ctx context.Context, req *pb.DeleteFileReq) (*pb.DeleteFileResp, error) {
+	err := h.service.Delete(ctx, req.GetShortName(), req.GetDeleteToken())
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "File not found.")
+		}
+
+		if errors.Is(err, domain.ErrWrongToken) {
+			return nil, status.Error(codes.PermissionDenied, "Wrong delete token.")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal Error.")
+	}
+
+	return &pb.DeleteFileResp{}, nil
+}
+
+// поменять срок жизни файла по токену владельца
+func (h *GrpcHandler) UpdateExpiry(ctx context.Context, req *pb.UpdateExpiryReq) (*pb.UpdateExpiryResp, error) {
+	expires, err := h.service.UpdateExpiry(ctx, req.GetShortName(), req.GetDeleteToken(), ttl(req.GetTtlSeconds()))
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
 			return nil, status.Error(codes.NotFound, "File not found.")
@@ -42,30 +250,244 @@ func (h *GrpcHandler) GetFile(ctx context.Context, req *pb.GetFileDataReq) (*pb.
 			return nil, status.Error(codes.DeadlineExceeded, "Link expired")
 		}
 
+		if errors.Is(err, domain.ErrWrongToken) {
+			return nil, status.Error(codes.PermissionDenied, "Wrong delete token.")
+		}
+
+		if errors.Is(err, domain.ErrInvalidTTL) {
+			return nil, status.Error(codes.InvalidArgument, "Requested expiry is not allowed.")
+		}
+
 		return nil, status.Error(codes.Internal, "Internal Error.")
 	}
 
-	return &pb.GetFileDataResp{
-		StPath:      file.StoragePath,
-		Filename:    file.OriginalName,
-		SizeBytes:   file.Size,
-		ContentType: file.ContentType,
-	}, nil
+	return &pb.UpdateExpiryResp{ExpiresAt: unixOrZero(expires)}, nil
 }
 
-// сохранить файл из временного пути в память и записать в бд
-func (h *GrpcHandler) RegisterFile(ctx context.Context, req *pb.RegisterFileRequest) (*pb.RegisterFileResp, error) {
-	sn, err := h.service.Upload(
-		ctx,
-		req.GetTmpName(),
-		req.GetFilename(),
-		req.GetSizeBytes(),
-		req.GetContentType(),
+// собрать загруженные файлы в набор под одной ссылкой
+func (h *GrpcHandler) CreateCollection(ctx context.Context, req *pb.CreateCollectionReq) (*pb.RegisterFileResp, error) {
+	meta := domain.CollectionMeta{
+		TTL:       ttl(req.GetTtlSeconds()),
+		Password:  req.GetPassword(),
+		ShortName: req.GetShortName(),
+		KeyID:     req.GetKeyId(),
+		Owner:     req.GetOwner(),
+	}
+	for _, m := range req.GetMembers() {
+		meta.Members = append(meta.Members, domain.MemberRef{ID: m.GetShortName(), Token: m.GetDeleteToken()})
+	}
+
+	res, err := h.service.CreateCollection(ctx, meta)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "File not found.")
+		}
+
+		if errors.Is(err, domain.ErrWrongToken) {
+			return nil, status.Error(codes.PermissionDenied, "Wrong delete token.")
+		}
+
+		if errors.Is(err, domain.ErrInvalidCollection) {
+			return nil, status.Error(codes.InvalidArgument, "Collection should contain 1 to 1000 distinct files that are not in another collection.")
+		}
+
+		return nil, uploadError(err)
+	}
+
+	return &pb.RegisterFileResp{ShortName: res.ID, DeleteToken: res.DeleteToken}, nil
+}
+
+// взять набор и данные всех его файлов
+func (h *GrpcHandler) GetCollection(ctx context.Context, req *pb.GetCollectionReq) (*pb.GetCollectionResp, error) {
+	c, err := h.service.GetCollection(ctx, req.GetShortName(), req.GetPassword())
+	if err != nil {
+		return nil, fileError(err)
+	}
+
+	resp := &pb.GetCollectionResp{ExpiresAt: unixOrZero(c.ExpiresAt)}
+	for _, f := range c.Files {
+		resp.Files = append(resp.Files, &pb.CollectionFile{
+			ShortName:    f.ID,
+			Filename:     f.OriginalName,
+			SizeBytes:    f.Size,
+			ContentType:  f.ContentType,
+			MaxDownloads: f.MaxDownloads,
+			Downloads:    f.Downloads,
+			Sha256:       f.ContentHash,
+		})
+	}
+
+	return resp, nil
+}
+
+// проверить ключ доступа к загрузке и вернуть его ограничения
+func (h *GrpcHandler) Authenticate(ctx context.Context, req *pb.AuthenticateReq) (*pb.ApiKey, error) {
+	k, err := h.service.Authenticate(ctx, req.GetKey())
+	if err != nil {
+		if errors.Is(err, domain.ErrUnauthenticated) {
+			return nil, status.Error(codes.Unauthenticated, "Invalid API key.")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal Error.")
+	}
+
+	return apiKey(k), nil
+}
+
+// страница действующих файлов по фильтру, новые сначала
+func (h *GrpcHandler) ListFiles(ctx context.Context, req *pb.ListFilesReq) (*pb.ListFilesResp, error) {
+	files, next, err := h.service.ListFiles(ctx, domain.FileFilter{
+		Owner:         req.GetOwner(),
+		NamePrefix:    req.GetNamePrefix(),
+		ContentType:   req.GetContentType(),
+		ExpiresAfter:  unixOrNone(req.GetExpiresAfter()),
+		ExpiresBefore: unixOrNone(req.GetExpiresBefore()),
+		Cursor:        req.GetPageToken(),
+		Limit:         int(req.GetPageSize()),
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidFilter) {
+			return nil, status.Error(codes.InvalidArgument, "Invalid file filter.")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal Error.")
+	}
+
+	resp := &pb.ListFilesResp{NextPageToken: next}
+	for _, f := range files {
+		resp.Files = append(resp.Files, &pb.ListedFile{
+			ShortName:    f.ID,
+			Filename:     f.OriginalName,
+			SizeBytes:    f.Size,
+			ContentType:  f.ContentType,
+			CreatedAt:    unixOrZero(f.CreatedAt),
+			ExpiresAt:    unixOrZero(f.ExpiresAt),
+			MaxDownloads: f.MaxDownloads,
+			Downloads:    f.Downloads,
+			Protected:    f.PasswordHash != "",
+			CollectionId: f.CollectionID,
+		})
+	}
+
+	return resp, nil
+}
+
+// сколько занимает владелец и какая у него квота, а в пространстве арендатора - и сам арендатор
+func (h *GrpcHandler) GetUsage(ctx context.Context, req *pb.GetUsageReq) (*pb.Usage, error) {
+	u, q, err := h.service.Usage(ctx, req.GetOwner())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Internal Error.")
+	}
+
+	resp := usage(u, q)
+	if domain.TenantFrom(ctx) != "" {
+		u, q, err := h.service.TenantUsage(ctx)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "Internal Error.")
+		}
+		resp.Tenant = usage(u, q)
+	}
+
+	return resp, nil
+}
+
+func usage(u domain.Usage, q domain.Quota) *pb.Usage {
+	return &pb.Usage{Bytes: u.Bytes, Files: u.Files, MaxBytes: q.MaxBytes, MaxFiles: q.MaxFiles}
+}
+
+func apiKey(k *domain.APIKey) *pb.ApiKey {
+	return &pb.ApiKey{
+		Id:            k.ID,
+		Name:          k.Name,
+		CreatedAt:     unixOrZero(k.CreatedAt),
+		MaxSizeBytes:  k.MaxSize,
+		MaxTtlSeconds: int64(k.MaxTTL / time.Second),
+		ContentTypes:  k.ContentTypes,
+		Tenant:        k.Tenant,
+	}
+}
+
+// отдать содержимое файла потоком, начиная с offset
+func (h *GrpcHandler) DownloadFile(req *pb.DownloadFileReq, stream pb.RegService_DownloadFileServer) error {
+	rc, err := h.service.Open(stream.Context(), req.GetShortName(), req.GetPassword(), req.GetOffset(), req.GetLength())
+	if err != nil {
+		return fileError(err)
+	}
+	defer rc.Close()
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := rc.Read(buf)
+		if n > 0 {
+			if sErr := stream.Send(&pb.DownloadFileResp{Chunk: buf[:n]}); sErr != nil {
+				return sErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return status.Error(codes.Internal, "Internal Error.")
+		}
+	}
+}
+
+// читает содержимое файла из клиентского потока, метаданные к этому моменту уже получены
+type chunkReader struct {
+	stream pb.RegService_UploadFileServer
+	buf    []byte
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		req, err := c.stream.Recv()
+		if err != nil {
+			return 0, err // io.EOF - клиент закончил передачу
+		}
+
+		c.buf = req.GetChunk()
+	}
+
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// принять файл потоком: сначала метаданные, потом куски содержимого
+func (h *GrpcHandler) UploadFile(stream pb.RegService_UploadFileServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "Empty upload.")
+	}
+
+	meta := first.GetMeta()
+	if meta == nil {
+		return status.Error(codes.InvalidArgument, "First message should contain file metadata.")
+	}
+
+	res, err := h.service.Store(
+		stream.Context(),
+		domain.UploadMeta{
+			Name:         meta.GetFilename(),
+			Size:         meta.GetSizeBytes(),
+			ContentType:  meta.GetContentType(),
+			TTL:          ttl(meta.GetTtlSeconds()),
+			MaxDownloads: meta.GetMaxDownloads(),
+			Password:     meta.GetPassword(),
+			ShortName:    meta.GetShortName(),
+			SHA256:       meta.GetSha256(),
+			MD5:          meta.GetMd5(),
+			KeyID:        meta.GetKeyId(),
+			Owner:        meta.GetOwner(),
+		},
+		&chunkReader{stream: stream},
 	)
 
 	if err != nil {
-		return nil, status.Error(codes.Internal, "Internal Error")
+		return uploadError(err)
 	}
 
-	return &pb.RegisterFileResp{ShortName: sn}, nil
+	return stream.SendAndClose(&pb.RegisterFileResp{ShortName: res.ID, DeleteToken: res.DeleteToken})
 }
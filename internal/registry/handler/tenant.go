@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// арендатор приходит в метаданных вызова, а дальше по цепочке идет в контексте.
+// перехватчики ставятся на весь сервер, так что ни один вызов не пройдет мимо проверки.
+// вместе с арендатором в контекст попадает клиент гейтвея, по нему считаются попытки ввода пароля
+
+const (
+	tenantMetadata = "tenant"
+	clientMetadata = "client"
+)
+
+// то, что перехватчикам нужно от сервиса
+type TenantChecker interface {
+	HasTenant(name string) bool
+}
+
+// контекст вызова в пространстве арендатора из метаданных
+func tenantContext(ctx context.Context, t TenantChecker) (context.Context, error) {
+	var name, client string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(tenantMetadata); len(v) > 0 {
+			name = v[0]
+		}
+		if v := md.Get(clientMetadata); len(v) > 0 {
+			client = v[0]
+		}
+	}
+
+	if !t.HasTenant(name) {
+		return nil, status.Error(codes.NotFound, "Unknown tenant.")
+	}
+
+	return domain.WithClient(domain.WithTenant(ctx, name), client), nil
+}
+
+func TenantUnaryInterceptor(t TenantChecker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := tenantContext(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func TenantStreamInterceptor(t TenantChecker) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := tenantContext(ss.Context(), t)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, tenantStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// поток с контекстом арендатора вместо исходного
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s tenantStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+	"github.com/mattn/go-sqlite3"
+)
+
+// занят ли айди арендатора в другой таблице. файлы и наборы открываются по одной и той же ссылке /get/{id}/
+func idTaken(ctx context.Context, tx *sql.Tx, table, tenant, id string) (bool, error) {
+	var taken bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+table+" WHERE tenant = ? AND id = ?);", tenant, id).Scan(&taken)
+	return taken, err
+}
+
+// сохранить набор и привязать к нему файлы members. файлы получают срок набора.
+// если айди уже занят - domain.ErrConflict, если какой-то файл пропал или уже в другом наборе - domain.ErrNotFound
+func (f *FileRepo) InsertCollection(ctx context.Context, c *domain.Collection, members []string) error {
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c.Tenant = domain.TenantFrom(ctx)
+	if taken, err := idTaken(ctx, tx, tableName, c.Tenant, c.ID); err != nil || taken {
+		if err == nil {
+			err = domain.ErrConflict
+		}
+		return err
+	}
+
+	query := "INSERT INTO " + collectionsTable + " (id, created_at, expired_at, password_hash, token_hash, owner, tenant, key_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?);"
+	_, err = tx.ExecContext(ctx, query, c.ID, c.CreatedAt.UTC(), nullTime(c.ExpiresAt), c.PasswordHash, c.TokenHash, c.Owner, c.Tenant, c.KeyID)
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	query = "UPDATE " + tableName + " SET collection_id = ?, expired_at = ? WHERE tenant = ? AND id = ? AND collection_id = '';"
+	for _, id := range members {
+		res, err := tx.ExecContext(ctx, query, c.ID, nullTime(c.ExpiresAt), c.Tenant, id)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return domain.ErrNotFound
+		}
+	}
+
+	return tx.Commit()
+}
+
+// взять набор арендатора вместе с файлами, даже если он уже истек
+func (f *FileRepo) LookupCollection(ctx context.Context, id string) (*domain.Collection, error) {
+	query := "SELECT id, created_at, expired_at, password_hash, token_hash, owner, tenant, key_id FROM " + collectionsTable + " WHERE tenant = ? AND id = ?;"
+
+	c := domain.Collection{}
+	var exp sql.NullTime
+	err := f.db.QueryRowContext(ctx, query, domain.TenantFrom(ctx), id).Scan(&c.ID, &c.CreatedAt, &exp, &c.PasswordHash, &c.TokenHash, &c.Owner, &c.Tenant, &c.KeyID)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.ExpiresAt = exp.Time
+
+	c.Files, err = f.listWhere(ctx, "tenant = ? AND collection_id = ?", c.Tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// поменять срок жизни набора и всех его файлов, нулевое время - бессрочно
+func (f *FileRepo) UpdateCollectionExpiry(ctx context.Context, id string, expiresAt time.Time) error {
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tenant := domain.TenantFrom(ctx)
+	res, err := tx.ExecContext(ctx, "UPDATE "+collectionsTable+" SET expired_at = ? WHERE tenant = ? AND id = ?;", nullTime(expiresAt), tenant, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE "+tableName+" SET expired_at = ? WHERE tenant = ? AND collection_id = ?;", nullTime(expiresAt), tenant, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// удалить набор и его файлы, вернуть ключи объектов, на которые больше никто не ссылается
+func (f *FileRepo) DeleteCollection(ctx context.Context, id string) ([]string, error) {
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tenant := domain.TenantFrom(ctx)
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+collectionsTable+" WHERE tenant = ? AND id = ?;", tenant, id); err != nil {
+		return nil, err
+	}
+
+	keys, _, err := deleteInTx(ctx, tx, "tenant = ? AND collection_id = ?", tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+	"github.com/mattn/go-sqlite3"
+)
+
+const keyColumns = "id, name, key_hash, created_at, max_size, max_ttl, content_types, tenant"
+
+func scanKey(row scanner) (*domain.APIKey, error) {
+	k := domain.APIKey{}
+
+	var ttl int64
+	var types string
+	if err := row.Scan(&k.ID, &k.Name, &k.KeyHash, &k.CreatedAt, &k.MaxSize, &ttl, &types, &k.Tenant); err != nil {
+		return nil, err
+	}
+
+	k.MaxTTL = time.Duration(ttl) * time.Second
+	if types != "" {
+		k.ContentTypes = strings.Split(types, ",")
+	}
+
+	return &k, nil
+}
+
+// сохранить ключ. если айди или хеш уже заняты - domain.ErrConflict
+func (f *FileRepo) InsertKey(ctx context.Context, k *domain.APIKey) error {
+	query := "INSERT INTO " + keysTable + " (" + keyColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?);"
+
+	_, err := f.db.ExecContext(ctx, query,
+		k.ID,
+		k.Name,
+		k.KeyHash,
+		k.CreatedAt.UTC(),
+		k.MaxSize,
+		int64(k.MaxTTL/time.Second),
+		strings.Join(k.ContentTypes, ","),
+		k.Tenant,
+	)
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+		return domain.ErrConflict
+	}
+
+	return err
+}
+
+// ключ по айди
+func (f *FileRepo) GetKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	return f.keyWhere(ctx, "id = ?", id)
+}
+
+// ключ по sha256 самого ключа
+func (f *FileRepo) KeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	return f.keyWhere(ctx, "key_hash = ?", hash)
+}
+
+func (f *FileRepo) keyWhere(ctx context.Context, where string, args ...any) (*domain.APIKey, error) {
+	query := "SELECT " + keyColumns + " FROM " + keysTable + " WHERE " + where + ";"
+
+	k, err := scanKey(f.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+
+	return k, err
+}
+
+// все ключи в порядке создания
+func (f *FileRepo) ListKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	rows, err := f.db.QueryContext(ctx, "SELECT "+keyColumns+" FROM "+keysTable+" ORDER BY created_at, id;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*domain.APIKey
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
+
+// удалить ключ, загрузки по нему сразу перестают проходить
+func (f *FileRepo) DeleteKey(ctx context.Context, id string) error {
+	res, err := f.db.ExecContext(ctx, "DELETE FROM "+keysTable+" WHERE id = ?;", id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
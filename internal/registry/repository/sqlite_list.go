@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+// страницы идут по rowid от новых записей к старым: rowid растет вместе со вставками и, в отличие
+// от created_at, всегда сравнивается как число. курсор - rowid последней отданной записи
+
+// действующие файлы арендатора по фильтру, новые сначала, и курсор следующей страницы.
+// пустой курсор - страница последняя. истекшие и исчерпавшие лимит файлы не попадают в выборку
+func (f *FileRepo) ListFiles(ctx context.Context, filter domain.FileFilter) ([]*domain.File, string, error) {
+	conds := []string{
+		"tenant = ?",
+		"(expired_at IS NULL OR expired_at > ?)",
+		"(max_downloads = 0 OR downloads < max_downloads)",
+	}
+	args := []any{domain.TenantFrom(ctx), time.Now().UTC()}
+
+	if filter.Cursor != "" {
+		rowid, err := strconv.ParseInt(filter.Cursor, 10, 64)
+		if err != nil || rowid <= 0 {
+			return nil, "", domain.ErrInvalidFilter
+		}
+		conds = append(conds, "rowid < ?")
+		args = append(args, rowid)
+	}
+	if filter.Owner != "" {
+		conds = append(conds, "owner = ?")
+		args = append(args, filter.Owner)
+	}
+	if filter.NamePrefix != "" {
+		conds = append(conds, "original_name LIKE ? ESCAPE '\\'")
+		args = append(args, escapeLike(filter.NamePrefix)+"%")
+	}
+	if major, ok := strings.CutSuffix(filter.ContentType, "/*"); ok {
+		conds = append(conds, "content_type LIKE ? ESCAPE '\\'")
+		args = append(args, escapeLike(major)+"/%")
+	} else if filter.ContentType != "" {
+		// тип может быть записан с параметрами, например text/plain; charset=utf-8.
+		// LIKE без шаблонов - то же сравнение, но без учета регистра, как и положено для типов
+		conds = append(conds, "(content_type LIKE ? ESCAPE '\\' OR content_type LIKE ? ESCAPE '\\')")
+		args = append(args, escapeLike(filter.ContentType), escapeLike(filter.ContentType)+";%")
+	}
+	if !filter.ExpiresAfter.IsZero() {
+		conds = append(conds, "(expired_at IS NULL OR expired_at >= ?)")
+		args = append(args, filter.ExpiresAfter.UTC())
+	}
+	if !filter.ExpiresBefore.IsZero() {
+		conds = append(conds, "expired_at <= ?")
+		args = append(args, filter.ExpiresBefore.UTC())
+	}
+
+	// на одну запись больше, чтобы знать, есть ли следующая страница
+	query := "SELECT rowid, " + fileColumns + " FROM " + tableName +
+		" WHERE " + strings.Join(conds, " AND ") + " ORDER BY rowid DESC LIMIT ?;"
+	args = append(args, filter.Limit+1)
+
+	rows, err := f.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var files []*domain.File
+	var last int64
+	for rows.Next() {
+		if len(files) == filter.Limit {
+			return files, strconv.FormatInt(last, 10), rows.Close()
+		}
+
+		file, err := scanFile(rowidScanner{row: rows, rowid: &last})
+		if err != nil {
+			return nil, "", err
+		}
+
+		files = append(files, file)
+	}
+
+	return files, "", rows.Err()
+}
+
+// читает rowid перед колонками записи, чтобы не дублировать scanFile
+type rowidScanner struct {
+	row   scanner
+	rowid *int64
+}
+
+func (s rowidScanner) Scan(dest ...any) error {
+	return s.row.Scan(append([]any{s.rowid}, dest...)...)
+}
+
+// экранировать спецсимволы LIKE, чтобы строка совпадала буквально (с ESCAPE '\')
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+// сколько байт и записей у владельца сейчас в бд арендатора из ctx. истекшие записи считаются,
+// пока очистка их не удалила, так что место освобождается вместе с ClearExpired
+func (f *FileRepo) Usage(ctx context.Context, owner string) (domain.Usage, error) {
+	query := "SELECT COALESCE(SUM(size_bytes), 0), COUNT(*) FROM " + tableName + " WHERE tenant = ? AND owner = ?;"
+
+	var u domain.Usage
+	err := f.db.QueryRowContext(ctx, query, domain.TenantFrom(ctx), owner).Scan(&u.Bytes, &u.Files)
+	return u, err
+}
+
+// сколько байт и записей у арендатора сейчас в бд, считается так же, как у владельца
+func (f *FileRepo) TenantUsage(ctx context.Context, tenant string) (domain.Usage, error) {
+	query := "SELECT COALESCE(SUM(size_bytes), 0), COUNT(*) FROM " + tableName + " WHERE tenant = ?;"
+
+	var u domain.Usage
+	err := f.db.QueryRowContext(ctx, query, tenant).Scan(&u.Bytes, &u.Files)
+	return u, err
+}
+
+// квота владельца в арендаторе из ctx, domain.ErrNotFound - отдельной квоты нет
+func (f *FileRepo) GetQuota(ctx context.Context, owner string) (*domain.Quota, error) {
+	query := "SELECT max_bytes, max_files FROM " + quotasTable + " WHERE tenant = ? AND owner = ?;"
+
+	var q domain.Quota
+	err := f.db.QueryRowContext(ctx, query, domain.TenantFrom(ctx), owner).Scan(&q.MaxBytes, &q.MaxFiles)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &q, nil
+}
+
+// задать владельцу в арендаторе из ctx отдельную квоту вместо прежней
+func (f *FileRepo) SetQuota(ctx context.Context, owner string, q domain.Quota) error {
+	query := "INSERT INTO " + quotasTable + " (tenant, owner, max_bytes, max_files) VALUES (?, ?, ?, ?) " +
+		"ON CONFLICT (tenant, owner) DO UPDATE SET max_bytes = excluded.max_bytes, max_files = excluded.max_files;"
+
+	_, err := f.db.ExecContext(ctx, query, domain.TenantFrom(ctx), owner, q.MaxBytes, q.MaxFiles)
+	return err
+}
+
+// вернуть владельцу квоту по умолчанию. если отдельной квоты не было - domain.ErrNotFound
+func (f *FileRepo) DeleteQuota(ctx context.Context, owner string) error {
+	res, err := f.db.ExecContext(ctx, "DELETE FROM "+quotasTable+" WHERE tenant = ? AND owner = ?;", domain.TenantFrom(ctx), owner)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
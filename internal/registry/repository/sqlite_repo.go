@@ -3,11 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+	"github.com/mattn/go-sqlite3"
 )
 
 // репозиторий содержит указатель на бд и реализует интерфейс FileRepoInterface
@@ -16,7 +17,11 @@ type FileRepo struct {
 }
 
 const (
-	tableName = "files" // имя таблицы для удобства
+	tableName        = "files"       // имя таблицы для удобства
+	blobsTable       = "blobs"       // объекты в хранилище, на которые ссылаются файлы, с числом ссылок
+	collectionsTable = "collections" // наборы файлов под одной ссылкой
+	keysTable        = "api_keys"    // ключи доступа к загрузке
+	quotasTable      = "quotas"      // квоты владельцев в арендаторах, отличные от квоты по умолчанию
 )
 
 // инициализация (создание таблиц) происходит прямо при создании репозитория
@@ -38,91 +43,640 @@ func NewFileRepo(db *sql.DB) (*FileRepo, error) {
 		return nil, fmt.Errorf("failed to set up table: %w", err)
 	}
 
+	if err := migrate(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate table: %w", err)
+	}
+
 	return &FileRepo{
 		db: db,
 	}, nil
 }
 
-// сохранить файл, вернуть nil в случае удачи, error в противном случае
+// колонки в том порядке, в котором их читает scanFile
+const fileColumns = "id, original_name, storage_path, size_bytes, content_type, created_at, expired_at, max_downloads, downloads, password_hash, token_hash, content_hash, integrity, collection_id, owner, tenant, key_id"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFile(row scanner) (*domain.File, error) {
+	file := domain.File{}
+
+	var exp sql.NullTime
+	err := row.Scan(
+		&file.ID,
+		&file.OriginalName,
+		&file.StoragePath,
+		&file.Size,
+		&file.ContentType,
+		&file.CreatedAt,
+		&exp,
+		&file.MaxDownloads,
+		&file.Downloads,
+		&file.PasswordHash,
+		&file.TokenHash,
+		&file.ContentHash,
+		&file.Integrity,
+		&file.CollectionID,
+		&file.Owner,
+		&file.Tenant,
+		&file.KeyID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	file.ExpiresAt = exp.Time
+	return &file, nil
+}
+
+// нулевое время хранится как NULL, такие записи никогда не истекают.
+// время всегда пишется в UTC: sqlite сравнивает его как строки
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// изменения схемы после первой версии таблицы. порядок менять нельзя, новые добавляются в конец.
+// номер последней примененной миграции хранится в PRAGMA user_version
+var migrations = []string{
+	// ограничение на число скачиваний, 0 - без ограничений
+	`ALTER TABLE files ADD COLUMN max_downloads INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE files ADD COLUMN downloads INTEGER NOT NULL DEFAULT 0;`,
+
+	// bcrypt-хеш пароля, пустая строка - файл без пароля
+	`ALTER TABLE files ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';`,
+
+	// sha256 токена владельца, пустая строка - файл загружен до появления токенов
+	`ALTER TABLE files ADD COLUMN token_hash TEXT NOT NULL DEFAULT '';`,
+
+	// общие объекты для одинакового содержимого. у старых файлов хеша нет, и объектом владеет сама запись
+	`ALTER TABLE files ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+	CREATE TABLE blobs (
+		hash TEXT PRIMARY KEY,
+		storage_path TEXT NOT NULL,
+		refs INTEGER NOT NULL
+	);`,
+
+	// результат проверки содержимого, пустая строка - все в порядке
+	`ALTER TABLE files ADD COLUMN integrity TEXT NOT NULL DEFAULT '';`,
+
+	// очистка ищет ближайший и уже наступившие сроки истечения
+	`CREATE INDEX files_expired_at ON files (expired_at);`,
+
+	// наборы файлов под одной ссылкой. файлы набора истекают вместе с ним
+	`CREATE TABLE collections (
+		id TEXT PRIMARY KEY,
+		created_at DATETIME,
+		expired_at TIMESTAMP,
+		password_hash TEXT NOT NULL DEFAULT '',
+		token_hash TEXT NOT NULL DEFAULT ''
+	);
+	ALTER TABLE files ADD COLUMN collection_id TEXT NOT NULL DEFAULT '';
+	CREATE INDEX files_collection_id ON files (collection_id);`,
+
+	// ключи доступа к загрузке: sha256 ключа и ограничения загрузок по нему.
+	// срок - в секундах, типы содержимого - через запятую
+	`CREATE TABLE api_keys (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT '',
+		key_hash TEXT NOT NULL UNIQUE,
+		created_at DATETIME,
+		max_size INTEGER NOT NULL DEFAULT 0,
+		max_ttl INTEGER NOT NULL DEFAULT 0,
+		content_types TEXT NOT NULL DEFAULT ''
+	);`,
+
+	// субъект токена, которым подписана загрузка, пустая строка - загрузка без входа
+	`ALTER TABLE files ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+	ALTER TABLE collections ADD COLUMN owner TEXT NOT NULL DEFAULT '';`,
+
+	// списки файлов владельца. rowid входит в любой индекс, так что порядок страниц индекс тоже дает
+	`CREATE INDEX files_owner ON files (owner);`,
+
+	// квоты отдельных владельцев. у кого здесь записи нет, действует квота из настроек сервера
+	`CREATE TABLE quotas (
+		owner TEXT PRIMARY KEY,
+		max_bytes INTEGER NOT NULL DEFAULT 0,
+		max_files INTEGER NOT NULL DEFAULT 0
+	);`,
+
+	// пространства арендаторов: короткое имя уникально только внутри арендатора, поэтому первичный
+	// ключ файлов и наборов меняется, а sqlite умеет это только пересозданием таблицы.
+	// rowid переносится как есть, чтобы не сломать курсоры списков. старые записи и ключи
+	// остаются в пространстве по умолчанию
+	`CREATE TABLE files_new (
+		id TEXT NOT NULL,
+		original_name TEXT NOT NULL,
+		storage_path TEXT NOT NULL,
+		size_bytes INTEGER,
+		content_type TEXT,
+		created_at DATETIME,
+		expired_at TIMESTAMP,
+		max_downloads INTEGER NOT NULL DEFAULT 0,
+		downloads INTEGER NOT NULL DEFAULT 0,
+		password_hash TEXT NOT NULL DEFAULT '',
+		token_hash TEXT NOT NULL DEFAULT '',
+		content_hash TEXT NOT NULL DEFAULT '',
+		integrity TEXT NOT NULL DEFAULT '',
+		collection_id TEXT NOT NULL DEFAULT '',
+		owner TEXT NOT NULL DEFAULT '',
+		tenant TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (tenant, id)
+	);
+	INSERT INTO files_new (rowid, id, original_name, storage_path, size_bytes, content_type, created_at, expired_at,
+		max_downloads, downloads, password_hash, token_hash, content_hash, integrity, collection_id, owner)
+	SELECT rowid, id, original_name, storage_path, size_bytes, content_type, created_at, expired_at,
+		max_downloads, downloads, password_hash, token_hash, content_hash, integrity, collection_id, owner FROM files;
+	DROP TABLE files;
+	ALTER TABLE files_new RENAME TO files;
+	CREATE INDEX files_expired_at ON files (expired_at);
+	CREATE INDEX files_collection_id ON files (tenant, collection_id);
+	CREATE INDEX files_owner ON files (owner);
+
+	CREATE TABLE collections_new (
+		id TEXT NOT NULL,
+		created_at DATETIME,
+		expired_at TIMESTAMP,
+		password_hash TEXT NOT NULL DEFAULT '',
+		token_hash TEXT NOT NULL DEFAULT '',
+		owner TEXT NOT NULL DEFAULT '',
+		tenant TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (tenant, id)
+	);
+	INSERT INTO collections_new (id, created_at, expired_at, password_hash, token_hash, owner)
+	SELECT id, created_at, expired_at, password_hash, token_hash, owner FROM collections;
+	DROP TABLE collections;
+	ALTER TABLE collections_new RENAME TO collections;
+
+	ALTER TABLE api_keys ADD COLUMN tenant TEXT NOT NULL DEFAULT '';`,
+
+	// результат проверки общего объекта: новая загрузка с тем же хешем не должна ссылаться
+	// на пропавший или испорченный объект. метка переносится с уже помеченных файлов
+	`ALTER TABLE blobs ADD COLUMN integrity TEXT NOT NULL DEFAULT '';
+	UPDATE blobs SET integrity = (SELECT MAX(integrity) FROM files WHERE files.storage_path = blobs.storage_path)
+	WHERE EXISTS (SELECT 1 FROM files WHERE files.storage_path = blobs.storage_path AND integrity != '');`,
+
+	// раньше время записывалось в местном поясе сервера, а сравнивается оно строкой со временем в UTC:
+	// при смешанных смещениях порядок строк не совпадает с порядком моментов. все уже записанное
+	// переводится в UTC. дробная часть секунды у старых записей сокращается до миллисекунд
+	`UPDATE files SET created_at = COALESCE(strftime('%Y-%m-%d %H:%M:%f+00:00', created_at), created_at),
+		expired_at = COALESCE(strftime('%Y-%m-%d %H:%M:%f+00:00', expired_at), expired_at);
+	UPDATE collections SET created_at = COALESCE(strftime('%Y-%m-%d %H:%M:%f+00:00', created_at), created_at),
+		expired_at = COALESCE(strftime('%Y-%m-%d %H:%M:%f+00:00', expired_at), expired_at);
+	UPDATE api_keys SET created_at = COALESCE(strftime('%Y-%m-%d %H:%M:%f+00:00', created_at), created_at);`,
+
+	// владелец (subject токена или ключ) живет в пространстве арендатора, как и его файлы:
+	// занятое и квота считаются отдельно в каждом арендаторе. старые квоты остаются
+	// в пространстве по умолчанию
+	`CREATE TABLE quotas_new (
+		tenant TEXT NOT NULL DEFAULT '',
+		owner TEXT NOT NULL,
+		max_bytes INTEGER NOT NULL DEFAULT 0,
+		max_files INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (tenant, owner)
+	);
+	INSERT INTO quotas_new (owner, max_bytes, max_files) SELECT owner, max_bytes, max_files FROM quotas;
+	DROP TABLE quotas;
+	ALTER TABLE quotas_new RENAME TO quotas;
+	DROP INDEX files_owner;
+	CREATE INDEX files_owner ON files (tenant, owner);`,
+
+	// ключ, по которому загружен файл или создан набор: ограничение ключа на срок действует и
+	// тогда, когда владелец меняет срок позже. у старых записей ключ восстанавливается из владельца,
+	// если файл загружен только по ключу
+	`ALTER TABLE files ADD COLUMN key_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE collections ADD COLUMN key_id TEXT NOT NULL DEFAULT '';
+	UPDATE files SET key_id = substr(owner, 5) WHERE owner LIKE 'key:%';
+	UPDATE collections SET key_id = substr(owner, 5) WHERE owner LIKE 'key:%';`,
+}
+
+// применить миграции, которых еще не было в этой бд
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version;").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+
+		// PRAGMA не принимает параметры
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d;", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// сохранить файл, вернуть nil в случае удачи, error в противном случае.
+// если айди уже занят - domain.ErrConflict, сервис может попробовать другой.
+// если объект с таким же содержимым уже есть, файл начинает ссылаться на него, и в StoragePath
+// записывается его ключ - загруженный сервисом объект тогда больше не нужен
 func (f *FileRepo) Insert(ctx context.Context, file *domain.File) error {
-	query := "INSERT INTO " + tableName + " (id, original_name, storage_path, size_bytes, content_type, created_at, expired_at)" +
-		"VALUES (?, ?, ?, ?, ?, ?, ?);"
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// файлы и наборы арендатора делят одно пространство коротких имен
+	file.Tenant = domain.TenantFrom(ctx)
+	if taken, err := idTaken(ctx, tx, collectionsTable, file.Tenant, file.ID); err != nil || taken {
+		if err == nil {
+			err = domain.ErrConflict
+		}
+		return err
+	}
 
-	_, err := f.db.ExecContext(
+	key := file.StoragePath
+	if file.ContentHash != "" {
+		if key, err = claimBlob(ctx, tx, file.ContentHash, file.StoragePath); err != nil {
+			return err
+		}
+	}
+
+	query := "INSERT INTO " + tableName + " (" + fileColumns + ")" +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);"
+
+	_, err = tx.ExecContext(
 		ctx, query,
 		file.ID,
 		file.OriginalName,
-		file.StoragePath,
+		key,
 		file.Size,
 		file.ContentType,
-		file.CreatedAt,
-		file.CreatedAt.Add(48*time.Hour), // файл живёт 48 часов, потом удаляется
+		file.CreatedAt.UTC(),
+		nullTime(file.ExpiresAt),
+		file.MaxDownloads,
+		file.Downloads,
+		file.PasswordHash,
+		file.TokenHash,
+		file.ContentHash,
+		file.Integrity,
+		file.CollectionID,
+		file.Owner,
+		file.Tenant,
+		file.KeyID,
 	)
 
-	return err
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// до коммита не трогаем: при коллизии айди сервис повторит вставку с той же структурой
+	file.StoragePath = key
+	return nil
 }
 
-// взять файл или ошибку
-func (f *FileRepo) Get(ctx context.Context, shortName string) (*domain.File, error) {
-	query := "SELECT * FROM " + tableName + " WHERE id = ?;"
-	respFile := domain.File{}
+// сослаться на общий объект с содержимым hash или завести новый с ключом key и вернуть ключ, который
+// запишется в файл. объект, помеченный проверкой как пропавший или испорченный, заменяется только что
+// загруженным: записи со старым ключом переезжают на новый (хеш тот же, значит и содержимое), а сам
+// старый объект без ссылок потом уберет сборка мусора
+func claimBlob(ctx context.Context, tx *sql.Tx, hash, key string) (string, error) {
+	query := "INSERT INTO " + blobsTable + " (hash, storage_path, refs) VALUES (?, ?, 1) " +
+		"ON CONFLICT(hash) DO UPDATE SET refs = refs + 1 RETURNING storage_path, integrity;"
 
-	var exp time.Time
-	err := f.db.QueryRowContext(ctx, query, shortName).Scan(
-		&respFile.ID,
-		&respFile.OriginalName,
-		&respFile.StoragePath,
-		&respFile.Size,
-		&respFile.ContentType,
-		&respFile.CreatedAt,
-		&exp,
-	)
+	var existing, state string
+	if err := tx.QueryRowContext(ctx, query, hash, key).Scan(&existing, &state); err != nil {
+		return "", err
+	}
+	if state == domain.IntegrityOK {
+		return existing, nil
+	}
+
+	query = "UPDATE " + blobsTable + " SET storage_path = ?, integrity = '' WHERE hash = ?;"
+	if _, err := tx.ExecContext(ctx, query, key, hash); err != nil {
+		return "", err
+	}
+
+	query = "UPDATE " + tableName + " SET storage_path = ?, integrity = '' WHERE storage_path = ? AND content_hash = ?;"
+	if _, err := tx.ExecContext(ctx, query, key, existing, hash); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
 
+// взять запись арендатора из контекста как есть, даже если ссылка уже не действует
+func (f *FileRepo) Lookup(ctx context.Context, shortName string) (*domain.File, error) {
+	query := "SELECT " + fileColumns + " FROM " + tableName + " WHERE tenant = ? AND id = ?;"
+
+	respFile, err := scanFile(f.db.QueryRowContext(ctx, query, domain.TenantFrom(ctx), shortName))
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
-	if time.Now().After(exp) {
+
+	return respFile, nil
+}
+
+// взять файл или ошибку
+func (f *FileRepo) Get(ctx context.Context, shortName string) (*domain.File, error) {
+	respFile, err := f.Lookup(ctx, shortName)
+	if err != nil {
+		return nil, err
+	}
+	if !respFile.ExpiresAt.IsZero() && time.Now().After(respFile.ExpiresAt) {
 		return nil, domain.ErrExpired
 	}
+	if respFile.Exhausted() {
+		return nil, domain.ErrDownloadsExhausted
+	}
+	if respFile.Integrity != domain.IntegrityOK {
+		return nil, domain.ErrCorrupted
+	}
+
+	return respFile, nil
+}
+
+// засчитать скачивание. проверка лимита и увеличение счетчика - один запрос,
+// поэтому два одновременных скачивания не пройдут по последней попытке оба
+func (f *FileRepo) CountDownload(ctx context.Context, id string) error {
+	query := "UPDATE " + tableName + " SET downloads = downloads + 1 " +
+		"WHERE tenant = ? AND id = ? AND (max_downloads = 0 OR downloads < max_downloads) AND (expired_at IS NULL OR expired_at > ?) " +
+		"AND integrity = '';"
+
+	res, err := f.db.ExecContext(ctx, query, domain.TenantFrom(ctx), id, time.Now().UTC())
+	if err != nil {
+		return err
+	}
 
-	return &respFile, nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// ничего не обновилось - выясняем почему: файла нет, он истек, испорчен или скачивания кончились
+	if n == 0 {
+		if _, err := f.Get(ctx, id); err != nil {
+			return err
+		}
+		return domain.ErrDownloadsExhausted
+	}
+
+	return nil
+}
+
+// поменять срок жизни файла, нулевое время - бессрочно
+func (f *FileRepo) UpdateExpiry(ctx context.Context, id string, expiresAt time.Time) error {
+	query := "UPDATE " + tableName + " SET expired_at = ? WHERE tenant = ? AND id = ?;"
+
+	res, err := f.db.ExecContext(ctx, query, nullTime(expiresAt), domain.TenantFrom(ctx), id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
 }
 
-// удалить файл из бд, вернуть nil, если получилось, в противном случае ошибку (несуществующий айди ошибкой не является).
-func (f *FileRepo) Delete(ctx context.Context, id string) error {
+// объекты хранилища по порядку ключей, начиная после after. общий объект возвращается один раз
+func (f *FileRepo) ListBlobs(ctx context.Context, after string, limit int) ([]domain.Blob, error) {
+	query := "SELECT storage_path, MAX(content_hash) FROM " + tableName +
+		" WHERE storage_path > ? GROUP BY storage_path ORDER BY storage_path LIMIT ?;"
+
+	rows, err := f.db.QueryContext(ctx, query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blobs []domain.Blob
+	for rows.Next() {
+		var b domain.Blob
+		if err := rows.Scan(&b.Key, &b.Hash); err != nil {
+			return nil, err
+		}
+
+		blobs = append(blobs, b)
+	}
 
-	query := "DELETE FROM " + tableName + " WHERE id = ?"
-	_, err := f.db.ExecContext(ctx, query, id)
-	return err
+	return blobs, rows.Err()
 }
 
-// почистить истекшие файлы
-func (f *FileRepo) ClearExpired(ctx context.Context) error {
-	query := "SELECT storage_path FROM " + tableName + " WHERE expired_at < DATETIME('now', 'localtime');"
-	rows, err := f.db.QueryContext(ctx, query)
+// записать результат проверки в сам объект и во все файлы, которые на него ссылаются
+func (f *FileRepo) MarkIntegrity(ctx context.Context, key, state string) error {
+	tx, err := f.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	query := "UPDATE " + blobsTable + " SET integrity = ? WHERE storage_path = ? AND integrity != ?;"
+	if _, err := tx.ExecContext(ctx, query, state, key, state); err != nil {
+		return err
+	}
+
+	query = "UPDATE " + tableName + " SET integrity = ? WHERE storage_path = ? AND integrity != ?;"
+	if _, err := tx.ExecContext(ctx, query, state, key, state); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// файлы, которые ссылаются на объект хранилища с ключом key
+func (f *FileRepo) ListByKey(ctx context.Context, key string) ([]*domain.File, error) {
+	return f.listWhere(ctx, "storage_path = ?", key)
+}
+
+// ссылается ли на объект хоть одна запись. у старых записей локального хранилища в бд лежит
+// полный путь вида data/storage/<key>, поэтому совпадение по окончанию пути тоже считается
+func (f *FileRepo) Referenced(ctx context.Context, key string) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM " + tableName + " WHERE storage_path = ? OR storage_path LIKE ? ESCAPE '\\') " +
+		"OR EXISTS (SELECT 1 FROM " + blobsTable + " WHERE storage_path = ?);"
+
+	pattern := "%/" + escapeLike(key)
+
+	var found bool
+	if err := f.db.QueryRowContext(ctx, query, key, pattern, key).Scan(&found); err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// файлы, у которых проверка нашла пропавшее или испорченное содержимое
+func (f *FileRepo) ListDamaged(ctx context.Context) ([]*domain.File, error) {
+	return f.listWhere(ctx, "integrity != ''")
+}
+
+// записи по условию, упорядоченные по айди
+func (f *FileRepo) listWhere(ctx context.Context, where string, args ...any) ([]*domain.File, error) {
+	query := "SELECT " + fileColumns + " FROM " + tableName + " WHERE " + where + " ORDER BY id;"
+
+	rows, err := f.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
+	var files []*domain.File
 	for rows.Next() {
-		var path string
-		if err := rows.Scan(&path); err != nil {
-			return err
+		file, err := scanFile(rows)
+		if err != nil {
+			return nil, err
 		}
 
-		// если возникла ошибка, просто пропускаем операцию, не прерывая цикл
-		_ = os.Remove(path)
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+// удалить файл арендатора из бд и вернуть ключи объектов, на которые больше никто не ссылается
+// (несуществующий айди ошибкой не является)
+func (f *FileRepo) Delete(ctx context.Context, id string) ([]string, error) {
+	keys, _, err := f.deleteWhere(ctx, "tenant = ? AND id = ?", domain.TenantFrom(ctx), id)
+	return keys, err
+}
+
+// удалить не больше limit истекших и исчерпавших лимит скачиваний записей (0 - все) и вернуть
+// ключи их содержимого в хранилище, чтобы сервис удалил и его, и число удаленных записей.
+// общий объект удаляется вместе с последней ссылкой на него
+func (f *FileRepo) ClearExpired(ctx context.Context, limit int) ([]string, int, error) {
+	now := time.Now().UTC()
+
+	// файлы набора истекают вместе с ним, так что сам набор можно удалять сразу
+	if _, err := f.db.ExecContext(ctx, "DELETE FROM "+collectionsTable+" WHERE expired_at <= ?;", now); err != nil {
+		return nil, 0, err
+	}
+
+	where := "expired_at <= ? OR (max_downloads > 0 AND downloads >= max_downloads)"
+	if limit > 0 {
+		where = "rowid IN (SELECT rowid FROM " + tableName + " WHERE " + where + " LIMIT ?)"
+		return f.deleteWhere(ctx, where, now, limit)
+	}
+
+	return f.deleteWhere(ctx, where, now)
+}
+
+// ближайший срок истечения среди еще не удаленных записей. ok == false - истекать нечему.
+// запрос идет по индексу, без агрегата: так драйвер отдает колонку как время, а не строку
+func (f *FileRepo) NextExpiry(ctx context.Context) (time.Time, bool, error) {
+	query := "SELECT expired_at FROM " + tableName + " WHERE expired_at IS NOT NULL ORDER BY expired_at LIMIT 1;"
+
+	var exp time.Time
+	err := f.db.QueryRowContext(ctx, query).Scan(&exp)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
 	}
 
-	if rows.Err() != nil {
-		return rows.Err()
+	return exp, true, nil
+}
+
+// удалить записи по условию и освободить их объекты. удаление записей и уменьшение счетчиков
+// ссылок - одна транзакция, поэтому одновременная загрузка такого же файла не останется без объекта
+func (f *FileRepo) deleteWhere(ctx context.Context, where string, args ...any) ([]string, int, error) {
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	keys, deleted, err := deleteInTx(ctx, tx, where, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+
+	return keys, deleted, nil
+}
+
+// то же внутри уже открытой транзакции
+func deleteInTx(ctx context.Context, tx *sql.Tx, where string, args ...any) ([]string, int, error) {
+	rows, err := tx.QueryContext(ctx, "DELETE FROM "+tableName+" WHERE "+where+" RETURNING storage_path, content_hash;", args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var keys []string
+	var deleted int
+	refs := make(map[string]int64) // сколько ссылок на каждый общий объект удалено
+	for rows.Next() {
+		var key, hash string
+		if err := rows.Scan(&key, &hash); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		deleted++
+
+		// объектом без хеша владеет сама запись
+		if hash == "" {
+			keys = append(keys, key)
+			continue
+		}
+
+		refs[hash]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	for hash, n := range refs {
+		if _, err := tx.ExecContext(ctx, "UPDATE "+blobsTable+" SET refs = refs - ? WHERE hash = ?;", n, hash); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if len(refs) > 0 {
+		rows, err := tx.QueryContext(ctx, "DELETE FROM "+blobsTable+" WHERE refs <= 0 RETURNING storage_path;")
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return nil, 0, err
+			}
+
+			keys = append(keys, key)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, 0, err
+		}
 	}
 
-	query = "DELETE FROM " + tableName + " WHERE expired_at < DATETIME('now', 'localtime');"
-	_, err = f.db.ExecContext(ctx, query)
-	return err
+	return keys, deleted, nil
 }
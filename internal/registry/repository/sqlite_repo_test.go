@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"testing"
 	"time"
 
@@ -63,6 +64,8 @@ func TestFileRepo_InsertAndGet(t *testing.T) {
 		Size:         1024,
 		ContentType:  "text/plain",
 		CreatedAt:    now,
+		Owner:        "alice",
+		KeyID:        "k1",
 	}
 
 	// 1. Тест Insert
@@ -70,6 +73,11 @@ func TestFileRepo_InsertAndGet(t *testing.T) {
 		t.Fatalf("Insert failed: %v", err)
 	}
 
+	// повторная вставка с тем же айди - отдельная ошибка, а не просто ошибка бд
+	if err := repo.Insert(ctx, file); err != domain.ErrConflict {
+		t.Errorf("Expected ErrConflict on duplicate id, got %v", err)
+	}
+
 	// 2. Тест Get (успешный)
 	fetched, err := repo.Get(ctx, file.ID)
 	if err != nil {
@@ -83,6 +91,12 @@ func TestFileRepo_InsertAndGet(t *testing.T) {
 	if fetched.OriginalName != file.OriginalName {
 		t.Errorf("Expected OriginalName %s, got %s", file.OriginalName, fetched.OriginalName)
 	}
+	if fetched.Owner != file.Owner {
+		t.Errorf("Expected Owner %s, got %s", file.Owner, fetched.Owner)
+	}
+	if fetched.KeyID != file.KeyID {
+		t.Errorf("Expected KeyID %s, got %s", file.KeyID, fetched.KeyID)
+	}
 
 	// Проверяем время (учитывая, что БД может отсечь наносекунды)
 	if fetched.CreatedAt.Unix() != file.CreatedAt.Unix() {
@@ -122,13 +136,893 @@ func TestFileRepo_Delete(t *testing.T) {
 	}
 
 	// Удаляем
-	if err := repo.Delete(ctx, file.ID); err != nil {
+	keys, err := repo.Delete(ctx, file.ID)
+	if err != nil {
 		t.Fatalf("Delete failed: %v", err)
 	}
+	if len(keys) != 1 || keys[0] != file.StoragePath {
+		t.Errorf("Expected [%s], got %v", file.StoragePath, keys)
+	}
 
 	// Проверяем, что файла больше нет
-	_, err := repo.Get(ctx, file.ID)
+	_, err = repo.Get(ctx, file.ID)
 	if err != domain.ErrNotFound {
 		t.Errorf("Expected ErrNotFound after delete, got %v", err)
 	}
 }
+
+func TestFileRepo_ClearExpired(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	old := &domain.File{
+		ID:           "old",
+		OriginalName: "old.txt",
+		StoragePath:  "old.dat",
+		CreatedAt:    time.Now().Add(-72 * time.Hour),
+		ExpiresAt:    time.Now().Add(-24 * time.Hour),
+	}
+	fresh := &domain.File{
+		ID:           "fresh",
+		OriginalName: "fresh.txt",
+		StoragePath:  "fresh.dat",
+		CreatedAt:    time.Now(),
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	forever := &domain.File{
+		ID:           "forever",
+		OriginalName: "forever.txt",
+		StoragePath:  "forever.dat",
+		CreatedAt:    time.Now().Add(-72 * time.Hour),
+	}
+
+	for _, f := range []*domain.File{old, fresh, forever} {
+		if err := repo.Insert(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := repo.Get(ctx, old.ID); err != domain.ErrExpired {
+		t.Errorf("Expected ErrExpired before cleanup, got %v", err)
+	}
+
+	// Lookup отдает запись, даже если ссылка уже истекла
+	if _, err := repo.Lookup(ctx, old.ID); err != nil {
+		t.Errorf("Lookup of expired file failed: %v", err)
+	}
+
+	keys, _, err := repo.ClearExpired(ctx, 0)
+	if err != nil {
+		t.Fatalf("ClearExpired failed: %v", err)
+	}
+
+	// вернуться должен только ключ истекшего файла
+	if len(keys) != 1 || keys[0] != old.StoragePath {
+		t.Errorf("Expected [%s], got %v", old.StoragePath, keys)
+	}
+
+	if _, err := repo.Get(ctx, old.ID); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for expired file, got %v", err)
+	}
+	if _, err := repo.Get(ctx, fresh.ID); err != nil {
+		t.Errorf("Fresh file should stay, got %v", err)
+	}
+	if _, err := repo.Get(ctx, forever.ID); err != nil {
+		t.Errorf("File without expiry should stay, got %v", err)
+	}
+}
+
+func TestFileRepo_NextExpiry(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if _, ok, err := repo.NextExpiry(ctx); ok || err != nil {
+		t.Fatalf("Expected nothing to expire in empty db, got %v, %v", ok, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for i, exp := range []time.Time{
+		now.Add(-2 * time.Hour),
+		now.Add(-time.Hour),
+		now.Add(-time.Minute),
+		now.Add(time.Hour),
+		{},
+	} {
+		f := &domain.File{ID: fmt.Sprintf("f%d", i), StoragePath: fmt.Sprintf("f%d.dat", i), CreatedAt: now, ExpiresAt: exp}
+		if err := repo.Insert(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	next, ok, err := repo.NextExpiry(ctx)
+	if err != nil || !ok || !next.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("Expected earliest expiry %v, got %v, %v, %v", now.Add(-2*time.Hour), next, ok, err)
+	}
+
+	// три истекшие записи удаляются пачками по две
+	keys, n, err := repo.ClearExpired(ctx, 2)
+	if err != nil || n != 2 || len(keys) != 2 {
+		t.Fatalf("Expected first batch of 2, got %v, %d, %v", keys, n, err)
+	}
+	keys, n, err = repo.ClearExpired(ctx, 2)
+	if err != nil || n != 1 || len(keys) != 1 || keys[0] != "f2.dat" {
+		t.Fatalf("Expected last batch [f2.dat], got %v, %d, %v", keys, n, err)
+	}
+
+	next, ok, err = repo.NextExpiry(ctx)
+	if err != nil || !ok || !next.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected next expiry %v, got %v, %v, %v", now.Add(time.Hour), next, ok, err)
+	}
+}
+
+func TestFileRepo_CountDownload(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	file := &domain.File{
+		ID:           "once",
+		OriginalName: "secret.txt",
+		StoragePath:  "once.dat",
+		CreatedAt:    time.Now(),
+		MaxDownloads: 1,
+	}
+
+	if err := repo.Insert(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CountDownload(ctx, file.ID); err != nil {
+		t.Fatalf("First download failed: %v", err)
+	}
+
+	// второе скачивание и даже просмотр данных уже недоступны
+	if err := repo.CountDownload(ctx, file.ID); err != domain.ErrDownloadsExhausted {
+		t.Errorf("Expected ErrDownloadsExhausted, got %v", err)
+	}
+	if _, err := repo.Get(ctx, file.ID); err != domain.ErrDownloadsExhausted {
+		t.Errorf("Expected ErrDownloadsExhausted on Get, got %v", err)
+	}
+
+	if err := repo.CountDownload(ctx, "non-existent-id"); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// исчерпанный файл удаляется при очистке
+	keys, _, err := repo.ClearExpired(ctx, 0)
+	if err != nil {
+		t.Fatalf("ClearExpired failed: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != file.StoragePath {
+		t.Errorf("Expected [%s], got %v", file.StoragePath, keys)
+	}
+}
+
+func TestFileRepo_UpdateExpiry(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	file := &domain.File{
+		ID:           "short",
+		OriginalName: "a.txt",
+		StoragePath:  "short.dat",
+		CreatedAt:    time.Now(),
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+
+	if err := repo.Insert(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+
+	// уже истекшая ссылка перестает открываться
+	if err := repo.UpdateExpiry(ctx, file.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("UpdateExpiry failed: %v", err)
+	}
+	if _, err := repo.Get(ctx, file.ID); err != domain.ErrExpired {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+
+	// нулевое время - бессрочно
+	if err := repo.UpdateExpiry(ctx, file.ID, time.Time{}); err != nil {
+		t.Fatalf("UpdateExpiry failed: %v", err)
+	}
+	got, err := repo.Get(ctx, file.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !got.ExpiresAt.IsZero() {
+		t.Errorf("Expected no expiry, got %v", got.ExpiresAt)
+	}
+
+	if err := repo.UpdateExpiry(ctx, "non-existent-id", time.Time{}); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFileRepo_SharedBlob(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	newFile := func(id, key string, expires time.Time) *domain.File {
+		return &domain.File{
+			ID:           id,
+			OriginalName: "build.zip",
+			StoragePath:  key,
+			CreatedAt:    time.Now(),
+			ExpiresAt:    expires,
+			ContentHash:  "abc",
+		}
+	}
+
+	first := newFile("first", "first.dat", time.Now().Add(-time.Hour))
+	second := newFile("second", "second.dat", time.Time{})
+	third := newFile("third", "third.dat", time.Time{})
+
+	for _, f := range []*domain.File{first, second, third} {
+		if err := repo.Insert(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// все три записи ссылаются на объект первой загрузки
+	if second.StoragePath != "first.dat" || third.StoragePath != "first.dat" {
+		t.Errorf("Expected shared key first.dat, got %s and %s", second.StoragePath, third.StoragePath)
+	}
+
+	// при коллизии айди счетчик ссылок не меняется
+	dup := newFile("second", "dup.dat", time.Time{})
+	if err := repo.Insert(ctx, dup); err != domain.ErrConflict {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	if dup.StoragePath != "dup.dat" {
+		t.Errorf("Expected untouched key after conflict, got %s", dup.StoragePath)
+	}
+
+	// пока есть живые ссылки, объект не удаляется
+	keys, _, err := repo.ClearExpired(ctx, 0)
+	if err != nil {
+		t.Fatalf("ClearExpired failed: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Expected no keys while blob is shared, got %v", keys)
+	}
+
+	if keys, err = repo.Delete(ctx, second.ID); err != nil || len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v, %v", keys, err)
+	}
+
+	// последняя ссылка уносит с собой объект
+	keys, err = repo.Delete(ctx, third.ID)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "first.dat" {
+		t.Errorf("Expected [first.dat], got %v", keys)
+	}
+}
+
+func TestFileRepo_Integrity(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, f := range []*domain.File{
+		{ID: "a", StoragePath: "shared.dat", CreatedAt: time.Now(), ContentHash: "abc"},
+		{ID: "b", StoragePath: "other.dat", CreatedAt: time.Now(), ContentHash: "abc"},
+		{ID: "c", StoragePath: "legacy.dat", CreatedAt: time.Now()},
+	} {
+		if err := repo.Insert(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// общий объект возвращается один раз, постранично по ключу
+	blobs, err := repo.ListBlobs(ctx, "", 10)
+	if err != nil {
+		t.Fatalf("ListBlobs failed: %v", err)
+	}
+	want := []domain.Blob{{Key: "legacy.dat"}, {Key: "shared.dat", Hash: "abc"}}
+	if len(blobs) != len(want) || blobs[0] != want[0] || blobs[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, blobs)
+	}
+	if blobs, _ := repo.ListBlobs(ctx, "legacy.dat", 10); len(blobs) != 1 {
+		t.Errorf("Expected one blob after legacy.dat, got %v", blobs)
+	}
+
+	// метка ставится всем файлам объекта, и они больше не отдаются
+	if err := repo.MarkIntegrity(ctx, "shared.dat", domain.IntegrityCorrupted); err != nil {
+		t.Fatalf("MarkIntegrity failed: %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if _, err := repo.Get(ctx, id); err != domain.ErrCorrupted {
+			t.Errorf("Expected ErrCorrupted for %s, got %v", id, err)
+		}
+	}
+	if err := repo.CountDownload(ctx, "a"); err != domain.ErrCorrupted {
+		t.Errorf("Expected ErrCorrupted on download, got %v", err)
+	}
+
+	damaged, err := repo.ListDamaged(ctx)
+	if err != nil {
+		t.Fatalf("ListDamaged failed: %v", err)
+	}
+	if len(damaged) != 2 {
+		t.Errorf("Expected 2 damaged files, got %d", len(damaged))
+	}
+
+	// объект восстановили - следующая проверка снимает метку
+	if err := repo.MarkIntegrity(ctx, "shared.dat", domain.IntegrityOK); err != nil {
+		t.Fatalf("MarkIntegrity failed: %v", err)
+	}
+	if _, err := repo.Get(ctx, "a"); err != nil {
+		t.Errorf("Expected file to be served again, got %v", err)
+	}
+}
+
+func TestFileRepo_DamagedBlobReplaced(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	old := &domain.File{ID: "old", StoragePath: "bad.dat", CreatedAt: time.Now(), ContentHash: "abc"}
+	if err := repo.Insert(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.MarkIntegrity(ctx, "bad.dat", domain.IntegrityCorrupted); err != nil {
+		t.Fatalf("MarkIntegrity failed: %v", err)
+	}
+
+	// испорченный объект не переиспользуется: новая загрузка с тем же хешем занимает его место
+	fresh := &domain.File{ID: "fresh", StoragePath: "good.dat", CreatedAt: time.Now(), ContentHash: "abc"}
+	if err := repo.Insert(ctx, fresh); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if fresh.StoragePath != "good.dat" {
+		t.Errorf("Expected the fresh upload to keep its key, got %s", fresh.StoragePath)
+	}
+
+	// старая запись переезжает на хороший объект и снова отдается
+	for _, id := range []string{"old", "fresh"} {
+		f, err := repo.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("Get %s failed: %v", id, err)
+		}
+		if f.StoragePath != "good.dat" {
+			t.Errorf("Expected %s to point at good.dat, got %s", id, f.StoragePath)
+		}
+	}
+
+	// на испорченный объект больше никто не ссылается, его заберет сборка мусора
+	if ok, err := repo.Referenced(ctx, "bad.dat"); err != nil || ok {
+		t.Errorf("Expected bad.dat to be unreferenced, got %v, %v", ok, err)
+	}
+
+	// следующая загрузка снова дедуплицируется на хороший объект
+	next := &domain.File{ID: "next", StoragePath: "next.dat", CreatedAt: time.Now(), ContentHash: "abc"}
+	if err := repo.Insert(ctx, next); err != nil {
+		t.Fatal(err)
+	}
+	if next.StoragePath != "good.dat" {
+		t.Errorf("Expected shared key good.dat, got %s", next.StoragePath)
+	}
+
+	// объект освобождается только вместе с последней из трех ссылок
+	for _, id := range []string{"old", "fresh"} {
+		if keys, err := repo.Delete(ctx, id); err != nil || len(keys) != 0 {
+			t.Errorf("Expected no keys for %s, got %v, %v", id, keys, err)
+		}
+	}
+	if keys, err := repo.Delete(ctx, "next"); err != nil || len(keys) != 1 || keys[0] != "good.dat" {
+		t.Errorf("Expected [good.dat], got %v, %v", keys, err)
+	}
+}
+
+func TestFileRepo_References(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, f := range []*domain.File{
+		{ID: "a", StoragePath: "shared.dat", CreatedAt: time.Now(), ContentHash: "abc"},
+		{ID: "b", StoragePath: "other.dat", CreatedAt: time.Now(), ContentHash: "abc"},
+		{ID: "c", StoragePath: "data/storage/legacy_1.dat", CreatedAt: time.Now()},
+	} {
+		if err := repo.Insert(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := repo.ListByKey(ctx, "shared.dat")
+	if err != nil {
+		t.Fatalf("ListByKey failed: %v", err)
+	}
+	if len(files) != 2 || files[0].ID != "a" || files[1].ID != "b" {
+		t.Errorf("Expected files a and b, got %v", files)
+	}
+
+	// старые записи хранят полный путь, а хранилище отдает только имя объекта.
+	// подчеркивание в ключе не должно работать как шаблон LIKE
+	cases := map[string]bool{
+		"shared.dat":    true,
+		"legacy_1.dat":  true,
+		"legacyx1.dat":  false,
+		"other.dat":     false,
+		"unknown.dat":   false,
+		"storage/a.dat": false,
+	}
+	for key, want := range cases {
+		got, err := repo.Referenced(ctx, key)
+		if err != nil {
+			t.Fatalf("Referenced(%s) failed: %v", key, err)
+		}
+		if got != want {
+			t.Errorf("Referenced(%s): expected %v, got %v", key, want, got)
+		}
+	}
+}
+
+func TestFileRepo_Collection(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, f := range []*domain.File{
+		{ID: "a", StoragePath: "a.dat", CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)},
+		{ID: "b", StoragePath: "b.dat", CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)},
+		{ID: "c", StoragePath: "c.dat", CreatedAt: time.Now()},
+	} {
+		if err := repo.Insert(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expires := time.Now().Add(2 * time.Hour).UTC()
+	c := &domain.Collection{ID: "set", CreatedAt: time.Now(), ExpiresAt: expires, TokenHash: "hash", Owner: "alice", KeyID: "k1"}
+	if err := repo.InsertCollection(ctx, c, []string{"a", "b"}); err != nil {
+		t.Fatalf("InsertCollection failed: %v", err)
+	}
+
+	// файлы и наборы делят пространство имен
+	if err := repo.InsertCollection(ctx, &domain.Collection{ID: "c"}, []string{"c"}); err != domain.ErrConflict {
+		t.Errorf("Expected ErrConflict for a file id, got %v", err)
+	}
+	if err := repo.Insert(ctx, &domain.File{ID: "set", StoragePath: "x.dat"}); err != domain.ErrConflict {
+		t.Errorf("Expected ErrConflict for a collection id, got %v", err)
+	}
+
+	// файл уже в наборе - второй раз его не добавить, и набор не создается
+	if err := repo.InsertCollection(ctx, &domain.Collection{ID: "other"}, []string{"c", "a"}); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a taken file, got %v", err)
+	}
+	if _, err := repo.LookupCollection(ctx, "other"); err != domain.ErrNotFound {
+		t.Errorf("Failed collection should not be stored, got %v", err)
+	}
+
+	got, err := repo.LookupCollection(ctx, "set")
+	if err != nil {
+		t.Fatalf("LookupCollection failed: %v", err)
+	}
+	if len(got.Files) != 2 || got.Files[0].ID != "a" || got.Files[1].ID != "b" {
+		t.Fatalf("Expected files a and b, got %v", got.Files)
+	}
+	if got.Owner != "alice" || got.KeyID != "k1" {
+		t.Errorf("Expected owner alice with key k1, got %q with %q", got.Owner, got.KeyID)
+	}
+	if !got.Files[0].ExpiresAt.Equal(expires) || got.Files[0].CollectionID != "set" {
+		t.Errorf("Expected file to take collection expiry, got %v in %q", got.Files[0].ExpiresAt, got.Files[0].CollectionID)
+	}
+
+	// срок набора меняется вместе со сроком его файлов
+	expires = time.Now().Add(-time.Minute).UTC()
+	if err := repo.UpdateCollectionExpiry(ctx, "set", expires); err != nil {
+		t.Fatalf("UpdateCollectionExpiry failed: %v", err)
+	}
+	if f, _ := repo.Lookup(ctx, "b"); !f.ExpiresAt.Equal(expires) {
+		t.Errorf("Expected file expiry %v, got %v", expires, f.ExpiresAt)
+	}
+
+	keys, _, err := repo.ClearExpired(ctx, 0)
+	if err != nil {
+		t.Fatalf("ClearExpired failed: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("Expected keys of both files, got %v", keys)
+	}
+	if _, err := repo.LookupCollection(ctx, "set"); err != domain.ErrNotFound {
+		t.Errorf("Expected expired collection to be removed, got %v", err)
+	}
+
+	// удаление набора уносит и его файлы
+	if err := repo.InsertCollection(ctx, &domain.Collection{ID: "keep"}, []string{"c"}); err != nil {
+		t.Fatal(err)
+	}
+	keys, err = repo.DeleteCollection(ctx, "keep")
+	if err != nil || len(keys) != 1 || keys[0] != "c.dat" {
+		t.Errorf("Expected [c.dat], got %v, %v", keys, err)
+	}
+	if _, err := repo.Lookup(ctx, "c"); err != domain.ErrNotFound {
+		t.Errorf("Expected collection file to be deleted, got %v", err)
+	}
+}
+
+func TestFileRepo_APIKeys(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	k := &domain.APIKey{
+		ID:           "key1",
+		Name:         "ci",
+		KeyHash:      "hash1",
+		CreatedAt:    time.Now(),
+		MaxSize:      1 << 20,
+		MaxTTL:       time.Hour,
+		ContentTypes: []string{"image/*", "text/plain"},
+		Tenant:       "sales",
+	}
+	if err := repo.InsertKey(ctx, k); err != nil {
+		t.Fatalf("InsertKey failed: %v", err)
+	}
+	if err := repo.InsertKey(ctx, &domain.APIKey{ID: "key2", KeyHash: "hash1"}); err != domain.ErrConflict {
+		t.Errorf("Expected ErrConflict for a duplicate hash, got %v", err)
+	}
+	if err := repo.InsertKey(ctx, &domain.APIKey{ID: "key2", KeyHash: "hash2"}); err != nil {
+		t.Fatalf("InsertKey without limits failed: %v", err)
+	}
+
+	got, err := repo.KeyByHash(ctx, "hash1")
+	if err != nil {
+		t.Fatalf("KeyByHash failed: %v", err)
+	}
+	if got.ID != "key1" || got.MaxSize != k.MaxSize || got.MaxTTL != time.Hour ||
+		len(got.ContentTypes) != 2 || got.ContentTypes[0] != "image/*" || got.Tenant != "sales" {
+		t.Errorf("Key limits were not stored: %+v", got)
+	}
+
+	// пустой список типов читается как nil, а не как один пустой тип
+	if got, err := repo.GetKey(ctx, "key2"); err != nil || got.ContentTypes != nil {
+		t.Errorf("Expected key without content types, got %+v, %v", got, err)
+	}
+
+	keys, err := repo.ListKeys(ctx)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d, %v", len(keys), err)
+	}
+
+	if err := repo.DeleteKey(ctx, "key1"); err != nil {
+		t.Fatalf("DeleteKey failed: %v", err)
+	}
+	if _, err := repo.KeyByHash(ctx, "hash1"); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a revoked key, got %v", err)
+	}
+	if err := repo.DeleteKey(ctx, "key1"); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a second delete, got %v", err)
+	}
+}
+
+func TestFileRepo_ListFiles(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	for _, f := range []*domain.File{
+		{ID: "a", OriginalName: "report.pdf", ContentType: "application/pdf", ExpiresAt: now.Add(time.Hour), Owner: "alice"},
+		{ID: "b", OriginalName: "photo.png", ContentType: "image/png", ExpiresAt: now.Add(48 * time.Hour), Owner: "alice"},
+		{ID: "c", OriginalName: "report_2.txt", ContentType: "text/plain; charset=utf-8", Owner: "alice"},
+		{ID: "d", OriginalName: "old.txt", ContentType: "text/plain", ExpiresAt: now.Add(-time.Hour), Owner: "alice"},
+		{ID: "e", OriginalName: "used.txt", ContentType: "text/plain", MaxDownloads: 1, Downloads: 1, Owner: "alice"},
+		{ID: "f", OriginalName: "report.pdf", ContentType: "application/pdf", Owner: "bob"},
+	} {
+		f.StoragePath, f.CreatedAt = f.ID+".dat", now
+		if err := repo.Insert(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(filter domain.FileFilter) string {
+		t.Helper()
+		if filter.Limit == 0 {
+			filter.Limit = 10
+		}
+
+		files, next, err := repo.ListFiles(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListFiles(%+v) failed: %v", filter, err)
+		}
+		if next != "" {
+			t.Errorf("ListFiles(%+v): unexpected next page %q", filter, next)
+		}
+
+		var got []string
+		for _, f := range files {
+			got = append(got, f.ID)
+		}
+		return fmt.Sprint(got)
+	}
+
+	// истекшие и исчерпанные не показываются, новые - первыми
+	tests := []struct {
+		filter domain.FileFilter
+		want   string
+	}{
+		{domain.FileFilter{Owner: "alice"}, "[c b a]"},
+		{domain.FileFilter{Owner: "alice", NamePrefix: "report_"}, "[c]"},
+		{domain.FileFilter{Owner: "alice", NamePrefix: "REPORT"}, "[c a]"},
+		{domain.FileFilter{Owner: "alice", ContentType: "text/plain"}, "[c]"},
+		{domain.FileFilter{Owner: "alice", ContentType: "image/*"}, "[b]"},
+		{domain.FileFilter{Owner: "alice", ExpiresBefore: now.Add(2 * time.Hour)}, "[a]"},
+		{domain.FileFilter{Owner: "alice", ExpiresAfter: now.Add(2 * time.Hour)}, "[c b]"},
+		{domain.FileFilter{NamePrefix: "report.pdf"}, "[f a]"},
+	}
+	for _, tt := range tests {
+		if got := ids(tt.filter); got != tt.want {
+			t.Errorf("ListFiles(%+v) = %s, want %s", tt.filter, got, tt.want)
+		}
+	}
+
+	// постранично выходит то же самое
+	var pages []string
+	filter := domain.FileFilter{Owner: "alice", Limit: 2}
+	for {
+		files, next, err := repo.ListFiles(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListFiles failed: %v", err)
+		}
+		for _, f := range files {
+			pages = append(pages, f.ID)
+		}
+		if next == "" {
+			break
+		}
+		filter.Cursor = next
+	}
+	if fmt.Sprint(pages) != "[c b a]" {
+		t.Errorf("Paged listing = %v, want [c b a]", pages)
+	}
+
+	if _, _, err := repo.ListFiles(ctx, domain.FileFilter{Cursor: "x", Limit: 1}); err != domain.ErrInvalidFilter {
+		t.Errorf("Expected ErrInvalidFilter for a bad cursor, got %v", err)
+	}
+}
+
+func TestFileRepo_Quotas(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, f := range []*domain.File{
+		{ID: "a", Size: 100, ExpiresAt: time.Now().Add(time.Hour), Owner: "alice"},
+		{ID: "b", Size: 50, ExpiresAt: time.Now().Add(-time.Hour), Owner: "alice"},
+		{ID: "c", Size: 1000, Owner: "bob"},
+	} {
+		f.StoragePath, f.CreatedAt = f.ID+".dat", time.Now()
+		if err := repo.Insert(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// истекший файл занимает место, пока его не удалит очистка
+	if u, err := repo.Usage(ctx, "alice"); err != nil || u != (domain.Usage{Bytes: 150, Files: 2}) {
+		t.Errorf("Usage before cleanup = %+v, %v", u, err)
+	}
+	if _, _, err := repo.ClearExpired(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := repo.Usage(ctx, "alice"); err != nil || u != (domain.Usage{Bytes: 100, Files: 1}) {
+		t.Errorf("Usage after cleanup = %+v, %v", u, err)
+	}
+	if u, err := repo.Usage(ctx, "nobody"); err != nil || u != (domain.Usage{}) {
+		t.Errorf("Usage of an unknown owner = %+v, %v", u, err)
+	}
+
+	if _, err := repo.GetQuota(ctx, "alice"); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound without a quota, got %v", err)
+	}
+	for _, q := range []domain.Quota{{MaxBytes: 10}, {MaxBytes: 20, MaxFiles: 3}} {
+		if err := repo.SetQuota(ctx, "alice", q); err != nil {
+			t.Fatalf("SetQuota failed: %v", err)
+		}
+	}
+	if q, err := repo.GetQuota(ctx, "alice"); err != nil || *q != (domain.Quota{MaxBytes: 20, MaxFiles: 3}) {
+		t.Errorf("GetQuota = %+v, %v", q, err)
+	}
+
+	if err := repo.DeleteQuota(ctx, "alice"); err != nil {
+		t.Fatalf("DeleteQuota failed: %v", err)
+	}
+	if err := repo.DeleteQuota(ctx, "alice"); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a second delete, got %v", err)
+	}
+}
+
+func TestFileRepo_TenantQuotas(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	sales := domain.WithTenant(context.Background(), "sales")
+	hr := domain.WithTenant(context.Background(), "hr")
+
+	// один и тот же subject в двух арендаторах - разные владельцы
+	for _, tc := range []struct {
+		ctx  context.Context
+		id   string
+		size int64
+	}{{sales, "a", 100}, {sales, "b", 50}, {hr, "c", 1000}} {
+		f := &domain.File{ID: tc.id, StoragePath: tc.id + ".dat", Size: tc.size, Owner: "alice", CreatedAt: time.Now()}
+		if err := repo.Insert(tc.ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if u, err := repo.Usage(sales, "alice"); err != nil || u != (domain.Usage{Bytes: 150, Files: 2}) {
+		t.Errorf("Usage in sales = %+v, %v", u, err)
+	}
+	if u, err := repo.Usage(hr, "alice"); err != nil || u != (domain.Usage{Bytes: 1000, Files: 1}) {
+		t.Errorf("Usage in hr = %+v, %v", u, err)
+	}
+	if u, err := repo.Usage(context.Background(), "alice"); err != nil || u != (domain.Usage{}) {
+		t.Errorf("Usage in the default tenant = %+v, %v", u, err)
+	}
+
+	if err := repo.SetQuota(sales, "alice", domain.Quota{MaxBytes: 10}); err != nil {
+		t.Fatalf("SetQuota in sales failed: %v", err)
+	}
+	if err := repo.SetQuota(hr, "alice", domain.Quota{MaxFiles: 3}); err != nil {
+		t.Fatalf("SetQuota in hr failed: %v", err)
+	}
+	if q, err := repo.GetQuota(sales, "alice"); err != nil || *q != (domain.Quota{MaxBytes: 10}) {
+		t.Errorf("GetQuota in sales = %+v, %v", q, err)
+	}
+	if _, err := repo.GetQuota(context.Background(), "alice"); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound in the default tenant, got %v", err)
+	}
+
+	// сброс квоты в одном арендаторе не трогает другой
+	if err := repo.DeleteQuota(sales, "alice"); err != nil {
+		t.Fatalf("DeleteQuota failed: %v", err)
+	}
+	if q, err := repo.GetQuota(hr, "alice"); err != nil || *q != (domain.Quota{MaxFiles: 3}) {
+		t.Errorf("GetQuota in hr after reset in sales = %+v, %v", q, err)
+	}
+}
+
+func TestFileRepo_Tenants(t *testing.T) {
+	repo, _, cleanup := setupDB(t)
+	defer cleanup()
+
+	sales := domain.WithTenant(context.Background(), "sales")
+	hr := domain.WithTenant(context.Background(), "hr")
+
+	// одно и то же короткое имя в двух пространствах - разные файлы
+	for _, tc := range []struct {
+		ctx  context.Context
+		name string
+		size int64
+	}{{sales, "q3.pdf", 10}, {hr, "salaries.xlsx", 20}} {
+		f := &domain.File{ID: "abc", OriginalName: tc.name, StoragePath: tc.name, Size: tc.size, CreatedAt: time.Now()}
+		if err := repo.Insert(tc.ctx, f); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	if err := repo.Insert(sales, &domain.File{ID: "abc", StoragePath: "x", CreatedAt: time.Now()}); err != domain.ErrConflict {
+		t.Errorf("Expected ErrConflict inside one tenant, got %v", err)
+	}
+
+	f, err := repo.Get(hr, "abc")
+	if err != nil || f.OriginalName != "salaries.xlsx" || f.Tenant != "hr" {
+		t.Errorf("Get in hr = %+v, %v", f, err)
+	}
+	if _, err := repo.Get(context.Background(), "abc"); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound in the default tenant, got %v", err)
+	}
+
+	files, _, err := repo.ListFiles(sales, domain.FileFilter{Limit: 10})
+	if err != nil || len(files) != 1 || files[0].OriginalName != "q3.pdf" {
+		t.Errorf("ListFiles in sales = %v, %v", files, err)
+	}
+	if u, err := repo.TenantUsage(context.Background(), "hr"); err != nil || u != (domain.Usage{Bytes: 20, Files: 1}) {
+		t.Errorf("TenantUsage = %+v, %v", u, err)
+	}
+
+	if _, err := repo.Delete(sales, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(hr, "abc"); err != nil {
+		t.Errorf("Deleting in sales removed the hr file: %v", err)
+	}
+
+	c := &domain.Collection{ID: "set", CreatedAt: time.Now()}
+	if err := repo.InsertCollection(hr, c, []string{"abc"}); err != nil {
+		t.Fatalf("InsertCollection failed: %v", err)
+	}
+	if _, err := repo.LookupCollection(sales, "set"); err != domain.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for another tenant's collection, got %v", err)
+	}
+	if got, err := repo.LookupCollection(hr, "set"); err != nil || len(got.Files) != 1 {
+		t.Errorf("LookupCollection in hr = %+v, %v", got, err)
+	}
+}
+
+// старые записи после миграции остаются в пространстве по умолчанию
+func TestFileRepo_TenantMigration(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE files (id TEXT PRIMARY KEY, original_name TEXT NOT NULL, storage_path TEXT NOT NULL,
+		size_bytes INTEGER, content_type TEXT, created_at DATETIME, expired_at TIMESTAMP);
+		INSERT INTO files VALUES ('old', 'a.txt', 'old.dat', 3, 'text/plain', '2024-01-01 00:00:00', NULL);`); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := NewFileRepo(db)
+	if err != nil {
+		t.Fatalf("NewFileRepo failed: %v", err)
+	}
+
+	f, err := repo.Get(context.Background(), "old")
+	if err != nil || f.OriginalName != "a.txt" || f.Tenant != "" {
+		t.Errorf("Get after migration = %+v, %v", f, err)
+	}
+}
+
+func TestFileRepo_LocalTimeMigration(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// старые записи хранили время в местном поясе сервера, как его пишет драйвер
+	const layout = "2006-01-02 15:04:05.999999999-07:00"
+	west, east := time.FixedZone("", -5*60*60), time.FixedZone("", 9*60*60)
+	now := time.Now().Truncate(time.Millisecond)
+	live := now.Add(2 * time.Hour)
+	gone := now.Add(-2 * time.Hour)
+
+	if _, err := db.Exec(`CREATE TABLE files (id TEXT PRIMARY KEY, original_name TEXT NOT NULL, storage_path TEXT NOT NULL,
+		size_bytes INTEGER, content_type TEXT, created_at DATETIME, expired_at TIMESTAMP);
+		INSERT INTO files VALUES ('live', 'a.txt', 'live.dat', 3, 'text/plain', ?, ?);
+		INSERT INTO files VALUES ('gone', 'b.txt', 'gone.dat', 3, 'text/plain', ?, ?);`,
+		now.In(west).Format(layout), live.In(west).Format(layout),
+		now.In(east).Format(layout), gone.In(east).Format(layout)); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := NewFileRepo(db)
+	if err != nil {
+		t.Fatalf("NewFileRepo failed: %v", err)
+	}
+	ctx := context.Background()
+
+	// без перевода в UTC строка "-05:00" казалась бы уже истекшей, а "+09:00" - еще живой
+	exp, ok, err := repo.NextExpiry(ctx)
+	if err != nil || !ok || !exp.Equal(gone) {
+		t.Errorf("Expected next expiry %v, got %v, %v, %v", gone, exp, ok, err)
+	}
+
+	keys, deleted, err := repo.ClearExpired(ctx, 0)
+	if err != nil {
+		t.Fatalf("ClearExpired failed: %v", err)
+	}
+	if deleted != 1 || len(keys) != 1 || keys[0] != "gone.dat" {
+		t.Errorf("Expected only gone.dat to be cleared, got %v (%d)", keys, deleted)
+	}
+
+	f, err := repo.Get(ctx, "live")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !f.ExpiresAt.Equal(live) || !f.CreatedAt.Equal(now) {
+		t.Errorf("Expected times %v and %v, got %v and %v", now, live, f.CreatedAt, f.ExpiresAt)
+	}
+}
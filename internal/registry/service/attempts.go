@@ -0,0 +1,97 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// счетчик неудачных попыток ввода пароля. после limit ошибок ключ блокируется на lockout даже для
+// верного пароля, так что перебор идет не быстрее нескольких попыток за период. ошибки старше
+// lockout забываются. сервис считает попытки и по паре файл-клиент, и по файлу целиком с большим
+// пределом: посторонний своими ошибками блокирует ссылку только себе, а смена адреса не дает
+// перебирать пароль быстрее общего предела
+type attemptLimiter struct {
+	mu       sync.Mutex
+	failures map[string]*list.Element // значения - *attempts
+	recent   list.List                // от последней ошибки к самой давней
+}
+
+type attempts struct {
+	key         string
+	count       int
+	last        time.Time // последняя ошибка
+	lockedUntil time.Time
+}
+
+const (
+	maxAttempts     = 5  // ошибок одного клиента
+	maxFileAttempts = 50 // ошибок всех клиентов вместе
+	lockout         = 15 * time.Minute
+)
+
+// больше записей в памяти не держится: место уступает та, где ошибка была давнее всего.
+// переменная, а не константа, чтобы тесты не заводили сотню тысяч записей
+var maxTrackedID = 100000
+
+// можно ли сейчас проверять пароль по ключу
+func (l *attemptLimiter) allowed(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.failures[key]
+	if !ok {
+		return true
+	}
+
+	return !now.Before(e.Value.(*attempts).lockedUntil)
+}
+
+// записать неудачную попытку, на limit-й ключ блокируется
+func (l *attemptLimiter) fail(key string, limit int, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.failures == nil {
+		l.failures = make(map[string]*list.Element)
+	}
+
+	// записи с конца очереди, где последняя ошибка давнее lockout, ничего уже не блокируют и не считают
+	for e := l.recent.Back(); e != nil && now.Sub(e.Value.(*attempts).last) > lockout; e = l.recent.Back() {
+		l.remove(e)
+	}
+
+	e, ok := l.failures[key]
+	if ok {
+		l.recent.MoveToFront(e)
+	} else {
+		if len(l.failures) >= maxTrackedID {
+			l.remove(l.recent.Back())
+		}
+
+		e = l.recent.PushFront(&attempts{key: key})
+		l.failures[key] = e
+	}
+
+	a := e.Value.(*attempts)
+	a.count++
+	a.last = now
+	if a.count >= limit {
+		a.lockedUntil = now.Add(lockout)
+	}
+}
+
+// верный пароль сбрасывает счетчик
+func (l *attemptLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.failures[key]; ok {
+		l.remove(e)
+	}
+}
+
+func (l *attemptLimiter) remove(e *list.Element) {
+	delete(l.failures, e.Value.(*attempts).key)
+	l.recent.Remove(e)
+}
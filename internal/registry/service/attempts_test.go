@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAttemptLimiter_Lockout(t *testing.T) {
+	var l attemptLimiter
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if !l.allowed("a", now) {
+			t.Fatalf("Expected attempt %d to be allowed", i)
+		}
+		l.fail("a", 3, now)
+	}
+
+	if l.allowed("a", now) {
+		t.Error("Expected a to be locked after 3 failures")
+	}
+	if !l.allowed("b", now) {
+		t.Error("Expected other keys to stay allowed")
+	}
+
+	// блокировка снимается через lockout
+	if l.allowed("a", now.Add(lockout-time.Second)) {
+		t.Error("Expected a to stay locked before lockout ends")
+	}
+	if !l.allowed("a", now.Add(lockout)) {
+		t.Error("Expected a to be allowed after lockout")
+	}
+}
+
+func TestAttemptLimiter_Reset(t *testing.T) {
+	var l attemptLimiter
+	now := time.Now()
+
+	l.fail("a", 3, now)
+	l.fail("a", 3, now)
+	l.reset("a")
+
+	// после сброса счет идет заново
+	l.fail("a", 3, now)
+	l.fail("a", 3, now)
+	if !l.allowed("a", now) {
+		t.Error("Expected reset to clear failures")
+	}
+
+	l.fail("a", 3, now)
+	l.reset("a")
+	if !l.allowed("a", now) {
+		t.Error("Expected reset to lift the lock")
+	}
+}
+
+func TestAttemptLimiter_Eviction(t *testing.T) {
+	defer func(n int) { maxTrackedID = n }(maxTrackedID)
+	maxTrackedID = 3
+
+	var l attemptLimiter
+	now := time.Now()
+
+	// записей больше предела не бывает, место уступает самая давняя
+	for i := 0; i < 5; i++ {
+		l.fail(strconv.Itoa(i), 1, now)
+	}
+	if len(l.failures) != 3 || l.recent.Len() != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(l.failures))
+	}
+	for _, key := range []string{"0", "1"} {
+		if !l.allowed(key, now) {
+			t.Errorf("Expected %s to be evicted", key)
+		}
+	}
+	if l.allowed("4", now) {
+		t.Error("Expected the latest entry to be kept")
+	}
+
+	// старые ошибки забываются и без нехватки места
+	l.fail("5", 1, now.Add(2*lockout))
+	if len(l.failures) != 1 {
+		t.Errorf("Expected stale entries to be swept, got %d", len(l.failures))
+	}
+}
+
+func TestFileService_PasswordAttempts(t *testing.T) {
+	s := setupService(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := string(hash)
+
+	alice := domain.WithClient(context.Background(), "1.2.3.4")
+	bob := domain.WithClient(context.Background(), "5.6.7.8")
+
+	for i := 0; i < maxAttempts; i++ {
+		if err := s.checkPassword(alice, "f1", h, "wrong"); !errors.Is(err, domain.ErrWrongPassword) {
+			t.Fatalf("Expected ErrWrongPassword, got %v", err)
+		}
+	}
+
+	// перебирающий блокирует ссылку только себе
+	if err := s.checkPassword(alice, "f1", h, "secret"); !errors.Is(err, domain.ErrTooManyAttempts) {
+		t.Errorf("Expected ErrTooManyAttempts for the guesser, got %v", err)
+	}
+	if err := s.checkPassword(bob, "f1", h, "secret"); err != nil {
+		t.Errorf("Expected another client to open the file, got %v", err)
+	}
+	if err := s.checkPassword(alice, "f2", h, "secret"); err != nil {
+		t.Errorf("Expected another file to stay open, got %v", err)
+	}
+
+	// но сменой адресов нельзя перебирать быстрее общего предела на файл
+	for i := 0; i < maxFileAttempts; i++ {
+		ctx := domain.WithClient(context.Background(), "10.0.0."+strconv.Itoa(i))
+		s.checkPassword(ctx, "f3", h, "wrong")
+	}
+	if err := s.checkPassword(bob, "f3", h, "secret"); !errors.Is(err, domain.ErrTooManyAttempts) {
+		t.Errorf("Expected the file to be locked for everyone, got %v", err)
+	}
+}
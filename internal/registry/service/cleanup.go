@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+const cleanupBatch = 500 // сколько записей удаляется за одну транзакцию, чтобы не держать бд подолгу
+
+// расписание очистки. очистка спит до ближайшего срока истечения, а загрузка или продление
+// со сроком раньше запланированного будят ее заранее
+type cleanupState struct {
+	wake chan struct{}
+	next atomic.Int64 // unix-время следующего пробуждения в наносекундах, 0 - срок пересчитывается
+}
+
+// разбудить очистку, если ссылка истекает раньше, чем та собиралась проснуться
+func (s *FileService) reschedule(expires time.Time) {
+	if expires.IsZero() {
+		return
+	}
+
+	if next := s.cleanup.next.Load(); next != 0 && expires.UnixNano() >= next {
+		return
+	}
+
+	select {
+	case s.cleanup.wake <- struct{}{}:
+	default:
+	}
+}
+
+// удалить истекшие записи из бд пачками, а затем их содержимое из хранилища
+func (s *FileService) clearExpired(ctx context.Context) error {
+	total := 0
+
+	for {
+		keys, n, err := s.Repo.ClearExpired(ctx, cleanupBatch)
+		if err != nil {
+			return err
+		}
+
+		// если удалить объект не вышло, просто пропускаем его, не прерывая цикл
+		for _, key := range keys {
+			s.deleteBlob(ctx, key)
+		}
+
+		total += n
+		if n < cleanupBatch {
+			break
+		}
+	}
+
+	if total > 0 {
+		s.Logger.Info("cleared expired files", "count", total)
+	}
+	return nil
+}
+
+// сколько спать до следующей очистки: до ближайшего срока, но не дольше CleanupInterval.
+// записи, исчерпавшие лимит скачиваний, срока не имеют и удаляются на таких плановых пробуждениях
+func (s *FileService) nextCleanup(ctx context.Context) time.Duration {
+	wait := s.CleanupInterval
+	if wait <= 0 {
+		wait = defaultCleanup
+	}
+
+	next, ok, err := s.Repo.NextExpiry(ctx)
+	if err != nil {
+		s.Logger.Error("error getting next expiry", "error", err)
+		return wait
+	}
+
+	if ok {
+		wait = max(min(wait, time.Until(next)), 0)
+	}
+
+	return wait
+}
+
+// запуск очищения диска и бд от просроченных записей
+func (s *FileService) StartCleanup(ctx context.Context) {
+	s.Logger.Info("starting cleanup worker")
+
+	go func() {
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for {
+			if err := s.clearExpired(ctx); err != nil {
+				s.Logger.Error("cleanup failed", "error", err)
+			}
+
+			// пока срок пересчитывается, любая новая загрузка будит очистку: иначе ее срок
+			// мог не попасть в запрос и потеряться
+			s.cleanup.next.Store(0)
+			wait := s.nextCleanup(ctx)
+			s.cleanup.next.Store(time.Now().Add(wait).UnixNano())
+
+			timer.Reset(wait)
+
+			select {
+			case <-timer.C:
+			case <-s.cleanup.wake:
+				timer.Stop()
+			case <-ctx.Done():
+				s.Logger.Info("cleanup stopped due to cancelled context", "", ctx.Err())
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const maxMembers = 1000 // больше файлов в одном наборе - скорее ошибка клиента, чем релиз
+
+// собрать уже загруженные файлы в набор под одной ссылкой. права на каждый файл подтверждаются
+// его токеном владельца, а срок набора становится сроком всех его файлов
+func (s *FileService) CreateCollection(ctx context.Context, meta domain.CollectionMeta) (*domain.UploadResult, error) {
+	if len(meta.Members) == 0 || len(meta.Members) > maxMembers {
+		return nil, domain.ErrInvalidCollection
+	}
+
+	apiKey, err := s.uploadKey(ctx, meta.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	created := time.Now()
+	expires, err := s.expiresAt(ctx, created, created, meta.TTL)
+	if err != nil {
+		return nil, err
+	}
+	if expires, err = keyExpiry(apiKey, created, expires, meta.TTL); err != nil {
+		return nil, err
+	}
+
+	if meta.ShortName != "" {
+		if err := validateName(meta.ShortName, meta.KeyID == "" && meta.Owner == ""); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]string, 0, len(meta.Members))
+	seen := make(map[string]bool, len(meta.Members))
+	for _, m := range meta.Members {
+		if seen[m.ID] {
+			return nil, domain.ErrInvalidCollection
+		}
+		seen[m.ID] = true
+
+		file, err := s.Repo.Lookup(ctx, m.ID)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return nil, err
+			}
+
+			s.Logger.Error("error creating a collection", "error", err)
+			return nil, domain.ErrInRepo
+		}
+
+		if !tokenMatches(file.TokenHash, m.Token) {
+			return nil, domain.ErrWrongToken
+		}
+		if file.CollectionID != "" {
+			return nil, domain.ErrInvalidCollection
+		}
+
+		ids = append(ids, m.ID)
+	}
+
+	var passwordHash string
+	if meta.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(meta.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, domain.ErrInvalidPassword
+		}
+		passwordHash = string(hash)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		s.Logger.Error("error generating a token", "error", err)
+		return nil, domain.ErrInService
+	}
+
+	c := domain.Collection{
+		CreatedAt:    created,
+		ExpiresAt:    expires,
+		PasswordHash: passwordHash,
+		TokenHash:    hashToken(token),
+		Owner:        meta.Owner,
+		KeyID:        meta.KeyID,
+	}
+
+	if meta.ShortName != "" {
+		c.ID = meta.ShortName
+		err = s.Repo.InsertCollection(ctx, &c, ids)
+	} else {
+		err = s.insertWithId(func(id string) error {
+			c.ID = id
+			return s.Repo.InsertCollection(ctx, &c, ids)
+		})
+	}
+
+	if err != nil {
+		// имя заняли или файл удалили, пока собирался набор
+		if (errors.Is(err, domain.ErrConflict) && meta.ShortName != "") || errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
+
+		s.Logger.Error("error creating a collection", "error", err)
+		return nil, domain.ErrInRepo
+	}
+
+	s.reschedule(expires)
+	s.Logger.Info("created collection: "+c.ID, "files", len(ids))
+	return &domain.UploadResult{ID: c.ID, DeleteToken: token}, nil
+}
+
+// набор по короткому имени вместе с файлами. для защищенного набора нужен его пароль
+func (s *FileService) GetCollection(ctx context.Context, id, password string) (*domain.Collection, error) {
+	c, err := s.Repo.LookupCollection(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
+
+		s.Logger.Error("error getting a collection", "error", err)
+		return nil, domain.ErrInRepo
+	}
+
+	if !c.ExpiresAt.IsZero() && time.Now().After(c.ExpiresAt) {
+		return nil, domain.ErrExpired
+	}
+
+	if err := s.checkPassword(ctx, domain.QualifiedID(c.Tenant, c.ID), c.PasswordHash, password); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// удалить набор и все его файлы по токену владельца набора
+func (s *FileService) deleteCollection(ctx context.Context, id, token string) error {
+	c, err := s.Repo.LookupCollection(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+
+		s.Logger.Error("error deleting a collection", "error", err)
+		return domain.ErrInRepo
+	}
+
+	if !tokenMatches(c.TokenHash, token) {
+		return domain.ErrWrongToken
+	}
+
+	keys, err := s.Repo.DeleteCollection(ctx, id)
+	if err != nil {
+		s.Logger.Error("error deleting a collection", "error", err)
+		return domain.ErrInRepo
+	}
+
+	for _, key := range keys {
+		s.deleteBlob(ctx, key)
+	}
+	s.Logger.Info("deleted collection by owner: " + id)
+	return nil
+}
+
+// продлить или сократить срок набора, файлы набора получают тот же срок
+func (s *FileService) updateCollectionExpiry(ctx context.Context, id, token string, ttl time.Duration) (time.Time, error) {
+	c, err := s.Repo.LookupCollection(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return time.Time{}, err
+		}
+
+		s.Logger.Error("error updating expiry", "error", err)
+		return time.Time{}, domain.ErrInRepo
+	}
+
+	if !tokenMatches(c.TokenHash, token) {
+		return time.Time{}, domain.ErrWrongToken
+	}
+
+	now := time.Now()
+	if !c.ExpiresAt.IsZero() && now.After(c.ExpiresAt) {
+		return time.Time{}, domain.ErrExpired
+	}
+
+	expires, err := s.expiresAt(ctx, c.CreatedAt, now, ttl)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if expires, err = s.keyUpdateExpiry(ctx, c.KeyID, c.CreatedAt, c.ExpiresAt, expires, ttl); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.Repo.UpdateCollectionExpiry(ctx, id, expires); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return time.Time{}, err
+		}
+
+		s.Logger.Error("error updating expiry", "error", err)
+		return time.Time{}, domain.ErrInRepo
+	}
+
+	s.reschedule(expires)
+	s.Logger.Info("updated expiry of a collection: " + id)
+	return expires, nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+const gcBatch = 100 // сколько объектов берется из хранилища или бд за раз
+
+// сборка мусора: временные файлы, которые никто не дозагрузил, объекты хранилища без записей
+// (реестр упал между записью объекта и вставкой в бд) и записи, содержимое которых пропало.
+// свежие файлы и объекты не трогаются: они могут принадлежать загрузке, которая идет прямо сейчас
+func (s *FileService) CollectGarbage(ctx context.Context, dryRun bool, minAge time.Duration) (*domain.GCReport, error) {
+	s.gcRunning.Lock()
+	defer s.gcRunning.Unlock()
+
+	if minAge <= 0 {
+		minAge = s.GCMinAge
+	}
+
+	report := domain.GCReport{StartedAt: time.Now(), DryRun: dryRun}
+	before := report.StartedAt.Add(-minAge)
+
+	if err := s.collectTmp(&report, before); err != nil {
+		return nil, err
+	}
+	if err := s.collectBlobs(ctx, &report, before); err != nil {
+		return nil, err
+	}
+	if err := s.collectDangling(ctx, &report); err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now()
+	s.Logger.Info("garbage collection finished", "dry_run", dryRun, "tmp_files", len(report.TmpFiles),
+		"orphan_blobs", len(report.OrphanBlobs), "dangling_files", len(report.DanglingFiles), "freed_bytes", report.FreedBytes)
+	return &report, nil
+}
+
+// временные файлы старше before. данные и метаданные возобновляемой загрузки (<uuid>.part и
+// <uuid>.json) стареют вместе - по самому свежему из них, чтобы не удалить половину живой загрузки
+func (s *FileService) collectTmp(report *domain.GCReport, before time.Time) error {
+	entries, err := os.ReadDir(s.TmpDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // гейтвей не делит диск с реестром
+	}
+	if err != nil {
+		return err
+	}
+
+	type group struct {
+		names  []string
+		size   int64
+		newest time.Time
+	}
+	groups := make(map[string]*group)
+	var order []string
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			continue // удалили, пока читали папку
+		}
+
+		stem := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		g, ok := groups[stem]
+		if !ok {
+			g = &group{}
+			groups[stem] = g
+			order = append(order, stem)
+		}
+
+		g.names = append(g.names, e.Name())
+		g.size += info.Size()
+		if info.ModTime().After(g.newest) {
+			g.newest = info.ModTime()
+		}
+	}
+
+	for _, stem := range order {
+		g := groups[stem]
+		if !g.newest.Before(before) {
+			continue
+		}
+
+		for _, name := range g.names {
+			if !report.DryRun {
+				if err := os.Remove(filepath.Join(s.TmpDir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+					s.Logger.Error("error deleting a tmp file", "name", name, "error", err)
+					continue
+				}
+			}
+
+			report.TmpFiles = append(report.TmpFiles, name)
+		}
+		report.FreedBytes += g.size
+	}
+
+	return nil
+}
+
+// объекты хранилища старше before, на которые не ссылается ни одна запись
+func (s *FileService) collectBlobs(ctx context.Context, report *domain.GCReport, before time.Time) error {
+	for after := ""; ; {
+		objects, err := s.Blobs.List(ctx, after, gcBatch)
+		if err != nil {
+			return err
+		}
+		if len(objects) == 0 {
+			return nil
+		}
+
+		for _, o := range objects {
+			if !o.ModTime.Before(before) {
+				continue
+			}
+
+			referenced, err := s.Repo.Referenced(ctx, o.Key)
+			if err != nil {
+				return err
+			}
+			if referenced {
+				continue
+			}
+
+			if !report.DryRun {
+				if err := s.Blobs.Delete(ctx, o.Key); err != nil {
+					s.Logger.Error("error deleting a blob", "key", o.Key, "error", err)
+					continue
+				}
+			}
+
+			s.Logger.Warn("orphan blob", "key", o.Key, "size", o.Size)
+			report.OrphanBlobs = append(report.OrphanBlobs, o.Key)
+			report.FreedBytes += o.Size
+		}
+
+		after = objects[len(objects)-1].Key
+	}
+}
+
+// записи, содержимого которых нет в хранилище. такие ссылки уже никогда не отдадут файл
+func (s *FileService) collectDangling(ctx context.Context, report *domain.GCReport) error {
+	for after := ""; ; {
+		blobs, err := s.Repo.ListBlobs(ctx, after, gcBatch)
+		if err != nil {
+			return err
+		}
+		if len(blobs) == 0 {
+			return nil
+		}
+
+		for _, b := range blobs {
+			_, err := s.Blobs.Stat(ctx, b.Key)
+			if !errors.Is(err, domain.ErrBlobNotFound) {
+				// ошибка самого хранилища - не повод удалять записи, попробуем в следующий раз
+				if err != nil {
+					s.Logger.Error("error checking a blob", "key", b.Key, "error", err)
+				}
+				continue
+			}
+
+			files, err := s.Repo.ListByKey(ctx, b.Key)
+			if err != nil {
+				return err
+			}
+
+			for _, f := range files {
+				id := domain.QualifiedID(f.Tenant, f.ID)
+				if !report.DryRun {
+					// записи с одним объектом могут лежать у разных арендаторов
+					if _, err := s.Repo.Delete(domain.WithTenant(ctx, f.Tenant), f.ID); err != nil {
+						s.Logger.Error("error deleting a dangling file", "id", id, "error", err)
+						continue
+					}
+				}
+
+				s.Logger.Warn("dangling file", "id", id, "key", b.Key)
+				report.DanglingFiles = append(report.DanglingFiles, id)
+			}
+		}
+
+		after = blobs[len(blobs)-1].Key
+	}
+}
+
+// запуск периодической сборки мусора, по аналогии с очисткой
+func (s *FileService) StartCollector(ctx context.Context) {
+	if s.GCInterval <= 0 {
+		s.Logger.Info("garbage collector is disabled")
+		return
+	}
+
+	s.Logger.Info("starting garbage collector worker", "interval", s.GCInterval)
+
+	go func() {
+		ti := time.NewTicker(s.GCInterval)
+		defer ti.Stop()
+
+		for {
+			select {
+			case <-ti.C:
+				if _, err := s.CollectGarbage(ctx, false, 0); err != nil {
+					s.Logger.Error("scheduled garbage collection failed", "error", err)
+				}
+			case <-ctx.Done():
+				s.Logger.Info("garbage collector stopped due to cancelled context", "", ctx.Err())
+				return
+			}
+		}
+	}()
+}
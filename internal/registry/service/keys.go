@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+// ключи доступа к загрузке. гейтвей проверяет ключ из запроса через Authenticate и передает айди
+// ключа вместе с загрузкой, а ограничения ключа проверяются здесь, при сохранении файла
+
+const (
+	keyPrefix   = "gfs_" // по префиксу ключ легко отличить от других секретов, например в логах
+	keyIDLength = 12
+)
+
+// создать ключ с ограничениями из k. сам ключ возвращается один раз, в бд остается только его хеш
+func (s *FileService) CreateKey(ctx context.Context, k domain.APIKey) (*domain.APIKey, string, error) {
+	if k.MaxSize < 0 || k.MaxTTL < 0 {
+		return nil, "", domain.ErrInvalidKey
+	}
+	if !s.HasTenant(k.Tenant) {
+		return nil, "", domain.ErrUnknownTenant
+	}
+
+	for i, t := range k.ContentTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if typ, sub, ok := strings.Cut(t, "/"); !ok || typ == "" || sub == "" || strings.ContainsAny(t, ", ;") {
+			return nil, "", domain.ErrInvalidKey
+		}
+		k.ContentTypes[i] = t
+	}
+
+	token, err := newToken()
+	if err != nil {
+		s.Logger.Error("error generating a key", "error", err)
+		return nil, "", domain.ErrInService
+	}
+
+	secret := keyPrefix + token
+	k.KeyHash = hashToken(secret)
+	k.CreatedAt = time.Now()
+
+	// айди ключа не секрет, коллизия здесь почти невозможна, и повтор не нужен
+	if k.ID, err = generateId(keyIDLength); err != nil {
+		s.Logger.Error("error generating a key", "error", err)
+		return nil, "", domain.ErrInService
+	}
+
+	if err := s.Repo.InsertKey(ctx, &k); err != nil {
+		s.Logger.Error("error creating a key", "error", err)
+		return nil, "", domain.ErrInRepo
+	}
+
+	s.Logger.Info("created api key: " + k.ID)
+	return &k, secret, nil
+}
+
+// ключ по его секрету, domain.ErrUnauthenticated - если такого ключа нет
+// или он выдан другому арендатору, чем в контексте
+func (s *FileService) Authenticate(ctx context.Context, secret string) (*domain.APIKey, error) {
+	if !strings.HasPrefix(secret, keyPrefix) {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	k, err := s.Repo.KeyByHash(ctx, hashToken(secret))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.ErrUnauthenticated
+		}
+
+		s.Logger.Error("error checking a key", "error", err)
+		return nil, domain.ErrInRepo
+	}
+	if k.Tenant != domain.TenantFrom(ctx) {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	return k, nil
+}
+
+func (s *FileService) ListKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	keys, err := s.Repo.ListKeys(ctx)
+	if err != nil {
+		s.Logger.Error("error listing keys", "error", err)
+		return nil, domain.ErrInRepo
+	}
+
+	return keys, nil
+}
+
+// отозвать ключ. уже загруженные по нему файлы остаются
+func (s *FileService) RevokeKey(ctx context.Context, id string) error {
+	if err := s.Repo.DeleteKey(ctx, id); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+
+		s.Logger.Error("error revoking a key", "error", err)
+		return domain.ErrInRepo
+	}
+
+	s.Logger.Info("revoked api key: " + id)
+	return nil
+}
+
+// ключ загрузки по айди, nil - анонимная загрузка. ключ могли отозвать после проверки гейтвеем.
+// ключ другого арендатора в этом пространстве не действует
+func (s *FileService) uploadKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	k, err := s.Repo.GetKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.ErrUnauthenticated
+		}
+
+		s.Logger.Error("error checking a key", "error", err)
+		return nil, domain.ErrInRepo
+	}
+	if k.Tenant != domain.TenantFrom(ctx) {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	return k, nil
+}
+
+// срок ссылки с учетом ограничения ключа. срок по умолчанию урезается до разрешенного,
+// а явно запрошенный больший срок или бессрочная ссылка - ошибка
+func keyExpiry(k *domain.APIKey, created, expires time.Time, ttl time.Duration) (time.Time, error) {
+	if k == nil || k.MaxTTL == 0 {
+		return expires, nil
+	}
+
+	limit := created.Add(k.MaxTTL)
+	if expires.IsZero() || expires.After(limit) {
+		if ttl != 0 {
+			return time.Time{}, domain.ErrInvalidTTL
+		}
+		return limit, nil
+	}
+
+	return expires, nil
+}
+
+// новый срок ссылки, загруженной по ключу keyID, с учетом ограничения ключа - так же, как при
+// загрузке. отозванный ключ своего ограничения уже не расскажет, поэтому такую ссылку можно
+// только сократить
+func (s *FileService) keyUpdateExpiry(ctx context.Context, keyID string, created, current, expires time.Time, ttl time.Duration) (time.Time, error) {
+	if keyID == "" {
+		return expires, nil
+	}
+
+	k, err := s.Repo.GetKey(ctx, keyID)
+	if errors.Is(err, domain.ErrNotFound) {
+		if !current.IsZero() && (expires.IsZero() || expires.After(current)) {
+			return time.Time{}, domain.ErrInvalidTTL
+		}
+		return expires, nil
+	}
+	if err != nil {
+		s.Logger.Error("error checking a key", "error", err)
+		return time.Time{}, domain.ErrInRepo
+	}
+
+	return keyExpiry(k, created, expires, ttl)
+}
+
+// подходит ли тип содержимого под список разрешенных (ключа или арендатора), пустой список
+// разрешает все. параметры типа вроде charset не учитываются
+func typeAllowed(types []string, contentType string) bool {
+	if len(types) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range types {
+		if allowed == mediaType || allowed == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// поток, который обрывается ошибкой err, как только прочитано больше left байт
+type sizeLimiter struct {
+	r    io.Reader
+	left int64
+	err  error
+}
+
+func limitSize(limit int64, r io.Reader) io.Reader {
+	if limit == 0 {
+		return r
+	}
+
+	return &sizeLimiter{r: r, left: limit, err: domain.ErrTooLarge}
+}
+
+func (l *sizeLimiter) Read(p []byte) (int, error) {
+	// на байт больше лимита - чтобы отличить файл ровно в лимит от файла больше лимита
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.left < 0 {
+		return 0, l.err
+	}
+
+	return n, err
+}
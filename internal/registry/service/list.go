@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// действующие файлы по фильтру, новые сначала, и курсор следующей страницы
+// (пустая строка - страница последняя). 0 в filter.Limit - размер страницы по умолчанию
+func (s *FileService) ListFiles(ctx context.Context, filter domain.FileFilter) ([]*domain.File, string, error) {
+	switch {
+	case filter.Limit < 0:
+		return nil, "", domain.ErrInvalidFilter
+	case filter.Limit == 0:
+		filter.Limit = defaultPageSize
+	case filter.Limit > maxPageSize:
+		filter.Limit = maxPageSize
+	}
+
+	if t := filter.ContentType; t != "" {
+		typ, sub, ok := strings.Cut(t, "/")
+		if !ok || typ == "" || sub == "" || typ == "*" || strings.ContainsAny(t, ", ;") {
+			return nil, "", domain.ErrInvalidFilter
+		}
+	}
+
+	if !filter.ExpiresAfter.IsZero() && !filter.ExpiresBefore.IsZero() && filter.ExpiresBefore.Before(filter.ExpiresAfter) {
+		return nil, "", domain.ErrInvalidFilter
+	}
+
+	files, next, err := s.Repo.ListFiles(ctx, filter)
+	if errors.Is(err, domain.ErrInvalidFilter) {
+		return nil, "", err
+	}
+	if err != nil {
+		s.Logger.Error("error listing files", "error", err)
+		return nil, "", domain.ErrInRepo
+	}
+
+	return files, next, nil
+}
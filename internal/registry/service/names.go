@@ -0,0 +1,49 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+// выбранное загружающим короткое имя: те же символы, что принимает гейтвей в ссылке
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+const (
+	minNameLength = 3
+	maxNameLength = 64
+)
+
+// имена, которые совпадают с путями гейтвея или могут ввести в заблуждение
+var reservedNames = map[string]bool{
+	"upload":    true,
+	"resumable": true,
+	"get":       true,
+	"info":      true,
+	"files":     true,
+	"zip":       true,
+	"quota":     true,
+	"api":       true,
+	"admin":     true,
+	"static":    true,
+	"health":    true,
+}
+
+// проверить имя до записи содержимого, чтобы не гонять байты впустую. анонимам выбирать имя
+// нельзя, иначе кто угодно занял бы любые имена
+func validateName(name string, anonymous bool) error {
+	if anonymous {
+		return domain.ErrNameNeedsAuth
+	}
+
+	if len(name) < minNameLength || len(name) > maxNameLength || !nameRegexp.MatchString(name) {
+		return domain.ErrInvalidName
+	}
+
+	if reservedNames[strings.ToLower(name)] {
+		return domain.ErrInvalidName
+	}
+
+	return nil
+}
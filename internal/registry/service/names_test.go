@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+	"github.com/kfcempoyee/gofilesharing/internal/registry/repository"
+	"github.com/kfcempoyee/gofilesharing/internal/registry/storage"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupService собирает сервис на in-memory бд и локальном хранилище во временной папке
+func setupService(t *testing.T) *FileService {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1) // у каждого соединения :memory: своя бд
+
+	repo, err := repository.NewFileRepo(db)
+	if err != nil {
+		t.Fatalf("Failed to init repo: %v", err)
+	}
+
+	blobs, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to init storage: %v", err)
+	}
+
+	return NewFileService(repo, blobs, slog.New(slog.DiscardHandler))
+}
+
+func TestFileService_ShortNameNeedsAuth(t *testing.T) {
+	s := setupService(t)
+	ctx := context.Background()
+
+	store := func(meta domain.UploadMeta) (*domain.UploadResult, error) {
+		meta.Name, meta.ContentType, meta.Size = "a.txt", "text/plain", 3
+		return s.Store(ctx, meta, strings.NewReader("abc"))
+	}
+
+	// аноним не может занять имя
+	_, err := store(domain.UploadMeta{ShortName: "vanity"})
+	if !errors.Is(err, domain.ErrNameNeedsAuth) || !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Fatalf("Expected ErrNameNeedsAuth, got %v", err)
+	}
+	if _, err := s.Repo.Lookup(ctx, "vanity"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected the name to stay free, got %v", err)
+	}
+
+	// а без имени анонимная загрузка по-прежнему работает
+	anon, err := store(domain.UploadMeta{})
+	if err != nil {
+		t.Fatalf("Anonymous upload failed: %v", err)
+	}
+
+	res, err := store(domain.UploadMeta{ShortName: "vanity", Owner: "alice"})
+	if err != nil {
+		t.Fatalf("Upload with a name failed: %v", err)
+	}
+	if res.ID != "vanity" {
+		t.Errorf("Expected ID vanity, got %s", res.ID)
+	}
+
+	// то же для наборов
+	members := []domain.MemberRef{{ID: anon.ID, Token: anon.DeleteToken}}
+	_, err = s.CreateCollection(ctx, domain.CollectionMeta{ShortName: "album", Members: members})
+	if !errors.Is(err, domain.ErrNameNeedsAuth) {
+		t.Fatalf("Expected ErrNameNeedsAuth for a collection, got %v", err)
+	}
+
+	c, err := s.CreateCollection(ctx, domain.CollectionMeta{ShortName: "album", Members: members, Owner: "alice"})
+	if err != nil {
+		t.Fatalf("CreateCollection with a name failed: %v", err)
+	}
+	if c.ID != "album" {
+		t.Errorf("Expected ID album, got %s", c.ID)
+	}
+}
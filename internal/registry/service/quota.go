@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+// квоты владельцев на байты и число файлов. занятое считается по записям в бд, поэтому удаление
+// файла владельцем и очистка истекших сразу освобождают место. у анонимных загрузок владельца
+// нет, и квота на них не действует. владелец с одним и тем же subject в разных арендаторах -
+// разные владельцы: и квота, и занятое у каждого свои. квота арендатора из настроек действует
+// на все его файлы, в том числе анонимные
+
+// квота владельца: отдельная, если она задана, иначе квота по умолчанию
+func (s *FileService) quotaFor(ctx context.Context, owner string) (domain.Quota, error) {
+	if owner == "" {
+		return domain.Quota{}, nil
+	}
+
+	q, err := s.Repo.GetQuota(ctx, owner)
+	if errors.Is(err, domain.ErrNotFound) {
+		return s.Quota, nil
+	}
+	if err != nil {
+		s.Logger.Error("error getting a quota", "error", err)
+		return domain.Quota{}, domain.ErrInRepo
+	}
+
+	return *q, nil
+}
+
+// сколько занимает владелец и сколько ему разрешено. у анонима квоты нет, и занятое не считается
+func (s *FileService) Usage(ctx context.Context, owner string) (domain.Usage, domain.Quota, error) {
+	q, err := s.quotaFor(ctx, owner)
+	if err != nil || owner == "" {
+		return domain.Usage{}, domain.Quota{}, err
+	}
+
+	u, err := s.Repo.Usage(ctx, owner)
+	if err != nil {
+		s.Logger.Error("error counting usage", "error", err)
+		return domain.Usage{}, domain.Quota{}, domain.ErrInRepo
+	}
+
+	return u, q, nil
+}
+
+// задать владельцу отдельную квоту, nil - вернуть квоту по умолчанию
+func (s *FileService) SetQuota(ctx context.Context, owner string, q *domain.Quota) error {
+	if owner == "" || (q != nil && (q.MaxBytes < 0 || q.MaxFiles < 0)) {
+		return domain.ErrInvalidQuota
+	}
+
+	var err error
+	if q == nil {
+		err = s.Repo.DeleteQuota(ctx, owner)
+	} else {
+		err = s.Repo.SetQuota(ctx, owner, *q)
+	}
+
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		s.Logger.Error("error setting a quota", "error", err)
+		return domain.ErrInRepo
+	}
+
+	return nil
+}
+
+// поместится ли еще один файл размером size (0 - размер неизвестен)
+func checkQuota(q domain.Quota, u domain.Usage, size int64) error {
+	if q.MaxFiles > 0 && u.Files+1 > q.MaxFiles {
+		return domain.ErrQuotaFiles
+	}
+	if q.MaxBytes > 0 && u.Bytes+size > q.MaxBytes {
+		return domain.ErrQuotaBytes
+	}
+
+	return nil
+}
+
+// оборвать поток, как только он перестает помещаться в квоту
+func limitQuota(q domain.Quota, u domain.Usage, r io.Reader) io.Reader {
+	if q.MaxBytes == 0 {
+		return r
+	}
+
+	return &sizeLimiter{r: r, left: q.MaxBytes - u.Bytes, err: domain.ErrQuotaBytes}
+}
+
+// вставить запись, если после загрузки она все еще помещается в квоты владельца q и арендатора tq.
+// одновременные загрузки могли занять место, пока шла запись, поэтому занятое перечитывается
+// и проверка с вставкой идут под одной блокировкой
+func (s *FileService) insertInQuota(ctx context.Context, owner string, q, tq domain.Quota, size int64, insert func() error) error {
+	if q == (domain.Quota{}) && tq == (domain.Quota{}) {
+		return insert()
+	}
+
+	s.quotaMu.Lock()
+	defer s.quotaMu.Unlock()
+
+	if q != (domain.Quota{}) {
+		u, err := s.Repo.Usage(ctx, owner)
+		if err != nil {
+			return err
+		}
+		if err := checkQuota(q, u, size); err != nil {
+			return err
+		}
+	}
+
+	if tq != (domain.Quota{}) {
+		u, err := s.Repo.TenantUsage(ctx, domain.TenantFrom(ctx))
+		if err != nil {
+			return err
+		}
+		if err := checkQuota(tq, u, size); err != nil {
+			return err
+		}
+	}
+
+	return insert()
+}
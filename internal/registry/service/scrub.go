@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+const scrubBatch = 100 // сколько объектов берется из бд за раз
+
+// состояние проверки хранилища: итоги последнего прохода. одновременно идет только один проход
+type scrubState struct {
+	mu      sync.Mutex
+	running sync.Mutex
+	last    *domain.ScrubReport
+}
+
+func (st *scrubState) report() *domain.ScrubReport {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.last == nil {
+		return nil
+	}
+
+	r := *st.last
+	return &r
+}
+
+func (st *scrubState) set(r domain.ScrubReport) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.last = &r
+}
+
+// перечитать все объекты хранилища и сверить с записанными хешами. пропавшие и испорченные
+// помечаются в бд, и такие файлы больше не отдаются. если проход уже идет, ждем его окончания
+func (s *FileService) Scrub(ctx context.Context) (*domain.ScrubReport, error) {
+	s.scrub.running.Lock()
+	defer s.scrub.running.Unlock()
+
+	report := domain.ScrubReport{StartedAt: time.Now()}
+	s.scrub.set(report)
+
+	for after := ""; ; {
+		blobs, err := s.Repo.ListBlobs(ctx, after, scrubBatch)
+		if err != nil {
+			return nil, err
+		}
+		if len(blobs) == 0 {
+			break
+		}
+
+		for _, b := range blobs {
+			state, err := s.checkBlob(ctx, b)
+			if err != nil {
+				// ошибка самого хранилища (сеть, права) - не повод помечать файл, попробуем в следующий раз
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+
+				s.Logger.Error("error checking a blob", "key", b.Key, "error", err)
+				continue
+			}
+
+			report.Checked++
+			switch state {
+			case domain.IntegrityMissing:
+				report.Missing++
+				s.Logger.Error("blob is missing", "key", b.Key)
+			case domain.IntegrityCorrupted:
+				report.Corrupted++
+				s.Logger.Error("blob is corrupted", "key", b.Key)
+			}
+
+			if err := s.Repo.MarkIntegrity(ctx, b.Key, state); err != nil {
+				s.Logger.Error("error marking a blob", "key", b.Key, "error", err)
+			}
+		}
+
+		after = blobs[len(blobs)-1].Key
+		s.scrub.set(report)
+	}
+
+	report.FinishedAt = time.Now()
+	s.scrub.set(report)
+
+	s.Logger.Info("scrub finished", "checked", report.Checked, "missing", report.Missing, "corrupted", report.Corrupted)
+	return &report, nil
+}
+
+// состояние одного объекта. у старых файлов без хеша проверяется только наличие
+func (s *FileService) checkBlob(ctx context.Context, b domain.Blob) (string, error) {
+	if b.Hash == "" {
+		_, err := s.Blobs.Stat(ctx, b.Key)
+		if errors.Is(err, domain.ErrBlobNotFound) {
+			return domain.IntegrityMissing, nil
+		}
+		return domain.IntegrityOK, err
+	}
+
+	rc, err := s.Blobs.Open(ctx, b.Key, 0, 0)
+	if errors.Is(err, domain.ErrBlobNotFound) {
+		return domain.IntegrityMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	sha := sha256.New()
+	if _, err := io.Copy(sha, rc); err != nil {
+		return "", err
+	}
+
+	if hex.EncodeToString(sha.Sum(nil)) != b.Hash {
+		return domain.IntegrityCorrupted, nil
+	}
+
+	return domain.IntegrityOK, nil
+}
+
+// итоги последнего прохода (nil, если проверки еще не было) и все файлы с найденными проблемами
+func (s *FileService) ScrubReport(ctx context.Context) (*domain.ScrubReport, []*domain.File, error) {
+	damaged, err := s.Repo.ListDamaged(ctx)
+	if err != nil {
+		s.Logger.Error("error listing damaged files", "error", err)
+		return nil, nil, domain.ErrInRepo
+	}
+
+	return s.scrub.report(), damaged, nil
+}
+
+// запуск периодической проверки хранилища, по аналогии с очисткой
+func (s *FileService) StartScrubber(ctx context.Context) {
+	if s.ScrubInterval <= 0 {
+		s.Logger.Info("scrubber is disabled")
+		return
+	}
+
+	s.Logger.Info("starting scrubber worker", "interval", s.ScrubInterval)
+
+	go func() {
+		ti := time.NewTicker(s.ScrubInterval)
+		defer ti.Stop()
+
+		for {
+			select {
+			case <-ti.C:
+				if _, err := s.Scrub(ctx); err != nil {
+					s.Logger.Error("scheduled scrub failed", "error", err)
+				}
+			case <-ctx.Done():
+				s.Logger.Info("scrubber stopped due to cancelled context", "", ctx.Err())
+				return
+			}
+		}
+	}()
+}
@@ -1,99 +1,465 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"io"
 	"log/slog"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+	"golang.org/x/crypto/bcrypt"
 )
 
-// интерфейс репо имеет 4 метода - сохранить, отдать, удалить файл и очистить хранилище
+// интерфейс репо - сохранить, отдать, удалить файл, поменять срок, засчитать скачивание, очистить
+// хранилище, записать результаты его проверки, найти ссылки на объекты для сборки мусора,
+// то же для наборов файлов и ключи доступа к загрузке
 type FileRepoInterface interface {
 	Insert(ctx context.Context, file *domain.File) error
 	Get(ctx context.Context, shortName string) (*domain.File, error)
-	Delete(ctx context.Context, id string) error
-	ClearExpired(ctx context.Context) error
+	Lookup(ctx context.Context, shortName string) (*domain.File, error)
+	Delete(ctx context.Context, id string) ([]string, error)
+	UpdateExpiry(ctx context.Context, id string, expiresAt time.Time) error
+	CountDownload(ctx context.Context, id string) error
+	ClearExpired(ctx context.Context, limit int) ([]string, int, error)
+	NextExpiry(ctx context.Context) (time.Time, bool, error)
+	InsertCollection(ctx context.Context, c *domain.Collection, members []string) error
+	LookupCollection(ctx context.Context, id string) (*domain.Collection, error)
+	UpdateCollectionExpiry(ctx context.Context, id string, expiresAt time.Time) error
+	DeleteCollection(ctx context.Context, id string) ([]string, error)
+	ListBlobs(ctx context.Context, after string, limit int) ([]domain.Blob, error)
+	MarkIntegrity(ctx context.Context, key, state string) error
+	ListDamaged(ctx context.Context) ([]*domain.File, error)
+	ListByKey(ctx context.Context, key string) ([]*domain.File, error)
+	Referenced(ctx context.Context, key string) (bool, error)
+	InsertKey(ctx context.Context, k *domain.APIKey) error
+	GetKey(ctx context.Context, id string) (*domain.APIKey, error)
+	KeyByHash(ctx context.Context, hash string) (*domain.APIKey, error)
+	ListKeys(ctx context.Context) ([]*domain.APIKey, error)
+	DeleteKey(ctx context.Context, id string) error
+	ListFiles(ctx context.Context, filter domain.FileFilter) ([]*domain.File, string, error)
+	Usage(ctx context.Context, owner string) (domain.Usage, error)
+	GetQuota(ctx context.Context, owner string) (*domain.Quota, error)
+	SetQuota(ctx context.Context, owner string, q domain.Quota) error
+	DeleteQuota(ctx context.Context, owner string) error
+	TenantUsage(ctx context.Context, tenant string) (domain.Usage, error)
 }
 
-// сервис должен содержать экземпляр репо и логгер (можно сделать новый или прокинуть общий)
+// хранилище содержимого файлов. в бд лежит только ключ объекта, а где и как хранятся байты
+// (локальный диск, S3 и т.п.) решает реализация
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	Open(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (int64, error)
+	List(ctx context.Context, after string, limit int) ([]domain.Object, error)
+}
+
+// сервис должен содержать экземпляр репо, хранилище и логгер (можно сделать новый или прокинуть общий)
 type FileService struct {
 	Repo   FileRepoInterface
+	Blobs  BlobStore
 	Logger *slog.Logger
+
+	DefaultTTL time.Duration // срок жизни ссылки, если загружающий его не указал
+	MaxTTL     time.Duration // максимальный срок жизни, 0 - без ограничений (разрешены бессрочные ссылки)
+
+	TmpDir          string        // сюда гейтвей кладет файлы, если делит диск с реестром
+	IDLength        int           // начальная длина короткого айди
+	CleanupInterval time.Duration // дольше этого очистка не спит, даже если ближайших сроков нет
+	ScrubInterval   time.Duration // как часто перепроверять содержимое хранилища, 0 - не проверять
+	GCInterval      time.Duration // как часто собирать мусор в хранилище, 0 - не собирать
+	GCMinAge        time.Duration // файлы и объекты моложе этого считаются частью идущей загрузки
+	Quota           domain.Quota  // квота владельца, если у него нет отдельной, нули - без ограничений
+
+	Tenants map[string]domain.Tenant // арендаторы по имени, без пространства по умолчанию
+
+	attempts  attemptLimiter // неудачные попытки ввода пароля
+	idGrowth  atomic.Int64   // на сколько символов айди стал длиннее IDLength из-за коллизий
+	scrub     scrubState     // итоги проверки хранилища
+	cleanup   cleanupState   // когда очистка проснется в следующий раз
+	gcRunning sync.Mutex     // одновременно идет только один проход сборки мусора
+	quotaMu   sync.Mutex     // проверка квоты и вставка записи идут без одновременных загрузок
 }
 
-// передаем в сервис репо и логгер
-func NewFileService(repo FileRepoInterface, logger *slog.Logger) *FileService {
+const (
+	defaultTTL      = 48 * time.Hour
+	defaultMax      = 30 * 24 * time.Hour
+	defaultTmpDir   = "data/tmp"
+	defaultIDLength = 5
+	defaultCleanup  = time.Hour
+	defaultScrub    = 7 * 24 * time.Hour
+	defaultGC       = 24 * time.Hour
+	defaultGCMinAge = 24 * time.Hour
+
+	idAttempts  = 3  // столько коллизий подряд на одной длине - и айди становится на символ длиннее
+	maxIDLength = 32 // дальше расти некуда, коллизия на такой длине - точно ошибка
+)
+
+// передаем в сервис репо, хранилище и логгер
+func NewFileService(repo FileRepoInterface, blobs BlobStore, logger *slog.Logger) *FileService {
 	return &FileService{
-		Repo:   repo,
-		Logger: logger,
+		Repo:            repo,
+		Blobs:           blobs,
+		Logger:          logger,
+		DefaultTTL:      defaultTTL,
+		MaxTTL:          defaultMax,
+		TmpDir:          defaultTmpDir,
+		IDLength:        defaultIDLength,
+		CleanupInterval: defaultCleanup,
+		ScrubInterval:   defaultScrub,
+		GCInterval:      defaultGC,
+		GCMinAge:        defaultGCMinAge,
+		cleanup:         cleanupState{wake: make(chan struct{}, 1)},
 	}
 }
 
+// момент истечения ссылки при сроке жизни ttl, отсчитанном от from, нулевое время - бессрочно.
+// итоговый срок не может превышать MaxTTL от момента загрузки created. сроки арендатора
+// из контекста заменяют общие, а срок по умолчанию урезается до наибольшего
+func (s *FileService) expiresAt(ctx context.Context, created, from time.Time, ttl time.Duration) (time.Time, error) {
+	defaultTTL, maxTTL := s.DefaultTTL, s.MaxTTL
+	if t := s.tenant(ctx); t.Name != "" {
+		if t.DefaultTTL > 0 {
+			defaultTTL = t.DefaultTTL
+		}
+		if t.MaxTTL > 0 {
+			maxTTL = t.MaxTTL
+		}
+	}
+
+	switch {
+	case ttl == 0:
+		ttl = defaultTTL
+		if maxTTL > 0 && ttl > maxTTL {
+			ttl = maxTTL
+		}
+	case ttl == domain.NoExpiry:
+		if maxTTL > 0 {
+			return time.Time{}, domain.ErrInvalidTTL
+		}
+		return time.Time{}, nil
+	case ttl < 0:
+		return time.Time{}, domain.ErrInvalidTTL
+	}
+
+	expires := from.Add(ttl)
+	if maxTTL > 0 && expires.Sub(created) > maxTTL {
+		return time.Time{}, domain.ErrInvalidTTL
+	}
+
+	return expires, nil
+}
+
 const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
-// генерирует короткий айди для ссылки
-// быстрее через builder
-func generateId(length int) string {
+// генерирует короткий айди для ссылки из crypto/rand, чтобы соседние ссылки нельзя было угадать.
+// байты >= 248 отбрасываются: 248 делится на 62 нацело, так все символы равновероятны
+func generateId(length int) (string, error) {
 	var b strings.Builder
 	b.Grow(length)
 
-	for range length {
-		b.WriteByte(charset[rand.Intn(len(charset))])
+	buf := make([]byte, length)
+	for b.Len() < length {
+		if _, err := crand.Read(buf); err != nil {
+			return "", err
+		}
+
+		for _, c := range buf {
+			if c >= 248 || b.Len() == length {
+				continue
+			}
+			b.WriteByte(charset[int(c)%len(charset)])
+		}
 	}
 
-	return b.String()
+	return b.String(), nil
 }
 
-// загрузить файл в бд
-func (s *FileService) Upload(ctx context.Context, uuid, name string, size int64, contentType string) (string, error) {
-	fileId := generateId(5) // генерируем айди
+// длина айди: начальная из настроек плюс рост из-за коллизий
+func (s *FileService) idLength() int {
+	return s.baseIdLength() + int(s.idGrowth.Load())
+}
 
-	storagePath := filepath.Join("data/storage", uuid+".dat")
-	err := os.MkdirAll(storagePath, 0755)
+func (s *FileService) baseIdLength() int {
+	if s.IDLength <= 0 {
+		return defaultIDLength
+	}
 
-	if err != nil {
+	return s.IDLength
+}
+
+// запомнить, что айди короче length больше не генерируются.
+// параллельные загрузки могут упереться в коллизии одновременно, длина при этом растет один раз
+func (s *FileService) growIdLength(length int) {
+	want := int64(length - s.baseIdLength())
+
+	for {
+		cur := s.idGrowth.Load()
+		if cur >= want || s.idGrowth.CompareAndSwap(cur, want) {
+			return
+		}
+	}
+}
+
+// записать файл или набор в бд под новым айди. при коллизии айди генерируется заново, а если коллизии
+// идут подряд - пространство айди заполняется, и длина растет для всех следующих загрузок
+func (s *FileService) insertWithId(insert func(id string) error) error {
+	length := s.idLength()
+
+	for conflicts := 0; ; {
+		id, err := generateId(length)
+		if err != nil {
+			return err
+		}
+
+		err = insert(id)
+		if !errors.Is(err, domain.ErrConflict) {
+			return err
+		}
+
+		conflicts++
+		s.Logger.Warn("short id collision", "id", id, "length", length)
+
+		if conflicts < idAttempts {
+			continue
+		}
+		if length >= maxIDLength {
+			return err
+		}
+
+		length++
+		conflicts = 0
+		s.growIdLength(length)
+	}
+}
+
+// секретный токен владельца: 256 случайных бит в base64url
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := crand.Read(b); err != nil {
 		return "", err
 	}
 
-	err = os.Rename(filepath.Join("data/tmp", uuid), storagePath)
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// в бд хранится только хеш токена. токен случайный и длинный, поэтому медленный хеш не нужен
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// загрузить в хранилище файл, который гейтвей положил во временную папку на общем диске
+func (s *FileService) Upload(ctx context.Context, uuid string, meta domain.UploadMeta) (*domain.UploadResult, error) {
+	tmpPath := filepath.Join(s.TmpDir, filepath.Base(uuid))
 
+	f, err := os.Open(tmpPath)
 	if err != nil {
 		s.Logger.Error("error uploading a file", "error", err)
-		return "", domain.ErrInService
+		return nil, domain.ErrInService
+	}
+	defer f.Close()
+
+	res, err := s.Store(ctx, meta, f)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = os.Remove(tmpPath)
+	return res, nil
+}
+
+// сохранить файл, пришедший потоком, в хранилище и записать в бд.
+// в отличие от Upload не требует общего с гейтвеем диска
+func (s *FileService) Store(ctx context.Context, meta domain.UploadMeta, r io.Reader) (*domain.UploadResult, error) {
+	// ограничения ключа и арендатора и срок проверяем до записи, чтобы не гонять байты впустую
+	apiKey, err := s.uploadKey(ctx, meta.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := s.tenant(ctx)
+	limit := maxSize(apiKey, tenant)
+	if limit > 0 && meta.Size > limit {
+		return nil, domain.ErrTooLarge
+	}
+	if !typeAllowed(tenant.ContentTypes, meta.ContentType) || (apiKey != nil && !typeAllowed(apiKey.ContentTypes, meta.ContentType)) {
+		return nil, domain.ErrTypeNotAllowed
+	}
+
+	// квоты тоже проверяем заранее по заявленному размеру, а по ходу записи - по прочитанному
+	usage, quota, err := s.Usage(ctx, meta.Owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkQuota(quota, usage, meta.Size); err != nil {
+		return nil, err
+	}
+	tenantUsage, tenantQuota, err := s.TenantUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkQuota(tenantQuota, tenantUsage, meta.Size); err != nil {
+		return nil, err
+	}
+
+	created := time.Now()
+	expires, err := s.expiresAt(ctx, created, created, meta.TTL)
+	if err != nil {
+		return nil, err
+	}
+	if expires, err = keyExpiry(apiKey, created, expires, meta.TTL); err != nil {
+		return nil, err
+	}
+
+	if meta.MaxDownloads < 0 {
+		return nil, domain.ErrInvalidLimit
+	}
+
+	// занятость имени проверяется заранее только для быстрого ответа,
+	// резервирует имя сама вставка в бд
+	if meta.ShortName != "" {
+		if err := validateName(meta.ShortName, meta.KeyID == "" && meta.Owner == ""); err != nil {
+			return nil, err
+		}
+
+		if _, err := s.Repo.Lookup(ctx, meta.ShortName); err == nil {
+			return nil, domain.ErrConflict
+		}
+	}
+
+	var passwordHash string
+	if meta.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(meta.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, domain.ErrInvalidPassword
+		}
+		passwordHash = string(hash)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		s.Logger.Error("error generating a token", "error", err)
+		return nil, domain.ErrInService
+	}
+
+	key := uuid.New().String() + ".dat"
+
+	// хеши считаются по ходу записи, второй раз содержимое не читается.
+	// md5 нужен только для проверки, если клиент его прислал
+	sha := sha256.New()
+	md := md5.New()
+	var sums io.Writer = sha
+	if len(meta.MD5) > 0 {
+		sums = io.MultiWriter(sha, md)
+	}
+
+	r = limitQuota(tenantQuota, tenantUsage, limitQuota(quota, usage, limitSize(limit, r)))
+	size, err := s.Blobs.Put(ctx, key, io.TeeReader(r, sums))
+	if errors.Is(err, domain.ErrTooLarge) || errors.Is(err, domain.ErrQuotaBytes) {
+		return nil, err
+	}
+	if err != nil {
+		s.Logger.Error("error storing a file", "error", err)
+		return nil, domain.ErrInService
+	}
+
+	if meta.Size > 0 && size != meta.Size {
+		s.deleteBlob(ctx, key)
+		return nil, domain.ErrSizeMismatch
+	}
+
+	shaSum := sha.Sum(nil)
+	if (len(meta.SHA256) > 0 && !bytes.Equal(meta.SHA256, shaSum)) ||
+		(len(meta.MD5) > 0 && !bytes.Equal(meta.MD5, md.Sum(nil))) {
+		s.deleteBlob(ctx, key)
+		return nil, domain.ErrDigestMismatch
 	}
 
 	newFile := domain.File{
-		ID:           fileId,
-		OriginalName: name,
-		StoragePath:  storagePath,
+		OriginalName: meta.Name,
+		StoragePath:  key,
 		Size:         size,
-		ContentType:  contentType,
-		CreatedAt:    time.Now(),
+		ContentType:  meta.ContentType,
+		CreatedAt:    created,
+		ExpiresAt:    expires,
+		MaxDownloads: meta.MaxDownloads,
+		PasswordHash: passwordHash,
+		TokenHash:    hashToken(token),
+		ContentHash:  hex.EncodeToString(shaSum),
+		Owner:        meta.Owner,
+		KeyID:        meta.KeyID,
 	}
 
-	err = s.Repo.Insert(ctx, &newFile)
+	err = s.insertInQuota(ctx, meta.Owner, quota, tenantQuota, size, func() error {
+		if meta.ShortName != "" {
+			newFile.ID = meta.ShortName
+			return s.Repo.Insert(ctx, &newFile)
+		}
+
+		return s.insertWithId(func(id string) error {
+			newFile.ID = id
+			return s.Repo.Insert(ctx, &newFile)
+		})
+	})
 
 	if err != nil {
-		s.Logger.Error("error uploading a file", "error", err)
-		return "", domain.ErrInRepo
+		s.deleteBlob(ctx, key)
+
+		// имя успели занять, пока шла загрузка
+		if errors.Is(err, domain.ErrConflict) && meta.ShortName != "" {
+			return nil, err
+		}
+		// место в квоте заняли одновременные загрузки
+		if errors.Is(err, domain.ErrQuotaBytes) || errors.Is(err, domain.ErrQuotaFiles) {
+			return nil, err
+		}
+
+		s.Logger.Error("error storing a file", "error", err)
+		return nil, domain.ErrInRepo
 	}
 
-	s.Logger.Info("uploaded file: " + uuid)
-	return fileId, nil
+	s.reschedule(expires)
+
+	// такое содержимое уже хранится, новый файл ссылается на него
+	if newFile.StoragePath != key {
+		s.deleteBlob(ctx, key)
+		s.Logger.Info("deduplicated file: " + newFile.StoragePath)
+		return &domain.UploadResult{ID: newFile.ID, DeleteToken: token}, nil
+	}
+
+	s.Logger.Info("stored file: " + key)
+	return &domain.UploadResult{ID: newFile.ID, DeleteToken: token}, nil
+}
+
+// удалить содержимое из хранилища, ошибка только логируется
+func (s *FileService) deleteBlob(ctx context.Context, key string) {
+	if err := s.Blobs.Delete(ctx, key); err != nil {
+		s.Logger.Error("error deleting a blob", "key", key, "error", err)
+	}
 }
 
-// берем путь и данные файла в бд по короткому имени
-func (s *FileService) Get(ctx context.Context, id string) (*domain.File, error) {
+// берем путь и данные файла в бд по короткому имени. для защищенного файла нужен его пароль
+func (s *FileService) Get(ctx context.Context, id, password string) (*domain.File, error) {
 	resFile, err := s.Repo.Get(ctx, id)
 
 	if err != nil {
-		if errors.Is(err, domain.ErrNotFound) {
+		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) ||
+			errors.Is(err, domain.ErrDownloadsExhausted) || errors.Is(err, domain.ErrCorrupted) {
 			return nil, err
 		}
 
@@ -101,35 +467,178 @@ func (s *FileService) Get(ctx context.Context, id string) (*domain.File, error)
 		return nil, domain.ErrInRepo
 	}
 
+	if err := s.checkPassword(ctx, domain.QualifiedID(resFile.Tenant, resFile.ID), resFile.PasswordHash, password); err != nil {
+		return nil, err
+	}
+
 	s.Logger.Info("got a file: success")
 	return resFile, nil
 }
 
-// запуск очищения диска и бд от просроченных записей
-func (s *FileService) StartCleanup(ctx context.Context) {
-	s.Logger.Info("starting cleanup worker")
+// проверить пароль файла или набора id с учетом ограничения на число попыток:
+// отдельно для клиента из контекста и для всех клиентов вместе
+func (s *FileService) checkPassword(ctx context.Context, id, hash, password string) error {
+	if hash == "" {
+		return nil
+	}
+
+	// пустой пароль - это не попытка подбора, а просто открытие ссылки без пароля
+	if password == "" {
+		return domain.ErrWrongPassword
+	}
+
+	// айди без пробелов, так что ключ клиента не совпадет с ключом другого файла
+	client := id + " " + domain.ClientFrom(ctx)
+
+	now := time.Now()
+	if !s.attempts.allowed(id, now) || !s.attempts.allowed(client, now) {
+		return domain.ErrTooManyAttempts
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		s.attempts.fail(client, maxAttempts, now)
+		s.attempts.fail(id, maxFileAttempts, now)
+		s.Logger.Warn("wrong password for a file", "id", id, "client", domain.ClientFrom(ctx))
+		return domain.ErrWrongPassword
+	}
 
-	go func() {
-		s.Logger.Info("starting initial cleanup")
-		if err := s.Repo.ClearExpired(ctx); err != nil {
-			s.Logger.Error("initial cleanup failed", "", err)
+	s.attempts.reset(client)
+	s.attempts.reset(id)
+	return nil
+}
+
+// открыть содержимое файла начиная с offset. length - сколько байт отдать, 0 - до конца файла.
+// у ссылки с лимитом скачиванием считается каждый поток: иначе повторные запросы с offset 1
+// выкачали бы почти весь файл, не тратя лимит. у ссылки без лимита счетчик - только статистика,
+// и докачка не засчитывается повторно
+func (s *FileService) Open(ctx context.Context, id, password string, offset, length int64) (io.ReadCloser, error) {
+	file, err := s.Get(ctx, id, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset < 0 || length < 0 || offset > file.Size {
+		return nil, domain.ErrInvalidRange
+	}
+
+	if offset == 0 || file.MaxDownloads > 0 {
+		if err := s.countDownload(ctx, id); err != nil {
+			return nil, err
 		}
+	}
 
-		ti := time.NewTicker(24 * time.Hour) // каждые 24 часа система очищает от истекших файлов
-		defer ti.Stop()
+	rc, err := s.Blobs.Open(ctx, file.StoragePath, offset, length)
 
-		for {
-			select {
-			case <-ti.C:
-				s.Logger.Info("starting scheduled cleanup")
+	// пропажу объекта помечаем сразу, не дожидаясь проверки хранилища
+	if errors.Is(err, domain.ErrBlobNotFound) {
+		s.Logger.Error("blob is missing", "key", file.StoragePath)
+		if err := s.Repo.MarkIntegrity(ctx, file.StoragePath, domain.IntegrityMissing); err != nil {
+			s.Logger.Error("error marking a blob", "key", file.StoragePath, "error", err)
+		}
+		return nil, domain.ErrCorrupted
+	}
 
-				if err := s.Repo.ClearExpired(ctx); err != nil {
-					s.Logger.Error("scheduled cleanup failed", "", err)
-				}
-			case <-ctx.Done():
-				s.Logger.Info("cleanup stopped due to cancelled context", "", ctx.Err())
-				return
-			}
+	if err != nil {
+		s.Logger.Error("error opening a file", "error", err)
+		return nil, domain.ErrInService
+	}
+
+	return rc, nil
+}
+
+// засчитать скачивание файла, ошибки бд наружу не отдаются
+func (s *FileService) countDownload(ctx context.Context, id string) error {
+	err := s.Repo.CountDownload(ctx, id)
+	if err == nil || errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) ||
+		errors.Is(err, domain.ErrDownloadsExhausted) || errors.Is(err, domain.ErrCorrupted) {
+		return err
+	}
+
+	s.Logger.Error("error counting a download", "error", err)
+	return domain.ErrInRepo
+}
+
+// удалить файл по просьбе владельца: сначала запись, затем содержимое
+func (s *FileService) Delete(ctx context.Context, id, token string) error {
+	file, err := s.Repo.Lookup(ctx, id)
+	if err != nil {
+		// по той же ссылке может быть набор файлов
+		if errors.Is(err, domain.ErrNotFound) {
+			return s.deleteCollection(ctx, id, token)
 		}
-	}()
+
+		s.Logger.Error("error deleting a file", "error", err)
+		return domain.ErrInRepo
+	}
+
+	if !tokenMatches(file.TokenHash, token) {
+		return domain.ErrWrongToken
+	}
+
+	// содержимое удаляется, только если на него не ссылаются другие файлы
+	keys, err := s.Repo.Delete(ctx, id)
+	if err != nil {
+		s.Logger.Error("error deleting a file", "error", err)
+		return domain.ErrInRepo
+	}
+
+	for _, key := range keys {
+		s.deleteBlob(ctx, key)
+	}
+	s.Logger.Info("deleted file by owner: " + id)
+	return nil
+}
+
+// продлить или сократить срок жизни ссылки по просьбе владельца. действуют те же ограничения,
+// что при загрузке: сервера, арендатора и ключа, по которому загружен файл
+func (s *FileService) UpdateExpiry(ctx context.Context, id, token string, ttl time.Duration) (time.Time, error) {
+	file, err := s.Repo.Lookup(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return s.updateCollectionExpiry(ctx, id, token, ttl)
+		}
+
+		s.Logger.Error("error updating expiry", "error", err)
+		return time.Time{}, domain.ErrInRepo
+	}
+
+	if !tokenMatches(file.TokenHash, token) {
+		return time.Time{}, domain.ErrWrongToken
+	}
+
+	// истекшую ссылку не воскрешаем, она ждет удаления
+	now := time.Now()
+	if !file.ExpiresAt.IsZero() && now.After(file.ExpiresAt) {
+		return time.Time{}, domain.ErrExpired
+	}
+
+	expires, err := s.expiresAt(ctx, file.CreatedAt, now, ttl)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if expires, err = s.keyUpdateExpiry(ctx, file.KeyID, file.CreatedAt, file.ExpiresAt, expires, ttl); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.Repo.UpdateExpiry(ctx, id, expires); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return time.Time{}, err
+		}
+
+		s.Logger.Error("error updating expiry", "error", err)
+		return time.Time{}, domain.ErrInRepo
+	}
+
+	s.reschedule(expires)
+	s.Logger.Info("updated expiry of a file: " + id)
+	return expires, nil
+}
+
+// подходит ли токен владельца к хешу файла или набора. у старых файлов токена нет, их удалить нельзя
+func tokenMatches(hash, token string) bool {
+	if hash == "" || token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hashToken(token))) == 1
 }
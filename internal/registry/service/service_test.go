@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+// загрузить файл с содержимым body, недостающие поля meta заполняются
+func storeFile(t *testing.T, s *FileService, ctx context.Context, meta domain.UploadMeta, body string) *domain.UploadResult {
+	t.Helper()
+
+	meta.Name, meta.ContentType, meta.Size = "a.txt", "text/plain", int64(len(body))
+	res, err := s.Store(ctx, meta, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	return res
+}
+
+func readAll(t *testing.T, rc io.ReadCloser) string {
+	t.Helper()
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	return string(data)
+}
+
+func TestFileService_OpenCountsRanges(t *testing.T) {
+	s := setupService(t)
+	ctx := context.Background()
+
+	// ссылка на одно скачивание: кусок с середины тратит лимит так же, как целый файл
+	once := storeFile(t, s, ctx, domain.UploadMeta{MaxDownloads: 1}, "0123456789")
+
+	rc, err := s.Open(ctx, once.ID, "", 1, 0)
+	if err != nil {
+		t.Fatalf("Open from offset 1 failed: %v", err)
+	}
+	if got := readAll(t, rc); got != "123456789" {
+		t.Errorf("Expected the tail of the file, got %q", got)
+	}
+
+	for _, offset := range []int64{0, 1} {
+		if _, err := s.Open(ctx, once.ID, "", offset, 0); !errors.Is(err, domain.ErrDownloadsExhausted) {
+			t.Errorf("Expected ErrDownloadsExhausted from offset %d, got %v", offset, err)
+		}
+	}
+
+	// без лимита докачка не засчитывается повторно
+	free := storeFile(t, s, ctx, domain.UploadMeta{}, "0123456789")
+	for _, offset := range []int64{0, 5, 5} {
+		rc, err := s.Open(ctx, free.ID, "", offset, 0)
+		if err != nil {
+			t.Fatalf("Open from offset %d failed: %v", offset, err)
+		}
+		rc.Close()
+	}
+
+	f, err := s.Repo.Lookup(ctx, free.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Downloads != 1 {
+		t.Errorf("Expected 1 download, got %d", f.Downloads)
+	}
+}
+
+func TestFileService_UpdateExpiryKeyPolicy(t *testing.T) {
+	s := setupService(t)
+	ctx := context.Background()
+
+	// ключ без бессрочных ссылок и не дольше часа с загрузки
+	k, _, err := s.CreateKey(ctx, domain.APIKey{Name: "ci", MaxTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := domain.UploadMeta{KeyID: k.ID, Owner: "key:" + k.ID, TTL: 10 * time.Minute}
+	file := storeFile(t, s, ctx, meta, "abc")
+	member := storeFile(t, s, ctx, meta, "abc")
+
+	members := []domain.MemberRef{{ID: member.ID, Token: member.DeleteToken}}
+	set, err := s.CreateCollection(ctx, domain.CollectionMeta{Members: members, KeyID: k.ID, Owner: meta.Owner, TTL: 10 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// владелец не может выйти за ограничение ключа ни для файла, ни для набора
+	for _, res := range []*domain.UploadResult{file, set} {
+		for _, ttl := range []time.Duration{domain.NoExpiry, 2 * time.Hour} {
+			if _, err := s.UpdateExpiry(ctx, res.ID, res.DeleteToken, ttl); !errors.Is(err, domain.ErrInvalidTTL) {
+				t.Errorf("Expected ErrInvalidTTL for %s with ttl %v, got %v", res.ID, ttl, err)
+			}
+		}
+
+		// срок по умолчанию урезается до разрешенного ключом, как при загрузке
+		f, err := s.UpdateExpiry(ctx, res.ID, res.DeleteToken, 0)
+		if err != nil {
+			t.Fatalf("UpdateExpiry with the default ttl failed: %v", err)
+		}
+		if f.IsZero() || time.Until(f) > time.Hour {
+			t.Errorf("Expected expiry within the key limit, got %v", f)
+		}
+	}
+
+	// отозванный ключ своего ограничения уже не расскажет: ссылку можно только сократить
+	if _, err := s.UpdateExpiry(ctx, set.ID, set.DeleteToken, 30*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeKey(ctx, k.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateExpiry(ctx, set.ID, set.DeleteToken, 45*time.Minute); !errors.Is(err, domain.ErrInvalidTTL) {
+		t.Errorf("Expected ErrInvalidTTL for a longer ttl of a revoked key, got %v", err)
+	}
+	if _, err := s.UpdateExpiry(ctx, set.ID, set.DeleteToken, 10*time.Minute); err != nil {
+		t.Errorf("Expected a shorter ttl to pass, got %v", err)
+	}
+}
+
+func TestFileService_UpdateExpiry(t *testing.T) {
+	s := setupService(t)
+	s.DefaultTTL, s.MaxTTL = time.Hour, 24*time.Hour
+	s.Tenants = map[string]domain.Tenant{"sales": {Name: "sales", MaxTTL: 2 * time.Hour}}
+	ctx := context.Background()
+
+	res := storeFile(t, s, ctx, domain.UploadMeta{}, "abc")
+
+	if _, err := s.UpdateExpiry(ctx, res.ID, "wrong", time.Hour); !errors.Is(err, domain.ErrWrongToken) {
+		t.Errorf("Expected ErrWrongToken, got %v", err)
+	}
+	if _, err := s.UpdateExpiry(ctx, "missing", res.DeleteToken, time.Hour); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// новый срок считается от текущего момента, но не дальше MaxTTL от загрузки
+	for _, ttl := range []time.Duration{25 * time.Hour, domain.NoExpiry, -2} {
+		if _, err := s.UpdateExpiry(ctx, res.ID, res.DeleteToken, ttl); !errors.Is(err, domain.ErrInvalidTTL) {
+			t.Errorf("Expected ErrInvalidTTL for ttl %v, got %v", ttl, err)
+		}
+	}
+
+	expires, err := s.UpdateExpiry(ctx, res.ID, res.DeleteToken, 20*time.Hour)
+	if err != nil {
+		t.Fatalf("UpdateExpiry failed: %v", err)
+	}
+	if d := time.Until(expires); d < 19*time.Hour || d > 20*time.Hour {
+		t.Errorf("Expected expiry in 20h, got %v", d)
+	}
+
+	// 0 - снова срок по умолчанию
+	if expires, err = s.UpdateExpiry(ctx, res.ID, res.DeleteToken, 0); err != nil || time.Until(expires) > time.Hour {
+		t.Errorf("Expected the default expiry, got %v, %v", expires, err)
+	}
+
+	// пределы арендатора заменяют общие
+	sales := domain.WithTenant(ctx, "sales")
+	tenantFile := storeFile(t, s, sales, domain.UploadMeta{}, "abc")
+	if _, err := s.UpdateExpiry(sales, tenantFile.ID, tenantFile.DeleteToken, 3*time.Hour); !errors.Is(err, domain.ErrInvalidTTL) {
+		t.Errorf("Expected ErrInvalidTTL beyond the tenant limit, got %v", err)
+	}
+
+	// истекшая ссылка не воскресает, даже если очистка до нее еще не добралась
+	if err := s.Repo.UpdateExpiry(ctx, res.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateExpiry(ctx, res.ID, res.DeleteToken, time.Hour); !errors.Is(err, domain.ErrExpired) {
+		t.Errorf("Expected ErrExpired for an expired link, got %v", err)
+	}
+}
+
+func TestFileService_StoreDigestMismatch(t *testing.T) {
+	s := setupService(t)
+	ctx := context.Background()
+
+	sha := sha256.Sum256([]byte("abc"))
+	sum := md5.Sum([]byte("abc"))
+	otherSHA := sha256.Sum256([]byte("abd"))
+	otherMD5 := md5.Sum([]byte("abd"))
+
+	store := func(meta domain.UploadMeta) error {
+		meta.Name, meta.ContentType, meta.Size, meta.ShortName, meta.Owner = "a.txt", "text/plain", 3, "named", "alice"
+		_, err := s.Store(ctx, meta, strings.NewReader("abc"))
+		return err
+	}
+
+	for _, meta := range []domain.UploadMeta{
+		{SHA256: otherSHA[:]},
+		{MD5: otherMD5[:]},
+		{SHA256: sha[:], MD5: otherMD5[:]},
+	} {
+		if err := store(meta); !errors.Is(err, domain.ErrDigestMismatch) {
+			t.Errorf("Expected ErrDigestMismatch, got %v", err)
+		}
+	}
+
+	// отвергнутая загрузка не оставляет ни записи, ни объекта в хранилище
+	if _, err := s.Repo.Lookup(ctx, "named"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected no record, got %v", err)
+	}
+	if objects, err := s.Blobs.List(ctx, "", 10); err != nil || len(objects) != 0 {
+		t.Errorf("Expected no objects, got %v, %v", objects, err)
+	}
+
+	if err := store(domain.UploadMeta{SHA256: sha[:], MD5: sum[:]}); err != nil {
+		t.Errorf("Expected matching digests to pass, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+// арендаторы берутся из настроек реестра. имя арендатора приходит с запросом и лежит в контексте,
+// репозиторий по нему разделяет пространства коротких имен, а здесь к загрузке применяются
+// настройки арендатора вместо общих
+
+// есть ли такой арендатор. пространство по умолчанию есть всегда
+func (s *FileService) HasTenant(name string) bool {
+	if name == "" {
+		return true
+	}
+
+	_, ok := s.Tenants[name]
+	return ok
+}
+
+// настройки арендатора из контекста, у пространства по умолчанию они нулевые
+func (s *FileService) tenant(ctx context.Context) domain.Tenant {
+	return s.Tenants[domain.TenantFrom(ctx)]
+}
+
+// сколько занимает арендатор из контекста и сколько ему разрешено
+func (s *FileService) TenantUsage(ctx context.Context) (domain.Usage, domain.Quota, error) {
+	t := s.tenant(ctx)
+	if t.Name == "" {
+		return domain.Usage{}, domain.Quota{}, nil
+	}
+
+	u, err := s.Repo.TenantUsage(ctx, t.Name)
+	if err != nil {
+		s.Logger.Error("error counting usage", "error", err)
+		return domain.Usage{}, domain.Quota{}, domain.ErrInRepo
+	}
+
+	return u, t.Quota, nil
+}
+
+// наибольший размер файла: меньшее из ограничений ключа и арендатора, 0 - без ограничений
+func maxSize(k *domain.APIKey, t domain.Tenant) int64 {
+	limit := t.MaxSize
+	if k != nil && k.MaxSize > 0 && (limit == 0 || k.MaxSize < limit) {
+		limit = k.MaxSize
+	}
+
+	return limit
+}
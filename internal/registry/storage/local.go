@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kfcempoyee/gofilesharing/internal/registry/domain"
+)
+
+// хранилище в папке на локальном диске реестра, каждый объект - отдельный файл
+type Local struct {
+	dir string
+}
+
+// папка создается сразу, чтобы не проверять ее при каждой записи
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Local{dir: dir}, nil
+}
+
+// берется только имя файла: так ключ не выйдет за пределы папки, а старые записи,
+// где в бд лежит полный путь вида data/storage/<uuid>.dat, продолжают находиться
+func (l *Local) path(key string) string {
+	return filepath.Join(l.dir, filepath.Base(key))
+}
+
+// записать объект. пишем во временный файл и переименовываем, чтобы
+// недописанный объект никогда не был виден под своим ключом
+func (l *Local) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp(l.dir, ".put-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), l.path(key)); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+func (l *Local) Open(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(l.path(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, domain.ErrBlobNotFound
+		}
+		return nil, err
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if length == 0 {
+		return f, nil
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+// удаление несуществующего объекта ошибкой не является
+func (l *Local) Delete(ctx context.Context, key string) error {
+	err := os.Remove(l.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (l *Local) Stat(ctx context.Context, key string) (int64, error) {
+	st, err := os.Stat(l.path(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, domain.ErrBlobNotFound
+		}
+		return 0, err
+	}
+
+	return st.Size(), nil
+}
+
+// объекты по порядку ключей, начиная после after. незаконченные записи (.put-*) тоже попадают
+// в список: если реестр упал посреди Put, их больше никто не удалит
+func (l *Local) List(ctx context.Context, after string, limit int) ([]domain.Object, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []domain.Object
+	for _, e := range entries {
+		if len(objects) == limit {
+			break
+		}
+		if e.IsDir() || e.Name() <= after {
+			continue
+		}
+
+		info, err := e.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			continue // удалили, пока читали папку
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, domain.Object{Key: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+
+	return objects, nil
+}